@@ -1,16 +1,35 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/MobasirSarkar/hookfilter/internal/server"
 	"github.com/joho/godotenv"
 )
 
+const usage = `usage: hookfilter [serve|worker|all]
+
+  serve   run the HTTP API and webhook ingestion
+  worker  run the background delivery worker
+  all     run both in a single process (default)`
+
 func main() {
 	if err := godotenv.Load(); err != nil {
 		panic(err)
 	}
 
-	server := server.New()
+	var arg string
+	if len(os.Args) > 1 {
+		arg = os.Args[1]
+	}
+	mode, err := server.ParseMode(arg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	server := server.New(mode)
 
 	if err := server.Run(); err != nil {
 		panic(err)
@@ -5,22 +5,67 @@ import (
 	"time"
 )
 
+// DelayedKeys locates a delayed task store. Members of Schedule are
+// "<pipeID>:<taskID>"; IndexPrefix+pipeID is the pipe's own schedule
+// and QueuePrefix+pipeID the queue a task moves to once due, after
+// which pipeID is added to Active.
+type DelayedKeys struct {
+	Schedule    string
+	Tasks       string
+	Index       string
+	IndexPrefix string
+	QueuePrefix string
+	Active      string
+}
+
+// DelayedEntry is a task waiting in a delayed store.
+type DelayedEntry struct {
+	Member string
+	Due    time.Time
+	Raw    string
+}
+
 type Cacher interface {
 	// standard function
 	Get(ctx context.Context, key string) (string, bool, error)
 	Set(ctx context.Context, key, val string, ttl time.Duration) error
 	SetNX(ctx context.Context, key string, ttl time.Duration) (bool, error)
 	Delete(ctx context.Context, key string) error
+	DeletePattern(ctx context.Context, pattern string) error
 
 	// requests counter
 	Incr(ctx context.Context, key string) (int64, error)
 	IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	TokenBucketReserve(ctx context.Context, key string, rate float64, burst int64) (time.Duration, error)
 	Expire(ctx context.Context, key string, ttl time.Duration) error
 
 	// queue function
 	QueuePush(ctx context.Context, queue, val string) error
+	QueuePushFront(ctx context.Context, queue, val string) error
 	QueueBlockingPop(ctx context.Context, queue string) (string, error)
+	QueueBlockingPopAny(ctx context.Context, queues []string) (string, string, error)
 	QueueTryPop(ctx context.Context, queue string) (string, bool, error)
+	QueueLen(ctx context.Context, queue string) (int64, error)
+	QueuePeek(ctx context.Context, queue string) (string, bool, error)
+	QueueUntrackIfEmpty(ctx context.Context, set, member, queue string) (bool, error)
+
+	// stream function
+	StreamAdd(ctx context.Context, stream string, maxLen int64, values map[string]any) (string, error)
+
+	// delayed function
+	DelayedAdd(ctx context.Context, keys DelayedKeys, member string, due time.Time, raw string) error
+	DelayedRemove(ctx context.Context, keys DelayedKeys, member string) (bool, error)
+	DelayedList(ctx context.Context, keys DelayedKeys, offset, limit int64) (int64, []DelayedEntry, error)
+	DelayedPromote(ctx context.Context, keys DelayedKeys, now time.Time, limit int64) (int, error)
+
+	// window function
+	WindowAdd(ctx context.Context, key, mode, windowID, raw string, ttl time.Duration) (string, bool, int64, error)
+	WindowTake(ctx context.Context, key, windowID string) ([]string, bool, error)
+
+	// set function
+	SetAdd(ctx context.Context, key string, members ...string) error
+	SetMembers(ctx context.Context, key string) ([]string, error)
+	SetRemove(ctx context.Context, key string, members ...string) error
 
 	// pub/sub function
 	Publish(ctx context.Context, channel, message string) error
@@ -3,6 +3,9 @@ package cache
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/MobasirSarkar/hookfilter/pkg/config"
@@ -15,6 +18,10 @@ var (
 	conTimeout    = 5 * time.Second
 )
 
+// ErrMalformedDelayed reports scheduled members DelayedPromote could not
+// map to a pipe and removed instead of promoting.
+var ErrMalformedDelayed = errors.New("malformed scheduled task")
+
 type RedisCache struct {
 	client *redis.Client
 }
@@ -92,12 +99,34 @@ func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// DeletePattern removes every key matching a glob pattern. It scans
+// rather than using KEYS so a large keyspace does not block redis.
+func (r *RedisCache) DeletePattern(ctx context.Context, pattern string) error {
+	iter := r.client.Scan(ctx, 0, pattern, 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 100 {
+			if err := r.client.Unlink(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return r.client.Unlink(ctx, keys...).Err()
+	}
+	return nil
+}
+
 func (r *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
 	return r.client.Incr(ctx, key).Result()
 }
 
-func (r *RedisCache) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
-	var incrWithTTL = redis.NewScript(`
+var incrWithTTL = redis.NewScript(`
 if redis.call("EXISTS", KEYS[1]) == 0 then
   redis.call("SET", KEYS[1], 1, "PX", ARGV[1])
   return 1
@@ -105,6 +134,8 @@ else
   return redis.call("INCR", KEYS[1])
 end
 `)
+
+func (r *RedisCache) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
 	if ttl <= 0 {
 		return 0, ErrInvalidTTL
 	}
@@ -115,6 +146,41 @@ end
 	return res.(int64), nil
 
 }
+
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+tokens = tokens - 1
+local wait = 0
+if tokens < 0 then
+  wait = math.ceil(-tokens / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], wait + math.ceil(burst / rate * 1000) + 1000)
+return wait
+`)
+
+// TokenBucketReserve reserves one token from the bucket at key, refilled
+// at rate tokens per second up to burst. The bucket may go into debt, so
+// concurrent callers get increasing waits and are paced evenly; the
+// returned duration is how long the caller must wait before using its
+// token. Redis' own clock is used so every process agrees on time.
+func (r *RedisCache) TokenBucketReserve(ctx context.Context, key string, rate float64, burst int64) (time.Duration, error) {
+	wait, err := tokenBucket.Run(ctx, r.client, []string{key}, rate, burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
 func (r *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
 	return r.client.Expire(ctx, key, ttl).Err()
 }
@@ -123,6 +189,12 @@ func (r *RedisCache) QueuePush(ctx context.Context, queue, val string) error {
 	return r.client.LPush(ctx, queue, val).Err()
 }
 
+// QueuePushFront puts val at the consuming end of queue so it is popped
+// next, used to hand back a task that was popped but not processed.
+func (r *RedisCache) QueuePushFront(ctx context.Context, queue, val string) error {
+	return r.client.RPush(ctx, queue, val).Err()
+}
+
 func (r *RedisCache) QueueBlockingPop(ctx context.Context, queue string) (string, error) {
 	results, err := r.client.BRPop(ctx, 1*time.Second, queue).Result()
 	if err == redis.Nil {
@@ -134,6 +206,19 @@ func (r *RedisCache) QueueBlockingPop(ctx context.Context, queue string) (string
 	return results[1], nil
 }
 
+// QueueBlockingPopAny pops from the first non-empty queue in priority order.
+// It returns the name of the queue the value came from along with the value.
+func (r *RedisCache) QueueBlockingPopAny(ctx context.Context, queues []string) (string, string, error) {
+	results, err := r.client.BRPop(ctx, 1*time.Second, queues...).Result()
+	if err == redis.Nil {
+		return "", "", ErrQueueEmpty
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return results[0], results[1], nil
+}
+
 func (r *RedisCache) QueueTryPop(ctx context.Context, queue string) (string, bool, error) {
 	val, err := r.client.RPop(ctx, queue).Result()
 	if err == redis.Nil {
@@ -145,6 +230,273 @@ func (r *RedisCache) QueueTryPop(ctx context.Context, queue string) (string, boo
 	return val, true, nil
 }
 
+func (r *RedisCache) QueueLen(ctx context.Context, queue string) (int64, error) {
+	return r.client.LLen(ctx, queue).Result()
+}
+
+// QueuePeek returns the element QueueTryPop would pop next without
+// removing it.
+func (r *RedisCache) QueuePeek(ctx context.Context, queue string) (string, bool, error) {
+	val, err := r.client.LIndex(ctx, queue, -1).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+var untrackIfEmpty = redis.NewScript(`
+if redis.call("LLEN", KEYS[2]) == 0 then
+  redis.call("SREM", KEYS[1], ARGV[1])
+  return 1
+end
+return 0
+`)
+
+// QueueUntrackIfEmpty removes member from set only if queue is empty,
+// atomically, so a push racing with the removal is never orphaned.
+func (r *RedisCache) QueueUntrackIfEmpty(ctx context.Context, set, member, queue string) (bool, error) {
+	res, err := untrackIfEmpty.Run(ctx, r.client, []string{set, queue}, member).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// StreamAdd appends an entry to a stream. A positive maxLen trims the
+// stream approximately to that length.
+func (r *RedisCache) StreamAdd(ctx context.Context, stream string, maxLen int64, values map[string]any) (string, error) {
+	args := &redis.XAddArgs{
+		Stream: stream,
+		Values: values,
+	}
+	if maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+	return r.client.XAdd(ctx, args).Result()
+}
+
+// DelayedAdd stores raw and schedules member at due in both the global
+// schedule and the pipe's index.
+func (r *RedisCache) DelayedAdd(ctx context.Context, keys DelayedKeys, member string, due time.Time, raw string) error {
+	score := float64(due.UnixMilli())
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, keys.Tasks, member, raw)
+	pipe.ZAdd(ctx, keys.Schedule, redis.Z{Score: score, Member: member})
+	pipe.ZAdd(ctx, keys.Index, redis.Z{Score: score, Member: member})
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+var removeDelayed = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+redis.call("ZREM", KEYS[3], ARGV[1])
+redis.call("HDEL", KEYS[2], ARGV[1])
+return 1
+`)
+
+// DelayedRemove cancels member, reporting whether it was still pending.
+// It races safely with DelayedPromote: whichever removes it from the
+// schedule first wins.
+func (r *RedisCache) DelayedRemove(ctx context.Context, keys DelayedKeys, member string) (bool, error) {
+	res, err := removeDelayed.Run(ctx, r.client, []string{keys.Schedule, keys.Tasks, keys.Index}, member).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// DelayedList pages through a pipe's index, earliest first, and returns
+// the index size alongside the page.
+func (r *RedisCache) DelayedList(ctx context.Context, keys DelayedKeys, offset, limit int64) (int64, []DelayedEntry, error) {
+	total, err := r.client.ZCard(ctx, keys.Index).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+	zs, err := r.client.ZRangeWithScores(ctx, keys.Index, offset, offset+limit-1).Result()
+	if err != nil || len(zs) == 0 {
+		return total, []DelayedEntry{}, err
+	}
+
+	members := make([]string, len(zs))
+	for i, z := range zs {
+		members[i] = z.Member.(string)
+	}
+	raws, err := r.client.HMGet(ctx, keys.Tasks, members...).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	entries := make([]DelayedEntry, 0, len(zs))
+	for i, z := range zs {
+		raw, ok := raws[i].(string)
+		if !ok {
+			// promoted or cancelled between the two reads
+			continue
+		}
+		entries = append(entries, DelayedEntry{
+			Member: members[i],
+			Due:    time.UnixMilli(int64(z.Score)),
+			Raw:    raw,
+		})
+	}
+	return total, entries, nil
+}
+
+var promoteDelayed = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+local raw = redis.call("HGET", KEYS[2], ARGV[1])
+redis.call("HDEL", KEYS[2], ARGV[1])
+redis.call("ZREM", KEYS[3], ARGV[1])
+if not raw then
+  return 0
+end
+redis.call("LPUSH", KEYS[4], raw)
+redis.call("SADD", KEYS[5], ARGV[2])
+return 1
+`)
+
+// DelayedPromote moves up to limit tasks due by now from the store onto
+// their pipe queues and returns how many were moved. Each task is moved
+// atomically by a script that declares every key it touches; the due
+// members are read first because the pipe keys follow from them.
+// Members without a pipe prefix are removed and reported with
+// ErrMalformedDelayed after the others are moved.
+func (r *RedisCache) DelayedPromote(ctx context.Context, keys DelayedKeys, now time.Time, limit int64) (int, error) {
+	due, err := r.client.ZRangeByScore(ctx, keys.Schedule, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	var malformed []string
+	for _, member := range due {
+		pipe, _, ok := strings.Cut(member, ":")
+		if !ok {
+			// left in place it would come back as due on every round
+			malformed = append(malformed, member)
+			continue
+		}
+		// a member cancelled or promoted elsewhere in the meantime is
+		// skipped by the script
+		n, err := promoteDelayed.Run(ctx, r.client,
+			[]string{keys.Schedule, keys.Tasks, keys.IndexPrefix + pipe, keys.QueuePrefix + pipe, keys.Active},
+			member, pipe,
+		).Int()
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+
+	if len(malformed) > 0 {
+		pipe := r.client.TxPipeline()
+		pipe.ZRem(ctx, keys.Schedule, malformed)
+		pipe.HDel(ctx, keys.Tasks, malformed...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return moved, err
+		}
+		return moved, fmt.Errorf("%w: removed %q from %s", ErrMalformedDelayed, malformed, keys.Schedule)
+	}
+	return moved, nil
+}
+
+var windowAdd = redis.NewScript(`
+local id = redis.call("HGET", KEYS[1], "id")
+local opened = 0
+if not id then
+  id = ARGV[2]
+  opened = 1
+  redis.call("HSET", KEYS[1], "id", id)
+end
+local n = redis.call("HINCRBY", KEYS[1], "count", 1)
+if ARGV[1] == "first" then
+  redis.call("HSETNX", KEYS[1], "value", ARGV[3])
+elseif ARGV[1] == "latest" then
+  redis.call("HSET", KEYS[1], "value", ARGV[3])
+else
+  redis.call("RPUSH", KEYS[2], ARGV[3])
+  redis.call("PEXPIRE", KEYS[2], ARGV[4])
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {id, opened, n}
+`)
+
+// WindowAdd adds raw to the open window at key, opening one with id
+// windowID if none exists. mode "first" keeps the first value, "latest"
+// the last one and anything else collects every value. It returns the
+// open window's id, whether this call opened it and its event count.
+func (r *RedisCache) WindowAdd(ctx context.Context, key, mode, windowID, raw string, ttl time.Duration) (string, bool, int64, error) {
+	res, err := windowAdd.Run(ctx, r.client, []string{key, key + ":items"},
+		mode, windowID, raw, ttl.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return "", false, 0, err
+	}
+	id, _ := res[0].(string)
+	opened, _ := res[1].(int64)
+	count, _ := res[2].(int64)
+	return id, opened == 1, count, nil
+}
+
+var windowTake = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "id") ~= ARGV[1] then
+  return false
+end
+local values = redis.call("LRANGE", KEYS[2], 0, -1)
+local single = redis.call("HGET", KEYS[1], "value")
+if single then
+  values = {single}
+end
+redis.call("DEL", KEYS[1], KEYS[2])
+return values
+`)
+
+// WindowTake closes the window at key and returns its values, but only
+// if it is still the window identified by windowID. ok is false when
+// that window was already taken.
+func (r *RedisCache) WindowTake(ctx context.Context, key, windowID string) ([]string, bool, error) {
+	res, err := windowTake.Run(ctx, r.client, []string{key, key + ":items"}, windowID).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
+}
+
+func (r *RedisCache) SetAdd(ctx context.Context, key string, members ...string) error {
+	args := make([]any, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	return r.client.SAdd(ctx, key, args...).Err()
+}
+
+func (r *RedisCache) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return r.client.SMembers(ctx, key).Result()
+}
+
+func (r *RedisCache) SetRemove(ctx context.Context, key string, members ...string) error {
+	args := make([]any, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	return r.client.SRem(ctx, key, args...).Err()
+}
+
 // Publish sends a message to a channel (e.g., "events:user_123").
 func (r *RedisCache) Publish(ctx context.Context, channel, message string) error {
 	return r.client.Publish(ctx, channel, message).Err()
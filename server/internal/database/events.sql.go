@@ -7,16 +7,18 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createEvent = `-- name: CreateEvent :exec
 INSERT INTO events (
-    id, pipe_id, status_code, request_payload, transformed_payload
+    id, pipe_id, status_code, request_payload, transformed_payload, pipe_revision, created_at
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6, $7
 )
+ON CONFLICT (id, created_at) DO NOTHING
 `
 
 type CreateEventParams struct {
@@ -25,6 +27,8 @@ type CreateEventParams struct {
 	StatusCode         int32     `json:"status_code"`
 	RequestPayload     []byte    `json:"request_payload"`
 	TransformedPayload []byte    `json:"transformed_payload"`
+	PipeRevision       int32     `json:"pipe_revision"`
+	CreatedAt          time.Time `json:"created_at"`
 }
 
 func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) error {
@@ -34,6 +38,8 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) error
 		arg.StatusCode,
 		arg.RequestPayload,
 		arg.TransformedPayload,
+		arg.PipeRevision,
+		arg.CreatedAt,
 	)
 	return err
 }
@@ -44,14 +50,19 @@ INSERT INTO events (
     pipe_id,
     status_code,
     request_payload,
-    transformed_payload
+    transformed_payload,
+    pipe_revision,
+    created_at
 )
 SELECT
     unnest($1::uuid[]),
     unnest($2::uuid[]),
     unnest($3::int[]),
     unnest($4::jsonb[]),
-    unnest($5::jsonb[])
+    unnest($5::jsonb[]),
+    unnest($6::int[]),
+    unnest($7::timestamp[])
+ON CONFLICT (id, created_at) DO NOTHING
 `
 
 type CreateEventsBatchParams struct {
@@ -60,6 +71,8 @@ type CreateEventsBatchParams struct {
 	StatusCodes         []int32     `json:"status_codes"`
 	RequestPayloads     [][]byte    `json:"request_payloads"`
 	TransformedPayloads [][]byte    `json:"transformed_payloads"`
+	PipeRevisions       []int32     `json:"pipe_revisions"`
+	CreatedAts          []time.Time `json:"created_ats"`
 }
 
 func (q *Queries) CreateEventsBatch(ctx context.Context, arg CreateEventsBatchParams) error {
@@ -69,12 +82,76 @@ func (q *Queries) CreateEventsBatch(ctx context.Context, arg CreateEventsBatchPa
 		arg.StatusCodes,
 		arg.RequestPayloads,
 		arg.TransformedPayloads,
+		arg.PipeRevisions,
+		arg.CreatedAts,
 	)
 	return err
 }
 
+const getEventForUser = `-- name: GetEventForUser :one
+SELECT e.id, e.pipe_id, e.request_payload, e.created_at, p.name AS pipe_name, p.slug AS pipe_slug
+FROM events e
+JOIN pipes p ON p.id = e.pipe_id
+WHERE e.id = $1 AND p.user_id = $2 AND p.deleted_at IS NULL
+LIMIT 1
+`
+
+type GetEventForUserParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type GetEventForUserRow struct {
+	ID             uuid.UUID `json:"id"`
+	PipeID         uuid.UUID `json:"pipe_id"`
+	RequestPayload []byte    `json:"request_payload"`
+	CreatedAt      time.Time `json:"created_at"`
+	PipeName       string    `json:"pipe_name"`
+	PipeSlug       string    `json:"pipe_slug"`
+}
+
+func (q *Queries) GetEventForUser(ctx context.Context, arg GetEventForUserParams) (GetEventForUserRow, error) {
+	row := q.db.QueryRow(ctx, getEventForUser, arg.ID, arg.UserID)
+	var i GetEventForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.PipeID,
+		&i.RequestPayload,
+		&i.CreatedAt,
+		&i.PipeName,
+		&i.PipeSlug,
+	)
+	return i, err
+}
+
+const getPipeEvent = `-- name: GetPipeEvent :one
+SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision FROM events
+WHERE id = $1 AND pipe_id = $2
+LIMIT 1
+`
+
+type GetPipeEventParams struct {
+	ID     uuid.UUID `json:"id"`
+	PipeID uuid.UUID `json:"pipe_id"`
+}
+
+func (q *Queries) GetPipeEvent(ctx context.Context, arg GetPipeEventParams) (Event, error) {
+	row := q.db.QueryRow(ctx, getPipeEvent, arg.ID, arg.PipeID)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.PipeID,
+		&i.StatusCode,
+		&i.RequestPayload,
+		&i.TransformedPayload,
+		&i.CreatedAt,
+		&i.PipeRevision,
+	)
+	return i, err
+}
+
 const listEvents = `-- name: ListEvents :many
-SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at FROM events
+SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision FROM events
 WHERE pipe_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -102,6 +179,86 @@ func (q *Queries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event
 			&i.RequestPayload,
 			&i.TransformedPayload,
 			&i.CreatedAt,
+			&i.PipeRevision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPipeEvents = `-- name: ListPipeEvents :many
+SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision FROM events
+WHERE pipe_id = $1
+  AND ($2::int IS NULL OR status_code = $2)
+  AND ($3::int IS NULL OR status_code >= $3)
+  AND ($4::int IS NULL OR status_code <= $4)
+  AND ($5::timestamp IS NULL OR created_at >= $5)
+  AND ($6::timestamp IS NULL OR created_at < $6)
+  AND ($7::text IS NULL
+       OR ($8::text <> 'transformed' AND request_payload @? $7::jsonpath)
+       OR ($8::text <> 'request' AND transformed_payload @? $7::jsonpath))
+  AND ($9::jsonb IS NULL
+       OR ($8::text <> 'transformed' AND request_payload @> $9)
+       OR ($8::text <> 'request' AND transformed_payload @> $9))
+  AND ($10::timestamp IS NULL
+       OR (created_at, id) < ($10, $11::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $12
+`
+
+type ListPipeEventsParams struct {
+	PipeID          uuid.UUID  `json:"pipe_id"`
+	StatusCode      *int32     `json:"status_code"`
+	MinStatus       *int32     `json:"min_status"`
+	MaxStatus       *int32     `json:"max_status"`
+	Since           *time.Time `json:"since"`
+	Until           *time.Time `json:"until"`
+	Path            *string    `json:"path"`
+	SearchIn        string     `json:"search_in"`
+	Contains        []byte     `json:"contains"`
+	CursorCreatedAt *time.Time `json:"cursor_created_at"`
+	CursorID        *uuid.UUID `json:"cursor_id"`
+	PageLimit       int32      `json:"page_limit"`
+}
+
+// A keyset page of the pipe's events, newest first. Every filter is
+// optional; search_in ('request', 'transformed' or 'any') selects the
+// payloads path and contains are matched against.
+func (q *Queries) ListPipeEvents(ctx context.Context, arg ListPipeEventsParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, listPipeEvents,
+		arg.PipeID,
+		arg.StatusCode,
+		arg.MinStatus,
+		arg.MaxStatus,
+		arg.Since,
+		arg.Until,
+		arg.Path,
+		arg.SearchIn,
+		arg.Contains,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Event{}
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.PipeID,
+			&i.StatusCode,
+			&i.RequestPayload,
+			&i.TransformedPayload,
+			&i.CreatedAt,
+			&i.PipeRevision,
 		); err != nil {
 			return nil, err
 		}
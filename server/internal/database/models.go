@@ -17,19 +17,66 @@ type Event struct {
 	RequestPayload     []byte    `json:"request_payload"`
 	TransformedPayload []byte    `json:"transformed_payload"`
 	CreatedAt          time.Time `json:"created_at"`
+	PipeRevision       int32     `json:"pipe_revision"`
 }
 
 type Pipe struct {
-	ID        uuid.UUID  `json:"id"`
-	UserID    uuid.UUID  `json:"user_id"`
-	Name      string     `json:"name"`
-	Slug      string     `json:"slug"`
-	TargetUrl string     `json:"target_url"`
-	JqFilter  string     `json:"jq_filter"`
-	IsActive  bool       `json:"is_active"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
+	ID                   uuid.UUID  `json:"id"`
+	UserID               uuid.UUID  `json:"user_id"`
+	Name                 string     `json:"name"`
+	Slug                 string     `json:"slug"`
+	TargetUrl            string     `json:"target_url"`
+	JqFilter             string     `json:"jq_filter"`
+	IsActive             bool       `json:"is_active"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	DeletedAt            *time.Time `json:"deleted_at"`
+	Weight               int32      `json:"weight"`
+	MaxConcurrency       int32      `json:"max_concurrency"`
+	TlsClientCert        *string    `json:"tls_client_cert"`
+	TlsClientKey         *string    `json:"-"`
+	TlsCaBundle          *string    `json:"tls_ca_bundle"`
+	TlsSpkiPins          []string   `json:"tls_spki_pins"`
+	TlsMinVersion        *string    `json:"tls_min_version"`
+	DestinationType      string     `json:"destination_type"`
+	DestinationConfig    []byte     `json:"destination_config"`
+	OutputFormat         string     `json:"output_format"`
+	OutputTemplate       *string    `json:"output_template"`
+	OutputContentType    *string    `json:"output_content_type"`
+	DeliveryDelaySeconds int32      `json:"delivery_delay_seconds"`
+	DeliverAtExpr        *string    `json:"deliver_at_expr"`
+	WindowMode           *string    `json:"window_mode"`
+	WindowKeyExpr        *string    `json:"window_key_expr"`
+	WindowSeconds        int32      `json:"window_seconds"`
+	WindowMaxEvents      int32      `json:"window_max_events"`
+	RateLimitPerSec      float64    `json:"rate_limit_per_sec"`
+	RateLimitBurst       int32      `json:"rate_limit_burst"`
+	RateLimitScope       string     `json:"rate_limit_scope"`
+	Revision             int32      `json:"revision"`
+	PausedAt             *time.Time `json:"paused_at"`
+	RedactionRules       []byte     `json:"redaction_rules"`
+}
+
+type PipeFixture struct {
+	ID            uuid.UUID `json:"id"`
+	PipeID        uuid.UUID `json:"pipe_id"`
+	Name          string    `json:"name"`
+	Input         []byte    `json:"input"`
+	Headers       []byte    `json:"headers"`
+	Expected      []byte    `json:"expected"`
+	ExpectDropped bool      `json:"expect_dropped"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type PipeRevision struct {
+	PipeID         uuid.UUID `json:"pipe_id"`
+	Revision       int32     `json:"revision"`
+	UserID         uuid.UUID `json:"user_id"`
+	Action         string    `json:"action"`
+	SourceRevision *int32    `json:"source_revision"`
+	Config         []byte    `json:"config"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type RefreshToken struct {
@@ -41,16 +88,23 @@ type RefreshToken struct {
 	CreatedAt *time.Time `json:"created_at"`
 }
 
+type RetentionPlan struct {
+	Plan          string `json:"plan"`
+	RetentionDays int32  `json:"retention_days"`
+}
+
 type User struct {
-	ID              uuid.UUID  `json:"id"`
-	Username        string     `json:"username"`
-	Email           string     `json:"email"`
-	Password        *string    `json:"-"`
-	OauthProvider   *string    `json:"oauth_provider"`
-	OauthProviderID *string    `json:"oauth_provider_id"`
-	AvatarUrl       *string    `json:"avatar_url"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
-	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
-	TokenVersion    int32      `json:"token_version"`
+	ID                 uuid.UUID  `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	Password           *string    `json:"-"`
+	OauthProvider      *string    `json:"oauth_provider"`
+	OauthProviderID    *string    `json:"oauth_provider_id"`
+	AvatarUrl          *string    `json:"avatar_url"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	TokenVersion       int32      `json:"token_version"`
+	Plan               *string    `json:"plan"`
+	EventRetentionDays *int32     `json:"event_retention_days"`
 }
@@ -26,22 +26,58 @@ func (q *Queries) CountPipesByUser(ctx context.Context, userID uuid.UUID) (int64
 }
 
 const createPipe = `-- name: CreatePipe :exec
-INSERT INTO pipes (
-   id, user_id, name, slug, target_url, jq_filter
-) VALUES (
-    $1, $2, $3, $4, $5, $6
+WITH created AS (
+    INSERT INTO pipes (
+       id, user_id, name, slug, target_url, jq_filter, weight, max_concurrency,
+       tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version,
+       destination_type, destination_config, output_format, output_template, output_content_type,
+       delivery_delay_seconds, deliver_at_expr,
+       window_mode, window_key_expr, window_seconds, window_max_events,
+       rate_limit_per_sec, rate_limit_burst, rate_limit_scope,
+       redaction_rules
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+        $21, $22, $23, $24, $25, $26, $27, $28
+    )
+    RETURNING *
 )
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+SELECT id, revision, user_id, 'create', pipe_config(to_jsonb(created))
+FROM created
 `
 
 type CreatePipeParams struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Name      string    `json:"name"`
-	Slug      string    `json:"slug"`
-	TargetUrl string    `json:"target_url"`
-	JqFilter  string    `json:"jq_filter"`
+	ID                   uuid.UUID `json:"id"`
+	UserID               uuid.UUID `json:"user_id"`
+	Name                 string    `json:"name"`
+	Slug                 string    `json:"slug"`
+	TargetUrl            string    `json:"target_url"`
+	JqFilter             string    `json:"jq_filter"`
+	Weight               int32     `json:"weight"`
+	MaxConcurrency       int32     `json:"max_concurrency"`
+	TlsClientCert        *string   `json:"tls_client_cert"`
+	TlsClientKey         *string   `json:"-"`
+	TlsCaBundle          *string   `json:"tls_ca_bundle"`
+	TlsSpkiPins          []string  `json:"tls_spki_pins"`
+	TlsMinVersion        *string   `json:"tls_min_version"`
+	DestinationType      string    `json:"destination_type"`
+	DestinationConfig    []byte    `json:"destination_config"`
+	OutputFormat         string    `json:"output_format"`
+	OutputTemplate       *string   `json:"output_template"`
+	OutputContentType    *string   `json:"output_content_type"`
+	DeliveryDelaySeconds int32     `json:"delivery_delay_seconds"`
+	DeliverAtExpr        *string   `json:"deliver_at_expr"`
+	WindowMode           *string   `json:"window_mode"`
+	WindowKeyExpr        *string   `json:"window_key_expr"`
+	WindowSeconds        int32     `json:"window_seconds"`
+	WindowMaxEvents      int32     `json:"window_max_events"`
+	RateLimitPerSec      float64   `json:"rate_limit_per_sec"`
+	RateLimitBurst       int32     `json:"rate_limit_burst"`
+	RateLimitScope       string    `json:"rate_limit_scope"`
+	RedactionRules       []byte    `json:"redaction_rules"`
 }
 
+// the pipe is created together with its first revision
 func (q *Queries) CreatePipe(ctx context.Context, arg CreatePipeParams) error {
 	_, err := q.db.Exec(ctx, createPipe,
 		arg.ID,
@@ -50,6 +86,28 @@ func (q *Queries) CreatePipe(ctx context.Context, arg CreatePipeParams) error {
 		arg.Slug,
 		arg.TargetUrl,
 		arg.JqFilter,
+		arg.Weight,
+		arg.MaxConcurrency,
+		arg.TlsClientCert,
+		arg.TlsClientKey,
+		arg.TlsCaBundle,
+		arg.TlsSpkiPins,
+		arg.TlsMinVersion,
+		arg.DestinationType,
+		arg.DestinationConfig,
+		arg.OutputFormat,
+		arg.OutputTemplate,
+		arg.OutputContentType,
+		arg.DeliveryDelaySeconds,
+		arg.DeliverAtExpr,
+		arg.WindowMode,
+		arg.WindowKeyExpr,
+		arg.WindowSeconds,
+		arg.WindowMaxEvents,
+		arg.RateLimitPerSec,
+		arg.RateLimitBurst,
+		arg.RateLimitScope,
+		arg.RedactionRules,
 	)
 	return err
 }
@@ -76,7 +134,7 @@ func (q *Queries) DeletePipe(ctx context.Context, arg DeletePipeParams) (int64,
 }
 
 const getPipeById = `-- name: GetPipeById :one
-SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at FROM pipes
+SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at, weight, max_concurrency, tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version, destination_type, destination_config, output_format, output_template, output_content_type, delivery_delay_seconds, deliver_at_expr, window_mode, window_key_expr, window_seconds, window_max_events, rate_limit_per_sec, rate_limit_burst, rate_limit_scope, revision, paused_at, redaction_rules FROM pipes
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
@@ -99,12 +157,36 @@ func (q *Queries) GetPipeById(ctx context.Context, arg GetPipeByIdParams) (Pipe,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Weight,
+		&i.MaxConcurrency,
+		&i.TlsClientCert,
+		&i.TlsClientKey,
+		&i.TlsCaBundle,
+		&i.TlsSpkiPins,
+		&i.TlsMinVersion,
+		&i.DestinationType,
+		&i.DestinationConfig,
+		&i.OutputFormat,
+		&i.OutputTemplate,
+		&i.OutputContentType,
+		&i.DeliveryDelaySeconds,
+		&i.DeliverAtExpr,
+		&i.WindowMode,
+		&i.WindowKeyExpr,
+		&i.WindowSeconds,
+		&i.WindowMaxEvents,
+		&i.RateLimitPerSec,
+		&i.RateLimitBurst,
+		&i.RateLimitScope,
+		&i.Revision,
+		&i.PausedAt,
+		&i.RedactionRules,
 	)
 	return i, err
 }
 
 const getPipeBySlug = `-- name: GetPipeBySlug :one
-SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at FROM pipes
+SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at, weight, max_concurrency, tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version, destination_type, destination_config, output_format, output_template, output_content_type, delivery_delay_seconds, deliver_at_expr, window_mode, window_key_expr, window_seconds, window_max_events, rate_limit_per_sec, rate_limit_burst, rate_limit_scope, revision, paused_at, redaction_rules FROM pipes
 WHERE slug = $1
   AND is_active = true
   AND deleted_at IS NULL
@@ -125,12 +207,122 @@ func (q *Queries) GetPipeBySlug(ctx context.Context, slug string) (Pipe, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Weight,
+		&i.MaxConcurrency,
+		&i.TlsClientCert,
+		&i.TlsClientKey,
+		&i.TlsCaBundle,
+		&i.TlsSpkiPins,
+		&i.TlsMinVersion,
+		&i.DestinationType,
+		&i.DestinationConfig,
+		&i.OutputFormat,
+		&i.OutputTemplate,
+		&i.OutputContentType,
+		&i.DeliveryDelaySeconds,
+		&i.DeliverAtExpr,
+		&i.WindowMode,
+		&i.WindowKeyExpr,
+		&i.WindowSeconds,
+		&i.WindowMaxEvents,
+		&i.RateLimitPerSec,
+		&i.RateLimitBurst,
+		&i.RateLimitScope,
+		&i.Revision,
+		&i.PausedAt,
+		&i.RedactionRules,
 	)
 	return i, err
 }
 
+const getPipeSchedules = `-- name: GetPipeSchedules :many
+SELECT id, weight, max_concurrency
+FROM pipes
+WHERE id = ANY($1::uuid[])
+`
+
+type GetPipeSchedulesRow struct {
+	ID             uuid.UUID `json:"id"`
+	Weight         int32     `json:"weight"`
+	MaxConcurrency int32     `json:"max_concurrency"`
+}
+
+func (q *Queries) GetPipeSchedules(ctx context.Context, ids []uuid.UUID) ([]GetPipeSchedulesRow, error) {
+	rows, err := q.db.Query(ctx, getPipeSchedules, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetPipeSchedulesRow{}
+	for rows.Next() {
+		var i GetPipeSchedulesRow
+		if err := rows.Scan(&i.ID, &i.Weight, &i.MaxConcurrency); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPipeTLS = `-- name: GetPipeTLS :one
+SELECT tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version
+FROM pipes
+WHERE id = $1 AND deleted_at IS NULL
+LIMIT 1
+`
+
+type GetPipeTLSRow struct {
+	TlsClientCert *string  `json:"tls_client_cert"`
+	TlsClientKey  *string  `json:"-"`
+	TlsCaBundle   *string  `json:"tls_ca_bundle"`
+	TlsSpkiPins   []string `json:"tls_spki_pins"`
+	TlsMinVersion *string  `json:"tls_min_version"`
+}
+
+func (q *Queries) GetPipeTLS(ctx context.Context, id uuid.UUID) (GetPipeTLSRow, error) {
+	row := q.db.QueryRow(ctx, getPipeTLS, id)
+	var i GetPipeTLSRow
+	err := row.Scan(
+		&i.TlsClientCert,
+		&i.TlsClientKey,
+		&i.TlsCaBundle,
+		&i.TlsSpkiPins,
+		&i.TlsMinVersion,
+	)
+	return i, err
+}
+
+const listPausedPipes = `-- name: ListPausedPipes :many
+SELECT id FROM pipes
+WHERE paused_at IS NOT NULL
+  AND deleted_at IS NULL
+`
+
+func (q *Queries) ListPausedPipes(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listPausedPipes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPipes = `-- name: ListPipes :many
-SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at
+SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at, weight, max_concurrency, tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version, destination_type, destination_config, output_format, output_template, output_content_type, delivery_delay_seconds, deliver_at_expr, window_mode, window_key_expr, window_seconds, window_max_events, rate_limit_per_sec, rate_limit_burst, rate_limit_scope, revision, paused_at, redaction_rules
 FROM pipes
 WHERE user_id = $1
   AND deleted_at IS NULL
@@ -164,6 +356,30 @@ func (q *Queries) ListPipes(ctx context.Context, arg ListPipesParams) ([]Pipe, e
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.Weight,
+			&i.MaxConcurrency,
+			&i.TlsClientCert,
+			&i.TlsClientKey,
+			&i.TlsCaBundle,
+			&i.TlsSpkiPins,
+			&i.TlsMinVersion,
+			&i.DestinationType,
+			&i.DestinationConfig,
+			&i.OutputFormat,
+			&i.OutputTemplate,
+			&i.OutputContentType,
+			&i.DeliveryDelaySeconds,
+			&i.DeliverAtExpr,
+			&i.WindowMode,
+			&i.WindowKeyExpr,
+			&i.WindowSeconds,
+			&i.WindowMaxEvents,
+			&i.RateLimitPerSec,
+			&i.RateLimitBurst,
+			&i.RateLimitScope,
+			&i.Revision,
+			&i.PausedAt,
+			&i.RedactionRules,
 		); err != nil {
 			return nil, err
 		}
@@ -175,31 +391,97 @@ func (q *Queries) ListPipes(ctx context.Context, arg ListPipesParams) ([]Pipe, e
 	return items, nil
 }
 
-const updatePipe = `-- name: UpdatePipe :one
+const pausePipe = `-- name: PausePipe :execrows
 UPDATE pipes
-SET target_url = $3,
-    jq_filter = $4,
-    is_active = $5,
+SET paused_at = COALESCE(paused_at, NOW()),
     updated_at = NOW()
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-RETURNING id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at
+`
+
+type PausePipeParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) PausePipe(ctx context.Context, arg PausePipeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, pausePipe, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resumePipe = `-- name: ResumePipe :execrows
+UPDATE pipes
+SET paused_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type ResumePipeParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) ResumePipe(ctx context.Context, arg ResumePipeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, resumePipe, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePipe = `-- name: UpdatePipe :one
+WITH updated AS (
+    UPDATE pipes
+    SET name = $3,
+        slug = $4,
+        target_url = $5,
+        jq_filter = $6,
+        is_active = $7,
+        redaction_rules = $9,
+        revision = CASE
+            WHEN (name, slug, target_url, jq_filter, redaction_rules) IS DISTINCT FROM ($3, $4, $5, $6, $9::jsonb)
+            THEN revision + 1
+            ELSE revision
+        END,
+        updated_at = NOW()
+    WHERE id = $1 AND user_id = $2 AND revision = $8 AND deleted_at IS NULL
+    RETURNING id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at, weight, max_concurrency, tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version, destination_type, destination_config, output_format, output_template, output_content_type, delivery_delay_seconds, deliver_at_expr, window_mode, window_key_expr, window_seconds, window_max_events, rate_limit_per_sec, rate_limit_burst, rate_limit_scope, revision, paused_at, redaction_rules
+), recorded AS (
+    INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+    SELECT id, revision, user_id, 'update', pipe_config(to_jsonb(updated))
+    FROM updated
+    WHERE updated.revision <> $8
+)
+SELECT id, user_id, name, slug, target_url, jq_filter, is_active, created_at, updated_at, deleted_at, weight, max_concurrency, tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version, destination_type, destination_config, output_format, output_template, output_content_type, delivery_delay_seconds, deliver_at_expr, window_mode, window_key_expr, window_seconds, window_max_events, rate_limit_per_sec, rate_limit_burst, rate_limit_scope, revision, paused_at, redaction_rules FROM updated
 `
 
 type UpdatePipeParams struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	TargetUrl string    `json:"target_url"`
-	JqFilter  string    `json:"jq_filter"`
-	IsActive  bool      `json:"is_active"`
+	ID             uuid.UUID `json:"id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	TargetUrl      string    `json:"target_url"`
+	JqFilter       string    `json:"jq_filter"`
+	IsActive       bool      `json:"is_active"`
+	Revision       int32     `json:"revision"`
+	RedactionRules []byte    `json:"redaction_rules"`
 }
 
+// Updates the editable fields of a pipe last seen at revision $8. A
+// change to anything but is_active bumps the revision and records it.
 func (q *Queries) UpdatePipe(ctx context.Context, arg UpdatePipeParams) (Pipe, error) {
 	row := q.db.QueryRow(ctx, updatePipe,
 		arg.ID,
 		arg.UserID,
+		arg.Name,
+		arg.Slug,
 		arg.TargetUrl,
 		arg.JqFilter,
 		arg.IsActive,
+		arg.Revision,
+		arg.RedactionRules,
 	)
 	var i Pipe
 	err := row.Scan(
@@ -213,10 +495,62 @@ func (q *Queries) UpdatePipe(ctx context.Context, arg UpdatePipeParams) (Pipe, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Weight,
+		&i.MaxConcurrency,
+		&i.TlsClientCert,
+		&i.TlsClientKey,
+		&i.TlsCaBundle,
+		&i.TlsSpkiPins,
+		&i.TlsMinVersion,
+		&i.DestinationType,
+		&i.DestinationConfig,
+		&i.OutputFormat,
+		&i.OutputTemplate,
+		&i.OutputContentType,
+		&i.DeliveryDelaySeconds,
+		&i.DeliverAtExpr,
+		&i.WindowMode,
+		&i.WindowKeyExpr,
+		&i.WindowSeconds,
+		&i.WindowMaxEvents,
+		&i.RateLimitPerSec,
+		&i.RateLimitBurst,
+		&i.RateLimitScope,
+		&i.Revision,
+		&i.PausedAt,
+		&i.RedactionRules,
 	)
 	return i, err
 }
 
+const updatePipeFilter = `-- name: UpdatePipeFilter :execrows
+WITH updated AS (
+    UPDATE pipes
+    SET jq_filter = $3,
+        revision = revision + 1,
+        updated_at = NOW()
+    WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+    RETURNING *
+)
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+SELECT id, revision, user_id, 'update_filter', pipe_config(to_jsonb(updated))
+FROM updated
+`
+
+type UpdatePipeFilterParams struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	JqFilter string    `json:"jq_filter"`
+}
+
+func (q *Queries) UpdatePipeFilter(ctx context.Context, arg UpdatePipeFilterParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePipeFilter, arg.ID, arg.UserID, arg.JqFilter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const verifyPipeOwnership = `-- name: VerifyPipeOwnership :one
 SELECT EXISTS (
   SELECT 1
@@ -6,32 +6,61 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	CountPipeRevisions(ctx context.Context, pipeID uuid.UUID) (int64, error)
 	CountPipesByUser(ctx context.Context, userID uuid.UUID) (int64, error)
 	CreateEvent(ctx context.Context, arg CreateEventParams) error
+	CreateEventPartition(ctx context.Context, month time.Time) (string, error)
 	CreateEventsBatch(ctx context.Context, arg CreateEventsBatchParams) error
 	CreatePipe(ctx context.Context, arg CreatePipeParams) error
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) error
 	CreateUserReturning(ctx context.Context, arg CreateUserReturningParams) (User, error)
+	DeleteEvents(ctx context.Context, arg DeleteEventsParams) (int64, error)
+	DeleteExpiredEvents(ctx context.Context, arg DeleteExpiredEventsParams) (int64, error)
 	DeletePipe(ctx context.Context, arg DeletePipeParams) (int64, error)
+	DeletePipeFixture(ctx context.Context, arg DeletePipeFixtureParams) (int64, error)
+	DropEventPartition(ctx context.Context, partName string) error
+	GetEventForUser(ctx context.Context, arg GetEventForUserParams) (GetEventForUserRow, error)
 	GetPipeById(ctx context.Context, arg GetPipeByIdParams) (Pipe, error)
 	GetPipeBySlug(ctx context.Context, slug string) (Pipe, error)
+	GetPipeEvent(ctx context.Context, arg GetPipeEventParams) (Event, error)
+	GetPipeRevision(ctx context.Context, arg GetPipeRevisionParams) (PipeRevision, error)
+	GetPipeSchedules(ctx context.Context, ids []uuid.UUID) ([]GetPipeSchedulesRow, error)
+	GetPipeTLS(ctx context.Context, id uuid.UUID) (GetPipeTLSRow, error)
 	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	GetRetentionBounds(ctx context.Context) (GetRetentionBoundsRow, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserById(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByOAuth(ctx context.Context, arg GetUserByOAuthParams) (User, error)
+	// The user's plan and retention override, with the plan's retention.
+	GetUserRetention(ctx context.Context, id uuid.UUID) (GetUserRetentionRow, error)
+	HasEventsBetween(ctx context.Context, arg HasEventsBetweenParams) (bool, error)
 	IncrementUserTokenVersion(ctx context.Context, id uuid.UUID) error
+	ListDefaultEventMonths(ctx context.Context) ([]ListDefaultEventMonthsRow, error)
+	ListEventPartitions(ctx context.Context) ([]string, error)
 	ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error)
+	ListExpiredEvents(ctx context.Context, arg ListExpiredEventsParams) ([]Event, error)
+	ListPausedPipes(ctx context.Context) ([]uuid.UUID, error)
+	ListPipeEvents(ctx context.Context, arg ListPipeEventsParams) ([]Event, error)
+	ListPipeFixtures(ctx context.Context, pipeID uuid.UUID) ([]PipeFixture, error)
+	ListPipeRevisions(ctx context.Context, arg ListPipeRevisionsParams) ([]PipeRevision, error)
 	ListPipes(ctx context.Context, arg ListPipesParams) ([]Pipe, error)
 	LoginOAuthUser(ctx context.Context, arg LoginOAuthUserParams) (User, error)
+	PausePipe(ctx context.Context, arg PausePipeParams) (int64, error)
+	ResumePipe(ctx context.Context, arg ResumePipeParams) (int64, error)
 	RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error
 	RevokeRefreshToken(ctx context.Context, id uuid.UUID) error
+	RollbackPipe(ctx context.Context, arg RollbackPipeParams) (int64, error)
+	SetUserEventRetention(ctx context.Context, arg SetUserEventRetentionParams) (int64, error)
 	UpdatePipe(ctx context.Context, arg UpdatePipeParams) (Pipe, error)
+	UpdatePipeFilter(ctx context.Context, arg UpdatePipeFilterParams) (int64, error)
+	UpsertPipeFixture(ctx context.Context, arg UpsertPipeFixtureParams) (PipeFixture, error)
 	VerifyPipeOwnership(ctx context.Context, arg VerifyPipeOwnershipParams) (bool, error)
 }
 
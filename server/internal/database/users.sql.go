@@ -42,7 +42,7 @@ INSERT INTO users (
 ) VALUES (
     $1, $2, $3, $4
 )
-RETURNING id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version
+RETURNING id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version, plan, event_retention_days
 `
 
 type CreateUserReturningParams struct {
@@ -72,12 +72,14 @@ func (q *Queries) CreateUserReturning(ctx context.Context, arg CreateUserReturni
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.TokenVersion,
+		&i.Plan,
+		&i.EventRetentionDays,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version FROM users
+SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version, plan, event_retention_days FROM users
 WHERE email = $1 AND deleted_at IS NULL LIMIT 1
 `
 
@@ -96,12 +98,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.TokenVersion,
+		&i.Plan,
+		&i.EventRetentionDays,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version FROM users
+SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version, plan, event_retention_days FROM users
 WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
@@ -120,12 +124,14 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.TokenVersion,
+		&i.Plan,
+		&i.EventRetentionDays,
 	)
 	return i, err
 }
 
 const getUserByOAuth = `-- name: GetUserByOAuth :one
-SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version FROM users
+SELECT id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version, plan, event_retention_days FROM users
 WHERE oauth_provider = $1 AND oauth_provider_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
@@ -149,10 +155,33 @@ func (q *Queries) GetUserByOAuth(ctx context.Context, arg GetUserByOAuthParams)
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.TokenVersion,
+		&i.Plan,
+		&i.EventRetentionDays,
 	)
 	return i, err
 }
 
+const getUserRetention = `-- name: GetUserRetention :one
+SELECT u.plan, u.event_retention_days, rp.retention_days AS plan_retention_days
+FROM users u
+LEFT JOIN retention_plans rp ON rp.plan = u.plan
+WHERE u.id = $1 AND u.deleted_at IS NULL
+`
+
+type GetUserRetentionRow struct {
+	Plan               *string `json:"plan"`
+	EventRetentionDays *int32  `json:"event_retention_days"`
+	PlanRetentionDays  *int32  `json:"plan_retention_days"`
+}
+
+// The user's plan and retention override, with the plan's retention.
+func (q *Queries) GetUserRetention(ctx context.Context, id uuid.UUID) (GetUserRetentionRow, error) {
+	row := q.db.QueryRow(ctx, getUserRetention, id)
+	var i GetUserRetentionRow
+	err := row.Scan(&i.Plan, &i.EventRetentionDays, &i.PlanRetentionDays)
+	return i, err
+}
+
 const incrementUserTokenVersion = `-- name: IncrementUserTokenVersion :exec
 UPDATE users
 SET token_version = token_version + 1
@@ -183,7 +212,7 @@ DO UPDATE SET
     avatar_url = COALESCE(EXCLUDED.avatar_url, users.avatar_url),
     updated_at = NOW()
 WHERE users.deleted_at IS NULL
-RETURNING id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version
+RETURNING id, username, email, password, oauth_provider, oauth_provider_id, avatar_url, created_at, updated_at, deleted_at, token_version, plan, event_retention_days
 `
 
 type LoginOAuthUserParams struct {
@@ -215,6 +244,27 @@ func (q *Queries) LoginOAuthUser(ctx context.Context, arg LoginOAuthUserParams)
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.TokenVersion,
+		&i.Plan,
+		&i.EventRetentionDays,
 	)
 	return i, err
 }
+
+const setUserEventRetention = `-- name: SetUserEventRetention :execrows
+UPDATE users
+SET event_retention_days = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetUserEventRetentionParams struct {
+	ID                 uuid.UUID `json:"id"`
+	EventRetentionDays *int32    `json:"event_retention_days"`
+}
+
+func (q *Queries) SetUserEventRetention(ctx context.Context, arg SetUserEventRetentionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserEventRetention, arg.ID, arg.EventRetentionDays)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
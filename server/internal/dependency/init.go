@@ -67,9 +67,9 @@ func NewDependency(ctx context.Context) (*Dependency, error) {
 	authHandler := auth.NewAuthHandler(servicer.AuthService, googleProvider, logger)
 
 	userHandler := user.NewUserHandler(servicer.UserService, logger)
-	playgroundHandler := playground.NewPlaygroundHandler(logger)
+	playgroundHandler := playground.NewPlaygroundHandler(servicer.PipeService, logger)
 
-	workerRunner := worker.NewRunner(cache, querier, 5, logger, cfg)
+	workerRunner := worker.NewRunner(cache, querier, int64(cfg.Worker.MaxConcurrency), logger, cfg)
 
 	return &Dependency{
 		Cache:             cache,
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/MobasirSarkar/hookfilter/internal/service/ingest"
 	"github.com/MobasirSarkar/hookfilter/pkg/logger"
@@ -50,11 +51,17 @@ func (h *IngestHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.ProcessWebhook(r.Context(), slug, payload); err != nil {
+	if err := h.service.ProcessWebhook(r.Context(), slug, payload, filterHeaders(r.Header)); err != nil {
 		if errors.Is(err, ingest.ErrPipeNotFound) {
 			response.Error(w, http.StatusNotFound, "Webook endpoint not found or inactive", meta)
 			return
 		}
+		if errors.Is(err, ingest.ErrBufferFull) {
+			// senders retry 503s, by then the pipe may be resumed
+			w.Header().Set("Retry-After", "60")
+			response.Error(w, http.StatusServiceUnavailable, "Webhook endpoint is paused and its buffer is full", meta)
+			return
+		}
 		h.log.Errorf("[HANDLER] -> webhook process error -> %v", err)
 		response.Error(w, http.StatusInternalServerError, "Failed to process webhook", meta)
 		return
@@ -62,3 +69,43 @@ func (h *IngestHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	response.Message(w, http.StatusAccepted, "Webook queued for processing", meta)
 }
+
+// SECRET_HEADERS never reach filters, they would end up in queued tasks.
+var SECRET_HEADERS = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+}
+
+// SECRET_HEADER_SUFFIXES catch provider specific credentials such as
+// x-auth-token or x-client-secret.
+var SECRET_HEADER_SUFFIXES = []string{"-token", "-secret"}
+
+// secretHeader reports whether a lower-cased header carries a credential
+// or a signature (x-hub-signature-256, stripe-signature, ...).
+func secretHeader(name string) bool {
+	if SECRET_HEADERS[name] || strings.Contains(name, "signature") {
+		return true
+	}
+	for _, suffix := range SECRET_HEADER_SUFFIXES {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterHeaders flattens the request headers for $headers, lower-cased
+// and keeping the first value of each.
+func filterHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		name = strings.ToLower(name)
+		if len(values) == 0 || secretHeader(name) {
+			continue
+		}
+		out[name] = values[0]
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package ingest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFilterHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		kept bool
+	}{
+		{"Content-Type", true},
+		{"User-Agent", true},
+		{"X-GitHub-Event", true},
+		{"X-Request-Id", true},
+		{"Authorization", false},
+		{"Proxy-Authorization", false},
+		{"Cookie", false},
+		{"X-Api-Key", false},
+		{"X-Hub-Signature-256", false},
+		{"Stripe-Signature", false},
+		{"X-Signature", false},
+		{"X-Auth-Token", false},
+		{"X-Gitlab-Token", false},
+		{"X-Client-Secret", false},
+	}
+
+	h := http.Header{}
+	for _, tt := range tests {
+		h.Set(tt.name, "value")
+	}
+	got := filterHeaders(h)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, kept := got[strings.ToLower(tt.name)]; kept != tt.kept {
+				t.Errorf("kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package pipe
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListEvents pages through the pipe's event history, newest first.
+func (h *PipeHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	query, err := eventQuery(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error(), meta)
+		return
+	}
+
+	page, err := h.Service.ListEvents(r.Context(), pipeID, userID, query)
+	if err != nil {
+		h.eventError(w, err, "ListEvents", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, page, "events fetched successfully", meta)
+}
+
+// GetEvent returns one event with its original and transformed payload.
+func (h *PipeHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+	eventID, err := uuid.Parse(chi.URLParam(r, "eventID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid eventID", meta)
+		return
+	}
+
+	event, err := h.Service.GetEvent(r.Context(), pipeID, userID, eventID)
+	if err != nil {
+		h.eventError(w, err, "GetEvent", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, event, "event fetched successfully", meta)
+}
+
+func (h *PipeHandler) eventError(w http.ResponseWriter, err error, op string, meta *response.Metadata) {
+	switch {
+	case errors.Is(err, pipe.ErrPipeNotFound):
+		response.Error(w, http.StatusNotFound, "pipe not found", meta)
+	case errors.Is(err, pipe.ErrEventNotFound):
+		response.Error(w, http.StatusNotFound, "event not found", meta)
+	case errors.Is(err, pipe.ErrInvalidInput):
+		response.Error(w, http.StatusBadRequest, err.Error(), meta)
+	default:
+		h.log.Errorf("%s failed: %v", op, err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+	}
+}
+
+// eventQuery reads the event history filters from the query string:
+// cursor, limit, status, outcome, from and to (RFC 3339), path
+// (jsonpath), contains (JSON) and in (request, transformed or any).
+func eventQuery(r *http.Request) (pipe.EventQuery, error) {
+	q := r.URL.Query()
+	query := pipe.EventQuery{
+		Cursor:   q.Get("cursor"),
+		Limit:    50,
+		Outcome:  q.Get("outcome"),
+		Path:     q.Get("path"),
+		SearchIn: q.Get("in"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = int32(min(limit, pipe.MAX_EVENTS_PAGE))
+	}
+	if v := q.Get("status"); v != "" {
+		status, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return query, errors.New("invalid status")
+		}
+		code := int32(status)
+		query.StatusCode = &code
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &query.Since}, {"to", &query.Until}} {
+		v := q.Get(bound.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, errors.New("invalid " + bound.name + ", expected RFC 3339")
+		}
+		// created_at is stored in UTC without a zone
+		t = t.UTC()
+		*bound.dst = &t
+	}
+	if v := q.Get("contains"); v != "" {
+		if !json.Valid([]byte(v)) {
+			return query, errors.New("contains must be a JSON document")
+		}
+		query.Contains = json.RawMessage(v)
+	}
+
+	switch query.SearchIn {
+	case "", pipe.SEARCH_REQUEST, pipe.SEARCH_TRANSFORMED, pipe.SEARCH_ANY:
+	default:
+		return query, errors.New("in must be request, transformed or any")
+	}
+	return query, nil
+}
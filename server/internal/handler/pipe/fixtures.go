@@ -0,0 +1,190 @@
+package pipe
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListFixtures lists the pipe's filter test cases.
+func (h *PipeHandler) ListFixtures(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	fixtures, err := h.Service.ListFixtures(r.Context(), pipeID, userID)
+	if err != nil {
+		h.fixtureError(w, err, "ListFixtures", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, fixtures, "fixtures fetched successfully", meta)
+}
+
+// SaveFixture creates a fixture or replaces the one with the same name.
+func (h *PipeHandler) SaveFixture(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	var req FixtureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	fixture, err := h.Service.SaveFixture(r.Context(), pipeID, userID, pipe.SaveFixtureParams{
+		Name:          req.Name,
+		Input:         req.Input,
+		Headers:       req.Headers,
+		Expected:      req.Expected,
+		ExpectDropped: req.ExpectDropped,
+	})
+	if err != nil {
+		h.fixtureError(w, err, "SaveFixture", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, fixture, "fixture saved successfully", meta)
+}
+
+// DeleteFixture removes a fixture from the pipe.
+func (h *PipeHandler) DeleteFixture(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+	fixtureID, err := uuid.Parse(chi.URLParam(r, "fixtureID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid fixtureID", meta)
+		return
+	}
+
+	if err := h.Service.DeleteFixture(r.Context(), pipeID, userID, fixtureID); err != nil {
+		h.fixtureError(w, err, "DeleteFixture", meta)
+		return
+	}
+
+	response.Message(w, http.StatusOK, "fixture deleted successfully", meta)
+}
+
+// RunFixtures runs every fixture against the pipe's filter, or against
+// jq_filter from the body to try a change before saving it.
+func (h *PipeHandler) RunFixtures(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	var req RunFixturesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	report, err := h.Service.RunFixtures(r.Context(), pipeID, userID, req.JqFilter)
+	if err != nil {
+		h.fixtureError(w, err, "RunFixtures", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report, "fixtures run completed", meta)
+}
+
+// UpdateFilter replaces the pipe's jq filter once it passes all
+// fixtures, or regardless of them with force.
+func (h *PipeHandler) UpdateFilter(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	var req FilterUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	report, err := h.Service.UpdateFilter(r.Context(), pipeID, userID, req.JqFilter, req.Force)
+	if err != nil {
+		var failed *pipe.FixturesFailedError
+		if errors.As(err, &failed) {
+			response.ErrorDetails(w, http.StatusUnprocessableEntity, err.Error(), failed.Report, meta)
+			return
+		}
+		h.fixtureError(w, err, "UpdateFilter", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report, "filter updated successfully", meta)
+}
+
+func (h *PipeHandler) fixtureError(w http.ResponseWriter, err error, op string, meta *response.Metadata) {
+	var filterErr *pipe.FilterError
+	switch {
+	case errors.Is(err, pipe.ErrPipeNotFound):
+		response.Error(w, http.StatusNotFound, "pipe not found", meta)
+	case errors.Is(err, pipe.ErrFixtureNotFound):
+		response.Error(w, http.StatusNotFound, "fixture not found", meta)
+	case errors.As(err, &filterErr):
+		response.ErrorDetails(w, http.StatusBadRequest, err.Error(), filterErr, meta)
+	case errors.Is(err, pipe.ErrInvalidInput):
+		response.Error(w, http.StatusBadRequest, err.Error(), meta)
+	default:
+		h.log.Errorf("%s failed: %v", op, err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+	}
+}
+
+// pipeParams reads the authenticated user and the pipeID URL param,
+// writing the error response when either is missing.
+func pipeParams(w http.ResponseWriter, r *http.Request, meta *response.Metadata) (uuid.UUID, uuid.UUID, bool) {
+	userIDStr, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	pipeID, err := uuid.Parse(chi.URLParam(r, "pipeID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid pipeID", meta)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, pipeID, true
+}
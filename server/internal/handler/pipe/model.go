@@ -1,8 +1,134 @@
 package pipe
 
+import (
+	"encoding/json"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+)
+
 type PipeRequest struct {
 	Name      string `json:"name" validate:"required,min=3,max=50"`
 	Slug      string `json:"slug" validate:"required,min=3"`
-	TargetURL string `json:"target_url" validate:"required,url"`
+	TargetURL string `json:"target_url" validate:"omitempty,url"`
 	JqFilter  string `json:"jq_filter" validate:"omitempty,max=1000"`
+	// SamplePayloads are checked against jq_filter, not stored.
+	SamplePayloads []any `json:"sample_payloads" validate:"omitempty,max=20"`
+
+	// scheduling, optional
+	Weight         int32 `json:"weight" validate:"omitempty,min=1,max=100"`
+	MaxConcurrency int32 `json:"max_concurrency" validate:"omitempty,min=0,max=1000"`
+
+	TLS *TLSRequest `json:"tls" validate:"omitempty"`
+
+	// destination, defaults to http delivery to target_url
+	DestinationType   string          `json:"destination_type" validate:"omitempty,oneof=http redis_stream redis_list file log slack discord teams"`
+	DestinationConfig json.RawMessage `json:"destination_config"`
+
+	Output *OutputRequest `json:"output" validate:"omitempty"`
+
+	// delayed delivery, optional. deliver_at_expr is a jq expression
+	// yielding unix seconds/millis or an RFC 3339 timestamp.
+	DeliveryDelaySeconds int32  `json:"delivery_delay_seconds" validate:"omitempty,min=0,max=604800"`
+	DeliverAtExpr        string `json:"deliver_at_expr" validate:"omitempty,max=1000"`
+
+	Window *WindowRequest `json:"window" validate:"omitempty"`
+
+	RateLimit *RateLimitRequest `json:"rate_limit" validate:"omitempty"`
+
+	// redaction of stored and streamed payloads, see redact.Rule
+	RedactionRules redact.Rules `json:"redaction_rules" validate:"omitempty,max=50"`
+}
+
+// FixtureRequest is a filter test case. Exactly one of expected and
+// expect_dropped must be set; expected may be null.
+type FixtureRequest struct {
+	Name          string            `json:"name" validate:"required,min=1,max=100"`
+	Input         any               `json:"input" validate:"required"`
+	Headers       map[string]string `json:"headers" validate:"omitempty,max=50"`
+	Expected      json.RawMessage   `json:"expected"`
+	ExpectDropped bool              `json:"expect_dropped"`
+}
+
+// RunFixturesRequest optionally names a filter to run instead of the
+// pipe's current one.
+type RunFixturesRequest struct {
+	JqFilter *string `json:"jq_filter" validate:"omitempty,max=1000"`
+}
+
+// PipeUpdateRequest is a partial update, absent fields are left as
+// they are. Force saves a new jq_filter even when fixtures fail.
+type PipeUpdateRequest struct {
+	Name      *string `json:"name" validate:"omitempty,min=3,max=50"`
+	Slug      *string `json:"slug" validate:"omitempty,min=3"`
+	TargetURL *string `json:"target_url" validate:"omitempty,url"`
+	JqFilter  *string `json:"jq_filter" validate:"omitempty,max=1000"`
+	IsActive  *bool   `json:"is_active"`
+	Force     bool    `json:"force"`
+
+	// applies to events ingested from now on, queued ones keep theirs
+	RedactionRules *redact.Rules `json:"redaction_rules" validate:"omitempty,max=50"`
+}
+
+// FilterUpdateRequest replaces a pipe's filter; force saves it even if
+// fixtures fail.
+type FilterUpdateRequest struct {
+	JqFilter string `json:"jq_filter" validate:"max=1000"`
+	Force    bool   `json:"force"`
+}
+
+// RollbackRequest is the optional body of a rollback; force restores
+// the revision's filter even if fixtures fail.
+type RollbackRequest struct {
+	Force bool `json:"force"`
+}
+
+// RateLimitRequest paces deliveries to per_second with bursts of up to
+// burst. Scope destination shares the limit across pipes targeting the
+// same host.
+type RateLimitRequest struct {
+	PerSecond float64 `json:"per_second" validate:"required,gt=0,max=10000"`
+	Burst     int32   `json:"burst" validate:"omitempty,min=0,max=10000"`
+	Scope     string  `json:"scope" validate:"omitempty,oneof=pipe destination"`
+}
+
+// WindowRequest debounces or aggregates events. Events with the same
+// key_expr result share a window, which closes after seconds or once
+// max_events arrive, whichever comes first.
+type WindowRequest struct {
+	Mode      string `json:"mode" validate:"required,oneof=latest first collect"`
+	KeyExpr   string `json:"key_expr" validate:"omitempty,max=1000"`
+	Seconds   int32  `json:"seconds" validate:"omitempty,min=0,max=86400"`
+	MaxEvents int32  `json:"max_events" validate:"omitempty,min=0,max=10000"`
+}
+
+// OutputRequest selects how the transformed payload is encoded when it
+// is POSTed to target_url. Template is a Go text/template body.
+type OutputRequest struct {
+	Format      string `json:"format" validate:"omitempty,oneof=json form xml template"`
+	Template    string `json:"template" validate:"required_if=Format template,max=16384"`
+	ContentType string `json:"content_type" validate:"omitempty,max=255"`
+}
+
+// TLSRequest configures how the worker connects to the target.
+// PEM values are sent as strings; the client key is stored encrypted.
+type TLSRequest struct {
+	ClientCert string   `json:"client_cert" validate:"required_with=ClientKey,max=16384"`
+	ClientKey  string   `json:"client_key" validate:"required_with=ClientCert,max=16384"`
+	CABundle   string   `json:"ca_bundle" validate:"omitempty,max=65536"`
+	SPKIPins   []string `json:"spki_pins" validate:"omitempty,max=10,dive,base64"`
+	MinVersion string   `json:"min_version" validate:"omitempty,oneof=1.2 1.3"`
+}
+
+func (t *TLSRequest) profile() *tlsprofile.Profile {
+	if t == nil {
+		return nil
+	}
+	return &tlsprofile.Profile{
+		ClientCert: t.ClientCert,
+		ClientKey:  t.ClientKey,
+		CABundle:   t.CABundle,
+		SPKIPins:   t.SPKIPins,
+		MinVersion: t.MinVersion,
+	}
 }
@@ -0,0 +1,57 @@
+package pipe
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/google/uuid"
+)
+
+// PausePipe holds the pipe's deliveries while still accepting events.
+func (h *PipeHandler) PausePipe(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.PausePipe(r.Context(), pipeID, userID); err != nil {
+		h.pauseError(w, err, "PausePipe", meta)
+		return
+	}
+
+	response.Message(w, http.StatusOK, "pipe paused successfully", meta)
+}
+
+// ResumePipe delivers the events buffered while paused and restarts
+// normal delivery.
+func (h *PipeHandler) ResumePipe(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.ResumePipe(r.Context(), pipeID, userID); err != nil {
+		h.pauseError(w, err, "ResumePipe", meta)
+		return
+	}
+
+	response.Message(w, http.StatusOK, "pipe resumed successfully", meta)
+}
+
+func (h *PipeHandler) pauseError(w http.ResponseWriter, err error, op string, meta *response.Metadata) {
+	switch {
+	case errors.Is(err, pipe.ErrPipeNotFound):
+		response.Error(w, http.StatusNotFound, "pipe not found", meta)
+	case errors.Is(err, pipe.ErrInvalidInput):
+		response.Error(w, http.StatusBadRequest, err.Error(), meta)
+	default:
+		h.log.Errorf("%s failed: %v", op, err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"strconv"
 
 	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
 	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
 	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
 	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
 	"github.com/MobasirSarkar/hookfilter/pkg/validator"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -52,18 +56,61 @@ func (h *PipeHandler) CreatePipe(w http.ResponseWriter, r *http.Request) {
 		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
 		return
 	}
-	err = h.Service.CreatePipe(r.Context(), pipe.CreatePipeParams{
-		UserID:    userID,
-		Name:      req.Name,
-		Slug:      req.Slug,
-		TargetUrl: req.TargetURL,
-		JQFilter:  req.JqFilter,
-	})
+	params := pipe.CreatePipeParams{
+		UserID:         userID,
+		Name:           req.Name,
+		Slug:           req.Slug,
+		TargetUrl:      req.TargetURL,
+		JQFilter:       req.JqFilter,
+		SamplePayloads: req.SamplePayloads,
+		Weight:         req.Weight,
+		MaxConcurrency: req.MaxConcurrency,
+		TLS:            req.TLS.profile(),
+
+		DestinationType:   req.DestinationType,
+		DestinationConfig: req.DestinationConfig,
+
+		DeliveryDelaySeconds: req.DeliveryDelaySeconds,
+		DeliverAtExpr:        req.DeliverAtExpr,
+
+		RedactionRules: req.RedactionRules,
+	}
+	if req.Window != nil {
+		params.WindowMode = req.Window.Mode
+		params.WindowKeyExpr = req.Window.KeyExpr
+		params.WindowSeconds = req.Window.Seconds
+		params.WindowMaxEvents = req.Window.MaxEvents
+	}
+	if req.RateLimit != nil {
+		params.RateLimitPerSec = req.RateLimit.PerSecond
+		params.RateLimitBurst = req.RateLimit.Burst
+		params.RateLimitScope = req.RateLimit.Scope
+	}
+	if req.Output != nil {
+		params.OutputFormat = req.Output.Format
+		params.OutputTemplate = req.Output.Template
+		params.OutputContentType = req.Output.ContentType
+	}
+	err = h.Service.CreatePipe(r.Context(), params)
 	if err != nil {
 		if errors.Is(err, pipe.ErrPipeExists) {
 			response.Error(w, http.StatusConflict, "pipe already exists with same slug", meta)
 			return
 		}
+		var filterErr *pipe.FilterError
+		if errors.As(err, &filterErr) {
+			response.ErrorDetails(w, http.StatusBadRequest, err.Error(), filterErr, meta)
+			return
+		}
+		if errors.Is(err, pipe.ErrInvalidInput) ||
+			errors.Is(err, pipe.ErrTargetNotAllowed) ||
+			errors.Is(err, tlsprofile.ErrInvalidProfile) ||
+			errors.Is(err, model.ErrInvalidDestination) ||
+			errors.Is(err, outbound.ErrInvalidEncoding) ||
+			errors.Is(err, redact.ErrInvalidRules) {
+			response.Error(w, http.StatusBadRequest, err.Error(), meta)
+			return
+		}
 		h.log.Errorf("[HANDLER] -> failed to create pipe -> %v", err)
 		response.Error(w, http.StatusInternalServerError, "Internal server error", meta)
 		return
@@ -150,6 +197,62 @@ func (h *PipeHandler) GetPipeByID(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, pipeD, "pipe fetched successfully", meta)
 }
 
+// UpdatePipe applies a partial update to the pipe.
+func (h *PipeHandler) UpdatePipe(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	var req PipeUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	updated, err := h.Service.UpdatePipe(r.Context(), pipeID, userID, pipe.UpdatePipeParams{
+		Name:      req.Name,
+		Slug:      req.Slug,
+		TargetUrl: req.TargetURL,
+		JQFilter:  req.JqFilter,
+		IsActive:  req.IsActive,
+		Force:     req.Force,
+
+		RedactionRules: req.RedactionRules,
+	})
+	if err != nil {
+		var failed *pipe.FixturesFailedError
+		var filterErr *pipe.FilterError
+		switch {
+		case errors.Is(err, pipe.ErrPipeNotFound):
+			response.Error(w, http.StatusNotFound, "pipe not found", meta)
+		case errors.Is(err, pipe.ErrPipeExists):
+			response.Error(w, http.StatusConflict, "pipe already exists with same slug", meta)
+		case errors.Is(err, pipe.ErrPipeConflict):
+			response.Error(w, http.StatusConflict, "pipe was modified concurrently, retry", meta)
+		case errors.As(err, &failed):
+			response.ErrorDetails(w, http.StatusUnprocessableEntity, err.Error(), failed.Report, meta)
+		case errors.As(err, &filterErr):
+			response.ErrorDetails(w, http.StatusBadRequest, err.Error(), filterErr, meta)
+		case errors.Is(err, pipe.ErrInvalidInput), errors.Is(err, pipe.ErrTargetNotAllowed),
+			errors.Is(err, redact.ErrInvalidRules):
+			response.Error(w, http.StatusBadRequest, err.Error(), meta)
+		default:
+			h.log.Errorf("[HANDLER] -> failed to update pipe -> %v", err)
+			response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, updated, "pipe updated successfully", meta)
+}
+
 func (h *PipeHandler) DeletePipe(w http.ResponseWriter, r *http.Request) {
 	meta := &response.Metadata{RequestID: uuid.NewString()}
 
@@ -180,3 +283,38 @@ func (h *PipeHandler) DeletePipe(w http.ResponseWriter, r *http.Request) {
 
 	response.Message(w, http.StatusOK, "Pipe delete successfully", meta)
 }
+
+func (h *PipeHandler) GetQueueStats(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userIDStr, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+
+	pipeID, err := uuid.Parse(chi.URLParam(r, "pipeID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid pipeID", meta)
+		return
+	}
+
+	stats, err := h.Service.GetQueueStats(r.Context(), pipeID, userID)
+	if err != nil {
+		if errors.Is(err, pipe.ErrPipeNotFound) {
+			response.Error(w, http.StatusNotFound, "pipe not found", meta)
+			return
+		}
+		h.log.Errorf("GetQueueStats failed: %v", err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, stats, "queue stats fetched successfully", meta)
+}
@@ -0,0 +1,166 @@
+package pipe
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListRevisions lists the pipe's configuration revisions, newest first.
+func (h *PipeHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 5 {
+		limit = 5
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	total, revisions, err := h.Service.ListRevisions(r.Context(), pipeID, userID, int32(page), int32(limit))
+	if err != nil {
+		h.revisionError(w, err, "ListRevisions", meta)
+		return
+	}
+
+	meta.Pagination = &response.Pagination{
+		Page:       int32(page),
+		Pagesize:   int32(limit),
+		Totalpages: int32((total + int64(limit) - 1) / int64(limit)),
+		TotalData:  int32(total),
+	}
+
+	response.JSON(w, http.StatusOK, revisions, "revisions fetched successfully", meta)
+}
+
+// GetRevision returns one revision with its configuration.
+func (h *PipeHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+	revision, err := strconv.ParseInt(chi.URLParam(r, "revision"), 10, 32)
+	if err != nil || revision < 1 {
+		response.Error(w, http.StatusBadRequest, "invalid revision", meta)
+		return
+	}
+
+	rev, err := h.Service.GetRevision(r.Context(), pipeID, userID, int32(revision))
+	if err != nil {
+		h.revisionError(w, err, "GetRevision", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rev, "revision fetched successfully", meta)
+}
+
+// DiffRevisions compares the revisions in ?from= and ?to=, by default
+// the current revision and the one before it.
+func (h *PipeHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+
+	var bounds [2]int32
+	for i, name := range []string{"from", "to"} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			response.Error(w, http.StatusBadRequest, "invalid "+name+" revision", meta)
+			return
+		}
+		bounds[i] = int32(n)
+	}
+
+	diff, err := h.Service.DiffRevisions(r.Context(), pipeID, userID, bounds[0], bounds[1])
+	if err != nil {
+		h.revisionError(w, err, "DiffRevisions", meta)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, diff, "revisions compared successfully", meta)
+}
+
+// RollbackPipe restores an earlier revision's configuration. A body of
+// {"force": true} restores its filter even if fixtures fail.
+func (h *PipeHandler) RollbackPipe(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userID, pipeID, ok := pipeParams(w, r, meta)
+	if !ok {
+		return
+	}
+	revision, err := strconv.ParseInt(chi.URLParam(r, "revision"), 10, 32)
+	if err != nil || revision < 1 {
+		response.Error(w, http.StatusBadRequest, "invalid revision", meta)
+		return
+	}
+
+	var req RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	if err := h.Service.RollbackPipe(r.Context(), pipeID, userID, int32(revision), req.Force); err != nil {
+		var failed *pipe.FixturesFailedError
+		if errors.As(err, &failed) {
+			response.ErrorDetails(w, http.StatusUnprocessableEntity, err.Error(), failed.Report, meta)
+			return
+		}
+		h.revisionError(w, err, "RollbackPipe", meta)
+		return
+	}
+
+	response.Message(w, http.StatusOK, "pipe rolled back to revision "+strconv.Itoa(int(revision)), meta)
+}
+
+func (h *PipeHandler) revisionError(w http.ResponseWriter, err error, op string, meta *response.Metadata) {
+	var filterErr *pipe.FilterError
+	switch {
+	case errors.Is(err, pipe.ErrPipeNotFound):
+		response.Error(w, http.StatusNotFound, "pipe not found", meta)
+	case errors.Is(err, pipe.ErrRevisionNotFound):
+		response.Error(w, http.StatusNotFound, "revision not found", meta)
+	case errors.Is(err, pipe.ErrPipeExists):
+		response.Error(w, http.StatusConflict, "another pipe now uses this revision's slug", meta)
+	case errors.As(err, &filterErr):
+		response.ErrorDetails(w, http.StatusBadRequest, err.Error(), filterErr, meta)
+	case errors.Is(err, pipe.ErrTargetNotAllowed), errors.Is(err, pipe.ErrInvalidInput),
+		errors.Is(err, model.ErrInvalidDestination), errors.Is(err, outbound.ErrInvalidEncoding),
+		errors.Is(err, tlsprofile.ErrInvalidProfile), errors.Is(err, redact.ErrInvalidRules):
+		response.Error(w, http.StatusBadRequest, err.Error(), meta)
+	default:
+		h.log.Errorf("%s failed: %v", op, err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+	}
+}
@@ -0,0 +1,112 @@
+package pipe
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListScheduled lists the pipe's events that are waiting for their
+// delivery time.
+func (h *PipeHandler) ListScheduled(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userIDStr, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+
+	pipeID, err := uuid.Parse(chi.URLParam(r, "pipeID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid pipeID", meta)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 5 {
+		limit = 5
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	total, events, err := h.Service.ListScheduled(r.Context(), pipeID, userID, int32(page), int32(limit))
+	if err != nil {
+		if errors.Is(err, pipe.ErrPipeNotFound) {
+			response.Error(w, http.StatusNotFound, "pipe not found", meta)
+			return
+		}
+		h.log.Errorf("ListScheduled failed: %v", err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		return
+	}
+
+	meta.Pagination = &response.Pagination{
+		Page:       int32(page),
+		Pagesize:   int32(limit),
+		Totalpages: int32((total + int64(limit) - 1) / int64(limit)),
+		TotalData:  int32(total),
+	}
+
+	response.JSON(w, http.StatusOK, events, "scheduled events fetched successfully", meta)
+}
+
+// CancelScheduled drops a scheduled event before it is delivered.
+func (h *PipeHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userIDStr, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+
+	pipeID, err := uuid.Parse(chi.URLParam(r, "pipeID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid pipeID", meta)
+		return
+	}
+	eventID, err := uuid.Parse(chi.URLParam(r, "eventID"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid eventID", meta)
+		return
+	}
+
+	err = h.Service.CancelScheduled(r.Context(), pipeID, userID, eventID.String())
+	if err != nil {
+		if errors.Is(err, pipe.ErrPipeNotFound) {
+			response.Error(w, http.StatusNotFound, "pipe not found", meta)
+			return
+		}
+		if errors.Is(err, pipe.ErrScheduledNotFound) {
+			response.Error(w, http.StatusNotFound, "scheduled event not found or already delivered", meta)
+			return
+		}
+		h.log.Errorf("CancelScheduled failed: %v", err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		return
+	}
+
+	response.Message(w, http.StatusOK, "scheduled event cancelled", meta)
+}
@@ -1,10 +1,43 @@
 package playground
 
+import (
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+)
+
 type PlaygroundRequest struct {
-	Payload any    `json:"payload" validate:"required,max=1000"`
+	Payload any    `json:"payload" validate:"required_without=EventID,omitempty,max=1000"`
 	Filter  string `json:"filter" validate:"required"`
+	// EventID runs the filter against a stored event of one of the
+	// caller's pipes instead of Payload; it needs an authenticated user.
+	EventID string `json:"event_id" validate:"omitempty,uuid"`
+	// Vars override the sample values bound to $pipe, $event_id,
+	// $received_at and $headers.
+	Vars *PlaygroundVars `json:"vars,omitempty"`
+}
+
+type PlaygroundVars struct {
+	Pipe       *PlaygroundPipe   `json:"pipe,omitempty"`
+	EventID    string            `json:"event_id,omitempty"`
+	ReceivedAt *time.Time        `json:"received_at,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+type PlaygroundPipe struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
 }
 
 type PlaygroundResponse struct {
-	Result any `json:"result"`
+	// Result is the first output, what the worker would deliver.
+	Result  any   `json:"result"`
+	Outputs []any `json:"outputs"`
+	// Input is the stored payload when the request named an event.
+	Input any `json:"input,omitempty"`
+	// Error is set when the filter failed to compile or run.
+	Error           *jsonfilter.Diagnostic `json:"error,omitempty"`
+	CompileTimeMs   float64                `json:"compile_time_ms"`
+	ExecutionTimeMs float64                `json:"execution_time_ms"`
 }
@@ -1,9 +1,14 @@
 package playground
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
 	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
 	"github.com/MobasirSarkar/hookfilter/pkg/logger"
 	"github.com/MobasirSarkar/hookfilter/pkg/response"
@@ -13,13 +18,34 @@ import (
 
 var validate = validator.Validator()
 
+// PLAYGROUND_LIMITS are tighter than the worker's, the endpoint is public.
+var PLAYGROUND_LIMITS = jsonfilter.Limits{
+	Timeout:        500 * time.Millisecond,
+	MaxOutputBytes: 256 << 10,
+	MaxOutputs:     100,
+}
+
+// PLAYGROUND_CACHE_SIZE bounds the playground's own program cache. It is
+// kept apart from the worker's so anonymous callers can't churn out the
+// filters of live pipes.
+const PLAYGROUND_CACHE_SIZE = 128
+
+// EventSource loads stored events for the playground.
+type EventSource interface {
+	GetEventSample(ctx context.Context, eventID, userID uuid.UUID) (*pipe.EventSample, error)
+}
+
 type PlaygroundHandler struct {
-	log *logger.Logger
+	events  EventSource
+	filters *jsonfilter.Cache
+	log     *logger.Logger
 }
 
-func NewPlaygroundHandler(log *logger.Logger) *PlaygroundHandler {
+func NewPlaygroundHandler(events EventSource, log *logger.Logger) *PlaygroundHandler {
 	return &PlaygroundHandler{
-		log: log,
+		events:  events,
+		filters: jsonfilter.NewCache(PLAYGROUND_CACHE_SIZE),
+		log:     log,
 	}
 }
 
@@ -36,17 +62,118 @@ func (h *PlaygroundHandler) HandlePlayground(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	result, err := jsonfilter.Transform(req.Payload, req.Filter)
+	input := req.Payload
+	vars := sampleVars(req.Vars)
+	res := &PlaygroundResponse{Outputs: []any{}}
+
+	if req.EventID != "" {
+		sample, status, msg := h.loadEvent(r, req.EventID)
+		if sample == nil {
+			response.Error(w, status, msg, meta)
+			return
+		}
+		input = sample.Payload
+		res.Input = sample.Payload
+		vars = jsonfilter.Vars{
+			Pipe: jsonfilter.PipeMeta{
+				ID:   sample.PipeID.String(),
+				Name: sample.PipeName,
+				Slug: sample.PipeSlug,
+			},
+			EventID:    sample.EventID.String(),
+			ReceivedAt: sample.ReceivedAt,
+		}
+	}
+
+	start := time.Now()
+	prog, err := h.filters.Compile(req.Filter)
+	res.CompileTimeMs = elapsedMs(start)
+	if err != nil {
+		d := jsonfilter.Diagnose(err)
+		res.Error = &d
+		response.ErrorDetails(w, http.StatusBadRequest, err.Error(), res, meta)
+		return
+	}
+
+	start = time.Now()
+	outputs, err := prog.RunAll(r.Context(), input, vars, PLAYGROUND_LIMITS)
+	res.ExecutionTimeMs = elapsedMs(start)
 	if err != nil {
-		h.log.Errorf("[HANDLER] filteration failed -> %v", err)
-		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		if r.Context().Err() != nil {
+			return
+		}
+		// filter failures are the caller's, report them rather than log
+		d := jsonfilter.Diagnose(err)
+		res.Error = &d
+		response.ErrorDetails(w, http.StatusUnprocessableEntity, err.Error(), res, meta)
 		return
 	}
 
-	res := &PlaygroundResponse{
-		Result: result,
+	res.Outputs = outputs
+	if len(outputs) > 0 {
+		res.Result = outputs[0]
 	}
 
 	response.JSON(w, http.StatusOK, res, "filteration completed.", meta)
 
 }
+
+// loadEvent fetches a stored event of the authenticated user, or the
+// status and message to fail with.
+func (h *PlaygroundHandler) loadEvent(r *http.Request, id string) (*pipe.EventSample, int, string) {
+	uid, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		return nil, http.StatusUnauthorized, "sign in to use stored events"
+	}
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "unauthorized"
+	}
+
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid event_id"
+	}
+
+	sample, err := h.events.GetEventSample(r.Context(), eventID, userID)
+	if err != nil {
+		if errors.Is(err, pipe.ErrEventNotFound) {
+			return nil, http.StatusNotFound, "event not found"
+		}
+		h.log.Errorf("[HANDLER] failed to load playground event -> %v", err)
+		return nil, http.StatusInternalServerError, "internal server error"
+	}
+	return sample, 0, ""
+}
+
+func elapsedMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// sampleVars fills the filter variables the way the worker would for a
+// freshly received event, with whatever the request overrides.
+func sampleVars(in *PlaygroundVars) jsonfilter.Vars {
+	vars := jsonfilter.Vars{
+		Pipe: jsonfilter.PipeMeta{
+			ID:   uuid.Nil.String(),
+			Name: "Playground",
+			Slug: "playground",
+		},
+		EventID:    uuid.NewString(),
+		ReceivedAt: time.Now().UTC(),
+	}
+	if in == nil {
+		return vars
+	}
+	if in.Pipe != nil {
+		vars.Pipe = jsonfilter.PipeMeta(*in.Pipe)
+	}
+	if in.EventID != "" {
+		vars.EventID = in.EventID
+	}
+	if in.ReceivedAt != nil {
+		vars.ReceivedAt = *in.ReceivedAt
+	}
+	vars.Headers = in.Headers
+	return vars
+}
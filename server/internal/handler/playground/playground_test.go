@@ -0,0 +1,185 @@
+package playground_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/MobasirSarkar/hookfilter/internal/handler/playground"
+	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/internal/service/auth"
+	"github.com/MobasirSarkar/hookfilter/internal/service/pipe"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/jwt"
+	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ownerID = uuid.New()
+	eventID = uuid.New()
+)
+
+const validToken = "valid-token"
+
+type fakeAuth struct{ auth.IdentityService }
+
+func (fakeAuth) ValidateToken(_ context.Context, token string) (*jwt.Claims, error) {
+	if token != validToken {
+		return nil, errors.New("invalid token")
+	}
+	return &jwt.Claims{UserID: ownerID.String()}, nil
+}
+
+type fakeEvents struct{}
+
+func (fakeEvents) GetEventSample(_ context.Context, id, userID uuid.UUID) (*pipe.EventSample, error) {
+	if id != eventID || userID != ownerID {
+		return nil, pipe.ErrEventNotFound
+	}
+	return &pipe.EventSample{
+		EventID:    id,
+		PipeID:     uuid.New(),
+		PipeName:   "Orders",
+		PipeSlug:   "orders",
+		ReceivedAt: time.Now(),
+		Payload:    map[string]any{"total": 42.0},
+	}, nil
+}
+
+// counter stands in for redis behind the rate limiter.
+type counter struct {
+	cache.Cacher
+	hits map[string]int64
+}
+
+func (c *counter) IncrWithTTL(_ context.Context, key string, _ time.Duration) (int64, error) {
+	c.hits[key]++
+	return c.hits[key], nil
+}
+
+func newHandler() http.Handler {
+	h := playground.NewPlaygroundHandler(fakeEvents{}, logger.NewLogger(&config.Config{}))
+	return middleware.OptionalJWTMiddleware(fakeAuth{})(http.HandlerFunc(h.HandlePlayground))
+}
+
+type envelope struct {
+	Data  playground.PlaygroundResponse `json:"data"`
+	Error string                        `json:"error"`
+}
+
+func post(t *testing.T, h http.Handler, token, body string) (int, envelope) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/jq/playground", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var env envelope
+	if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return rec.Code, env
+}
+
+func TestHandlePlayground(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+		result any
+	}{
+		{
+			name:   "anonymous payload",
+			body:   `{"payload": {"a": 1}, "filter": ".a"}`,
+			status: http.StatusOK,
+			result: 1.0,
+		},
+		{
+			name:   "anonymous stored event",
+			body:   `{"event_id": "` + eventID.String() + `", "filter": ".total"}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid token",
+			token:  "forged",
+			body:   `{"payload": {"a": 1}, "filter": ".a"}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "authenticated payload",
+			token:  validToken,
+			body:   `{"payload": {"a": 1}, "filter": ".a"}`,
+			status: http.StatusOK,
+			result: 1.0,
+		},
+		{
+			name:   "authenticated stored event",
+			token:  validToken,
+			body:   `{"event_id": "` + eventID.String() + `", "filter": ".total"}`,
+			status: http.StatusOK,
+			result: 42.0,
+		},
+		{
+			name:   "someone else's event",
+			token:  validToken,
+			body:   `{"event_id": "` + uuid.NewString() + `", "filter": ".total"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "syntax error",
+			body:   `{"payload": {}, "filter": ".["}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "runaway filter",
+			body:   `{"payload": {}, "filter": "\"x\" * 2000000000"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	h := newHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, env := post(t, h, tt.token, tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", status, tt.status, env.Error)
+			}
+			if tt.status == http.StatusOK && env.Data.Result != tt.result {
+				t.Errorf("result = %v, want %v", env.Data.Result, tt.result)
+			}
+		})
+	}
+}
+
+func TestHandlePlaygroundReturnsStoredInput(t *testing.T) {
+	_, env := post(t, newHandler(), validToken, `{"event_id": "`+eventID.String()+`", "filter": "."}`)
+	input, ok := env.Data.Input.(map[string]any)
+	if !ok || input["total"] != 42.0 {
+		t.Errorf("input = %v, want the stored payload", env.Data.Input)
+	}
+}
+
+func TestPlaygroundRateLimitsBeforeAuth(t *testing.T) {
+	c := &counter{hits: map[string]int64{}}
+	h := middleware.RateLimit(c, 2, time.Minute)(newHandler())
+
+	body := `{"payload": {}, "filter": "."}`
+	for range 2 {
+		if status, _ := post(t, h, "forged", body); status != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", status, http.StatusUnauthorized)
+		}
+	}
+	if status, _ := post(t, h, validToken, body); status != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d once bad tokens used up the limit", status, http.StatusTooManyRequests)
+	}
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 
@@ -8,9 +9,18 @@ import (
 	"github.com/MobasirSarkar/hookfilter/internal/service/user"
 	"github.com/MobasirSarkar/hookfilter/pkg/logger"
 	"github.com/MobasirSarkar/hookfilter/pkg/response"
+	"github.com/MobasirSarkar/hookfilter/pkg/validator"
 	"github.com/google/uuid"
 )
 
+var validate = validator.Validator()
+
+// RetentionRequest sets how many days the caller's events are kept;
+// null goes back to their plan's retention.
+type RetentionRequest struct {
+	EventRetentionDays *int32 `json:"event_retention_days" validate:"omitempty,min=1"`
+}
+
 type UserHandler struct {
 	service user.Service
 	log     *logger.Logger
@@ -43,3 +53,59 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	response.JSON(w, http.StatusOK, profile, "user profile fetched", meta)
 }
+
+func (h *UserHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userId, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+	retention, err := h.service.GetRetention(r.Context(), userId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			response.Error(w, http.StatusNotFound, "user not found", meta)
+			return
+		}
+		h.log.Errorf("[HANDLER] -> failed to fetch event retention -> %v", err)
+		response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		return
+	}
+	response.JSON(w, http.StatusOK, retention, "event retention fetched", meta)
+}
+
+func (h *UserHandler) UpdateRetention(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+
+	userId, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", meta)
+		return
+	}
+
+	var req RetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request format", meta)
+		return
+	}
+
+	retention, err := h.service.UpdateRetention(r.Context(), userId, req.EventRetentionDays)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			response.Error(w, http.StatusNotFound, "user not found", meta)
+		case errors.Is(err, user.ErrRetentionTooLong):
+			response.Error(w, http.StatusUnprocessableEntity, err.Error(), meta)
+		default:
+			h.log.Errorf("[HANDLER] -> failed to update event retention -> %v", err)
+			response.Error(w, http.StatusInternalServerError, "internal server error", meta)
+		}
+		return
+	}
+	response.JSON(w, http.StatusOK, retention, "event retention updated", meta)
+}
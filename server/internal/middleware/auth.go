@@ -50,3 +50,18 @@ func UserIDFromContext(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(ctxUserID).(string)
 	return id, ok
 }
+
+// OptionalJWTMiddleware authenticates requests that carry a bearer token
+// and lets anonymous ones through without a user in the context.
+func OptionalJWTMiddleware(authSvc auth.IdentityService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := JWTMiddleware(authSvc)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/chatfmt"
+)
+
+const (
+	DestinationHTTP        = "http"
+	DestinationRedisStream = "redis_stream"
+	DestinationRedisList   = "redis_list"
+	DestinationFile        = "file"
+	DestinationLog         = "log"
+	DestinationSlack       = "slack"
+	DestinationDiscord     = "discord"
+	DestinationTeams       = "teams"
+)
+
+var (
+	ErrInvalidDestination = errors.New("invalid destination")
+
+	sinkKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+)
+
+// RedisDestinationConfig configures the redis_stream and redis_list sinks.
+// Keys are namespaced per user, see SinkKey.
+type RedisDestinationConfig struct {
+	Key string `json:"key"`
+	// MaxLen caps a stream's length (approximately), 0 means unbounded.
+	MaxLen int64 `json:"max_len"`
+}
+
+// FileDestinationConfig configures the append-only NDJSON file sink.
+// Path is relative to the user's own directory inside the operator
+// configured sink directory, see Resolve.
+type FileDestinationConfig struct {
+	Path string `json:"path"`
+	// MaxBytes rotates the file once it would grow past this size.
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxFiles is how many rotated files are kept.
+	MaxFiles int `json:"max_files"`
+}
+
+// ChatDestinationConfig is the message template used by the slack,
+// discord and teams destinations. The webhook URL is the pipe's target_url.
+type ChatDestinationConfig = chatfmt.Template
+
+// UsesTargetURL reports whether kind delivers to the pipe's target_url.
+func UsesTargetURL(kind string) bool {
+	switch kind {
+	case "", DestinationHTTP, DestinationSlack, DestinationDiscord, DestinationTeams:
+		return true
+	}
+	return false
+}
+
+// IsChatDestination reports whether kind is one of the chat formatters.
+func IsChatDestination(kind string) bool {
+	return kind == DestinationSlack || kind == DestinationDiscord || kind == DestinationTeams
+}
+
+// SinkKey returns the redis key a user's sink writes to, so pipes can
+// never write into HookFilter's own keys or another user's.
+func SinkKey(userID, key string) string {
+	return fmt.Sprintf("sink:%s:%s", userID, key)
+}
+
+// ValidateDestination checks that raw is a valid config for kind.
+// fileSinks reports whether the operator enabled file destinations.
+func ValidateDestination(kind string, raw json.RawMessage, fileSinks bool) error {
+	switch kind {
+	case "", DestinationHTTP, DestinationLog:
+		return nil
+	case DestinationRedisStream, DestinationRedisList:
+		var cfg RedisDestinationConfig
+		if err := decodeConfig(raw, &cfg); err != nil {
+			return err
+		}
+		if !sinkKeyPattern.MatchString(cfg.Key) {
+			return fmt.Errorf("%w: key must be 1-128 characters of letters, digits, '_', '.', ':' or '-'", ErrInvalidDestination)
+		}
+		if cfg.MaxLen < 0 {
+			return fmt.Errorf("%w: max_len must not be negative", ErrInvalidDestination)
+		}
+		return nil
+	case DestinationFile:
+		if !fileSinks {
+			return fmt.Errorf("%w: file destinations are disabled on this server", ErrInvalidDestination)
+		}
+		var cfg FileDestinationConfig
+		if err := decodeConfig(raw, &cfg); err != nil {
+			return err
+		}
+		if _, err := cfg.Resolve("/", "user"); err != nil {
+			return err
+		}
+		if cfg.MaxBytes < 0 || cfg.MaxFiles < 0 {
+			return fmt.Errorf("%w: max_bytes and max_files must not be negative", ErrInvalidDestination)
+		}
+		return nil
+	case DestinationSlack, DestinationDiscord, DestinationTeams:
+		var cfg ChatDestinationConfig
+		if err := decodeConfig(raw, &cfg); err != nil {
+			return err
+		}
+		if _, err := cfg.Compile(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDestination, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidDestination, kind)
+	}
+}
+
+// Resolve joins Path onto the user's directory in dir, so users never
+// share files, rejecting paths that escape it.
+func (c FileDestinationConfig) Resolve(dir, userID string) (string, error) {
+	if userID == "" || filepath.Base(userID) != userID || userID == ".." {
+		return "", fmt.Errorf("%w: file destinations need an owner", ErrInvalidDestination)
+	}
+	clean := filepath.Clean("/" + c.Path)
+	if c.Path == "" || clean == "/" || strings.HasSuffix(c.Path, "/") {
+		return "", fmt.Errorf("%w: path must name a file", ErrInvalidDestination)
+	}
+	if filepath.Clean(c.Path) != strings.TrimPrefix(clean, "/") {
+		return "", fmt.Errorf("%w: path must be relative and stay inside the sink directory", ErrInvalidDestination)
+	}
+	return filepath.Join(dir, userID, clean), nil
+}
+
+func decodeConfig(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package model
+
+// Rate limit scopes decide which deliveries share a token bucket.
+const (
+	// RateScopePipe gives every pipe its own bucket.
+	RateScopePipe = "pipe"
+	// RateScopeDestination shares one bucket across all of a user's
+	// pipes delivering to the same host.
+	RateScopeDestination = "destination"
+)
+
+func IsRateScope(scope string) bool {
+	return scope == RateScopePipe || scope == RateScopeDestination
+}
@@ -1,13 +1,56 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type WorkerTask struct {
 	EventID    string
 	RetryCount int
 	PipeID     uuid.UUID
 	UserID     uuid.UUID
-	TargetURL  string
-	JQFilter   string
-	Payload    any
+	// PipeRevision is the pipe configuration revision the task was
+	// ingested with, recorded on its event.
+	PipeRevision int32
+	// PipeName, PipeSlug and ReceivedAt are exposed to the filter as
+	// $pipe and $received_at.
+	PipeName   string
+	PipeSlug   string
+	ReceivedAt time.Time
+	// Headers are the webhook request headers a filter sees as $headers.
+	Headers   map[string]string `json:",omitempty"`
+	TargetURL string
+	JQFilter  string
+	Payload   any
+	// TLSProfile identifies the pipe's TLS settings at ingest time,
+	// empty when the destination uses system defaults.
+	TLSProfile string
+	// DestinationType selects the sink, empty means DestinationHTTP.
+	DestinationType   string
+	DestinationConfig json.RawMessage
+	// Output* select the body encoding for http delivery,
+	// empty means JSON.
+	OutputFormat      string
+	OutputTemplate    string
+	OutputContentType string
+	// Window* are set on the flush task of an aggregation window; its
+	// Payload is filled from the window when the task is processed.
+	WindowKey  string
+	WindowID   string
+	WindowMode string
+	// RateLimit* pace deliveries, zero RateLimitPerSec means unlimited.
+	RateLimitPerSec float64
+	RateLimitBurst  int
+	RateLimitScope  string
+	// RedactionRules are applied to the payloads stored on the event and
+	// streamed to the realtime socket, never to what is delivered. Like
+	// the rest of the pipe's settings they are the ones at ingest: tasks
+	// already queued or paused keep their rules when the pipe's change.
+	RedactionRules json.RawMessage `json:",omitempty"`
+	// RateReserved is set on a task deferred by the rate limiter, which
+	// already holds a token for its delivery time.
+	RateReserved bool
 }
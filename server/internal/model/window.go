@@ -0,0 +1,15 @@
+package model
+
+// Window modes decide what a closed window delivers.
+const (
+	// WindowLatest delivers the last event of the window.
+	WindowLatest = "latest"
+	// WindowFirst delivers the first event of the window.
+	WindowFirst = "first"
+	// WindowCollect delivers every event of the window as an array.
+	WindowCollect = "collect"
+)
+
+func IsWindowMode(mode string) bool {
+	return mode == WindowLatest || mode == WindowFirst || mode == WindowCollect
+}
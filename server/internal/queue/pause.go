@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/google/uuid"
+)
+
+const (
+	// PAUSED_KEY is the set of paused pipes. The dispatcher leaves their
+	// sub-queues alone, so tasks build up there in arrival order until
+	// the pipe is resumed.
+	PAUSED_KEY = "pipes:paused"
+)
+
+// Pause stops the dispatcher from taking the pipe's tasks.
+func Pause(ctx context.Context, c cache.Cacher, pipeIDs ...uuid.UUID) error {
+	if len(pipeIDs) == 0 {
+		return nil
+	}
+	members := make([]string, len(pipeIDs))
+	for i, id := range pipeIDs {
+		members[i] = id.String()
+	}
+	return c.SetAdd(ctx, PAUSED_KEY, members...)
+}
+
+// Resume hands the pipes' buffered tasks back to the dispatcher, which
+// drains them oldest first.
+func Resume(ctx context.Context, c cache.Cacher, pipeIDs ...uuid.UUID) error {
+	if len(pipeIDs) == 0 {
+		return nil
+	}
+	members := make([]string, len(pipeIDs))
+	for i, id := range pipeIDs {
+		members[i] = id.String()
+	}
+	return c.SetRemove(ctx, PAUSED_KEY, members...)
+}
+
+// Paused returns the set of paused pipes.
+func Paused(ctx context.Context, c cache.Cacher) (map[uuid.UUID]bool, error) {
+	members, err := c.SetMembers(ctx, PAUSED_KEY)
+	if err != nil {
+		return nil, err
+	}
+	paused := make(map[uuid.UUID]bool, len(members))
+	for _, m := range members {
+		if id, err := uuid.Parse(m); err == nil {
+			paused[id] = true
+		}
+	}
+	return paused, nil
+}
+
+// Oldest returns the task the pipe's sub-queue of base will hand out
+// next, which is the one that has waited longest.
+func Oldest(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID) (string, bool, error) {
+	return c.QueuePeek(ctx, PipeKey(base, pipeID))
+}
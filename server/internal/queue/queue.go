@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/google/uuid"
+)
+
+const (
+	// WEBHOOK_QUEUE_KEY is the base queue ingestion writes to.
+	WEBHOOK_QUEUE_KEY = "webhook_queue"
+)
+
+// PipeKey returns the per-pipe sub-queue of a base queue.
+func PipeKey(base string, pipeID uuid.UUID) string {
+	return fmt.Sprintf("%s:pipe:%s", base, pipeID.String())
+}
+
+// ActiveKey returns the set tracking which pipes of a base queue
+// currently have pending tasks.
+func ActiveKey(base string) string {
+	return fmt.Sprintf("%s:active", base)
+}
+
+// Push appends a task to the pipe's sub-queue of base and marks
+// the pipe as active so the dispatcher picks it up.
+func Push(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, raw string) error {
+	if err := c.QueuePush(ctx, PipeKey(base, pipeID), raw); err != nil {
+		return err
+	}
+	return c.SetAdd(ctx, ActiveKey(base), pipeID.String())
+}
+
+// Requeue hands a popped task back to the front of the queue it came
+// from: the pipe's sub-queue of base, or base itself for uuid.Nil.
+func Requeue(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, raw string) error {
+	if pipeID == uuid.Nil {
+		return c.QueuePushFront(ctx, base, raw)
+	}
+	if err := c.QueuePushFront(ctx, PipeKey(base, pipeID), raw); err != nil {
+		return err
+	}
+	return c.SetAdd(ctx, ActiveKey(base), pipeID.String())
+}
+
+// Drop discards every task pending for a pipe on base and stops
+// tracking it as active.
+func Drop(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID) error {
+	if err := c.Delete(ctx, PipeKey(base, pipeID)); err != nil {
+		return err
+	}
+	return c.SetRemove(ctx, ActiveKey(base), pipeID.String())
+}
+
+// Depth returns the number of tasks pending for a pipe on base.
+func Depth(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID) (int64, error) {
+	return c.QueueLen(ctx, PipeKey(base, pipeID))
+}
+
+const (
+	// DELAYED_STORE holds tasks scheduled for later delivery by their
+	// pipe, which users can list and cancel.
+	DELAYED_STORE = "delayed"
+	// DEFERRED_STORE holds tasks the worker put off itself, rate limited
+	// deliveries and retries parked at shutdown. They are not scheduled
+	// events and never show up in, or can be cancelled through, the API.
+	DEFERRED_STORE = "deferred"
+	// FLUSH_STORE holds the timed flush tasks of open aggregation
+	// windows. Like the deferred store it is internal: cancelling a
+	// flush through the API would strand the window's events.
+	FLUSH_STORE = "flush"
+)
+
+// delayedKeys lays out the delayed store of base.
+func delayedKeys(base string, pipeID uuid.UUID) cache.DelayedKeys {
+	return storeKeys(base, DELAYED_STORE, pipeID)
+}
+
+// storeKeys lays out a delayed store of base: a global schedule the
+// worker promotes from, a per-pipe index for listing and the task bodies.
+func storeKeys(base, store string, pipeID uuid.UUID) cache.DelayedKeys {
+	return cache.DelayedKeys{
+		Schedule:    fmt.Sprintf("%s:%s", base, store),
+		Tasks:       fmt.Sprintf("%s:%s:tasks", base, store),
+		Index:       fmt.Sprintf("%s:%s:pipe:%s", base, store, pipeID.String()),
+		IndexPrefix: fmt.Sprintf("%s:%s:pipe:", base, store),
+		QueuePrefix: fmt.Sprintf("%s:pipe:", base),
+		Active:      ActiveKey(base),
+	}
+}
+
+func delayedMember(pipeID uuid.UUID, eventID string) string {
+	return pipeID.String() + ":" + eventID
+}
+
+// Scheduled is a task held back until its delivery time.
+type Scheduled struct {
+	EventID   string
+	DeliverAt time.Time
+	Raw       string
+}
+
+// Schedule holds a task in the delayed store of base until at, when the
+// worker moves it onto the pipe's sub-queue.
+func Schedule(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, eventID string, at time.Time, raw string) error {
+	return c.DelayedAdd(ctx, delayedKeys(base, pipeID), delayedMember(pipeID, eventID), at, raw)
+}
+
+// Defer holds a task the worker put off in the internal deferred store
+// of base until at, when it is moved back onto the pipe's sub-queue.
+func Defer(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, eventID string, at time.Time, raw string) error {
+	return c.DelayedAdd(ctx, storeKeys(base, DEFERRED_STORE, pipeID), delayedMember(pipeID, eventID), at, raw)
+}
+
+// ScheduleFlush holds a window's flush task in the internal flush store
+// of base until at, when it is moved onto the pipe's sub-queue.
+func ScheduleFlush(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, windowID string, at time.Time, raw string) error {
+	return c.DelayedAdd(ctx, storeKeys(base, FLUSH_STORE, pipeID), delayedMember(pipeID, windowID), at, raw)
+}
+
+// CancelFlush drops a window's timed flush task, reporting false if it
+// was not pending.
+func CancelFlush(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, windowID string) (bool, error) {
+	return c.DelayedRemove(ctx, storeKeys(base, FLUSH_STORE, pipeID), delayedMember(pipeID, windowID))
+}
+
+// ListScheduled returns a page of the pipe's pending scheduled tasks,
+// earliest first, together with the total number pending.
+func ListScheduled(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, offset, limit int64) (int64, []Scheduled, error) {
+	total, entries, err := c.DelayedList(ctx, delayedKeys(base, pipeID), offset, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	prefix := delayedMember(pipeID, "")
+	out := make([]Scheduled, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, Scheduled{
+			EventID:   strings.TrimPrefix(e.Member, prefix),
+			DeliverAt: e.Due,
+			Raw:       e.Raw,
+		})
+	}
+	return total, out, nil
+}
+
+// CancelScheduled drops a scheduled task, reporting false if it was not
+// pending (unknown, already delivered or already cancelled).
+func CancelScheduled(ctx context.Context, c cache.Cacher, base string, pipeID uuid.UUID, eventID string) (bool, error) {
+	return c.DelayedRemove(ctx, delayedKeys(base, pipeID), delayedMember(pipeID, eventID))
+}
+
+// PromoteDue moves up to limit tasks of base that are due by now onto
+// their pipe sub-queues, from each of the scheduled, deferred and flush
+// stores. It returns the largest of the counts, so a caller promoting
+// in batches keeps going while any store has more due. Malformed
+// members don't stop the other stores; they come back joined as
+// cache.ErrMalformedDelayed once every store is done.
+func PromoteDue(ctx context.Context, c cache.Cacher, base string, now time.Time, limit int64) (int, error) {
+	most := 0
+	var malformed error
+	for _, store := range []string{DELAYED_STORE, DEFERRED_STORE, FLUSH_STORE} {
+		n, err := c.DelayedPromote(ctx, storeKeys(base, store, uuid.Nil), now, limit)
+		if errors.Is(err, cache.ErrMalformedDelayed) {
+			malformed = errors.Join(malformed, err)
+		} else if err != nil {
+			return most, err
+		}
+		most = max(most, n)
+	}
+	return most, malformed
+}
@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/google/uuid"
+)
+
+// WindowKey returns the key of the pipe's open window for a grouping
+// key, which may be any string produced by the pipe's key expression.
+func WindowKey(base string, pipeID uuid.UUID, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s:window:%s:%s", base, pipeID.String(), hex.EncodeToString(sum[:12]))
+}
+
+// AddToWindow adds a payload to the window at key, opening windowID if
+// no window is open. It returns the open window's id, whether this call
+// opened it and how many events it now holds.
+func AddToWindow(ctx context.Context, c cache.Cacher, key, mode, windowID, payload string, ttl time.Duration) (string, bool, int64, error) {
+	return c.WindowAdd(ctx, key, mode, windowID, payload, ttl)
+}
+
+// TakeWindow closes window windowID and returns its payloads. ok is
+// false if the window was already flushed by an earlier trigger.
+func TakeWindow(ctx context.Context, c cache.Cacher, key, windowID string) ([]string, bool, error) {
+	return c.WindowTake(ctx, key, windowID)
+}
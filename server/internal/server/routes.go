@@ -1,10 +1,12 @@
 package server
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"time"
 
 	"github.com/MobasirSarkar/hookfilter/internal/middleware"
+	"github.com/MobasirSarkar/hookfilter/pkg/metrics"
 	"github.com/MobasirSarkar/hookfilter/pkg/response"
 	"github.com/go-chi/chi/v5"
 	chiM "github.com/go-chi/chi/v5/middleware"
@@ -20,7 +22,7 @@ func (s *Server) MountRoutes(router chi.Router) http.Handler {
 	router.Use(chiM.RealIP)
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-User-ID"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -29,6 +31,14 @@ func (s *Server) MountRoutes(router chi.Router) http.Handler {
 
 	// public / webhook routes
 	router.Get("/health", s.Health)
+	// metrics carry pipe ids, the public listener only serves them to
+	// holders of the metrics token
+	if token := s.Dependencies.Config.Server.MetricsToken; token != "" {
+		router.Method(http.MethodGet, "/metrics", metricsHandler(token))
+	}
+	if s.Mode.runsWorker() {
+		router.Get("/health/worker", s.WorkerHealth)
+	}
 	s.IngestRoutes(router)
 	s.RealtimeRoutes(router)
 
@@ -44,6 +54,32 @@ func (s *Server) MountRoutes(router chi.Router) http.Handler {
 	return router
 }
 
+// MountWorkerRoutes mounts the routes served by a standalone worker process.
+func (s *Server) MountWorkerRoutes(router chi.Router) http.Handler {
+	router.Use(chiM.Recoverer)
+	router.Get("/health", s.WorkerHealth)
+	router.Method(http.MethodGet, "/metrics", metricsHandler(s.Dependencies.Config.Server.MetricsToken))
+	return router
+}
+
+// metricsHandler serves the metrics registry, requiring token as a
+// bearer token when it is set.
+func metricsHandler(token string) http.Handler {
+	next := metrics.Default.Handler()
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) ProtectedRotues(r chi.Router) {
 	r.Use(middleware.JWTMiddleware(s.Dependencies.AuthHandler.Service))
 
@@ -63,6 +99,23 @@ func (s *Server) PipeRoutes(router chi.Router) {
 		r.Post("/", handler.CreatePipe)
 		r.Get("/", handler.ListPipes)
 		r.Get("/{pipeID}", handler.GetPipeByID)
+		r.Patch("/{pipeID}", handler.UpdatePipe)
+		r.Post("/{pipeID}/pause", handler.PausePipe)
+		r.Post("/{pipeID}/resume", handler.ResumePipe)
+		r.Get("/{pipeID}/queue", handler.GetQueueStats)
+		r.Get("/{pipeID}/events", handler.ListEvents)
+		r.Get("/{pipeID}/events/{eventID}", handler.GetEvent)
+		r.Get("/{pipeID}/scheduled", handler.ListScheduled)
+		r.Delete("/{pipeID}/scheduled/{eventID}", handler.CancelScheduled)
+		r.Get("/{pipeID}/fixtures", handler.ListFixtures)
+		r.Post("/{pipeID}/fixtures", handler.SaveFixture)
+		r.Post("/{pipeID}/fixtures/run", handler.RunFixtures)
+		r.Delete("/{pipeID}/fixtures/{fixtureID}", handler.DeleteFixture)
+		r.Put("/{pipeID}/filter", handler.UpdateFilter)
+		r.Get("/{pipeID}/revisions", handler.ListRevisions)
+		r.Get("/{pipeID}/revisions/diff", handler.DiffRevisions)
+		r.Get("/{pipeID}/revisions/{revision}", handler.GetRevision)
+		r.Post("/{pipeID}/revisions/{revision}/rollback", handler.RollbackPipe)
 		r.Delete("/{pipeID}", handler.DeletePipe)
 	})
 }
@@ -114,6 +167,8 @@ func (s *Server) UserRoutes(router chi.Router) {
 	handler := s.Dependencies.UserHandler
 	router.Route("/users", func(r chi.Router) {
 		r.Get("/me", handler.GetProfile)
+		r.Get("/me/retention", handler.GetRetention)
+		r.Put("/me/retention", handler.UpdateRetention)
 	})
 }
 
@@ -122,7 +177,10 @@ func (s *Server) PlaygroundRoutes(router chi.Router) {
 	cache := s.Dependencies.Cache
 	standardLimiter := middleware.RateLimit(cache, 20, time.Minute)
 	router.Route("/jq", func(r chi.Router) {
+		// limit first so requests with bad tokens count against the IP too
 		r.Use(standardLimiter)
+		// anonymous use is fine, a token unlocks running stored events
+		r.Use(middleware.OptionalJWTMiddleware(s.Dependencies.AuthHandler.Service))
 		r.Post("/playground", handler.HandlePlayground)
 	})
 }
@@ -132,3 +190,13 @@ func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
 		RequestID: uuid.NewString(),
 	})
 }
+
+func (s *Server) WorkerHealth(w http.ResponseWriter, r *http.Request) {
+	meta := &response.Metadata{RequestID: uuid.NewString()}
+	status := s.Dependencies.Worker.Health()
+	if !status.Healthy {
+		response.JSON(w, http.StatusServiceUnavailable, status, "Worker is not polling", meta)
+		return
+	}
+	response.JSON(w, http.StatusOK, status, "Worker is running", meta)
+}
@@ -15,13 +15,47 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// Mode selects which parts of HookFilter the process runs.
+type Mode string
+
+const (
+	// ModeServe runs only the HTTP API and ingestion endpoints.
+	ModeServe Mode = "serve"
+	// ModeWorker runs only the background delivery worker.
+	ModeWorker Mode = "worker"
+	// ModeAll runs the HTTP server and the worker in one process.
+	ModeAll Mode = "all"
+)
+
+var ErrUnknownMode = errors.New("unknown mode, expected one of: serve, worker, all")
+
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeServe, ModeWorker, ModeAll:
+		return m, nil
+	case "":
+		return ModeAll, nil
+	default:
+		return "", ErrUnknownMode
+	}
+}
+
+func (m Mode) runsHTTP() bool {
+	return m == ModeServe || m == ModeAll
+}
+
+func (m Mode) runsWorker() bool {
+	return m == ModeWorker || m == ModeAll
+}
+
 type Server struct {
 	HttpServer   *http.Server
 	Dependencies *dependency.Dependency
 	Logger       *logger.Logger
+	Mode         Mode
 }
 
-func New() *Server {
+func New(mode Mode) *Server {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	cfg, err := config.Load()
@@ -38,13 +72,21 @@ func New() *Server {
 	server := &Server{
 		Dependencies: dependencies,
 		Logger:       log,
+		Mode:         mode,
 	}
 
 	router := chi.NewRouter()
-	server.MountRoutes(router)
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Hostname, cfg.Server.Port)
+	if mode.runsHTTP() {
+		server.MountRoutes(router)
+	} else {
+		// a standalone worker only exposes its health endpoint
+		server.MountWorkerRoutes(router)
+		addr = fmt.Sprintf("%s:%d", cfg.Server.Hostname, cfg.Worker.HealthPort)
+	}
 
 	server.HttpServer = &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Hostname, cfg.Server.Port),
+		Addr:         addr,
 		Handler:      router,
 		WriteTimeout: 30 * time.Second,
 		ReadTimeout:  30 * time.Second,
@@ -54,13 +96,15 @@ func New() *Server {
 }
 
 func (s *Server) Run() error {
-	s.Logger.Infof("[SERVER] -> Running at %s", s.HttpServer.Addr)
+	s.Logger.Infof("[SERVER] -> Running in %q mode at %s", s.Mode, s.HttpServer.Addr)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	s.Logger.Info("[WORKER] Starting background processor....")
-	s.Dependencies.Worker.Start(ctx, s.Dependencies.Config.Worker.Concurrency)
+	if s.Mode.runsWorker() {
+		s.Logger.Info("[WORKER] Starting background processor....")
+		s.Dependencies.Worker.Start(ctx, s.Dependencies.Config.Worker.Concurrency)
+	}
 
 	go func() {
 		if err := s.HttpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -78,17 +122,31 @@ func (s *Server) Run() error {
 
 	// stop http server
 	if err := s.HttpServer.Shutdown(shutCtx); err != nil {
-		s.Logger.Error("[SERVER] shutdown failed -> %v", err)
+		s.Logger.Errorf("[SERVER] shutdown failed -> %v", err)
 		return err
 	}
 
-	s.Logger.Info("[WORKER] Waiting for active tasks to finish...")
-	s.Dependencies.Worker.Stop()
-	s.Logger.Info("[WORKER] Stopped.")
+	if s.Mode.runsWorker() {
+		s.Logger.Info("[WORKER] Draining active tasks...")
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), s.Dependencies.Config.Worker.DrainTimeout)
+		report, err := s.Dependencies.Worker.Stop(drainCtx)
+		cancelDrain()
+		if err != nil {
+			s.Logger.Warnf("[WORKER] drain deadline exceeded, in-flight tasks cancelled -> %v", err)
+		}
+		s.Logger.Infow("[WORKER] Stopped.",
+			"requeued", report.Requeued(),
+			"buffered", report.Buffered,
+			"in_flight", report.InFlight,
+			"backoff", report.Backoff,
+			"failed", report.Failed,
+			"timed_out", report.TimedOut,
+		)
+	}
 
 	// close cache
 	if err := s.Dependencies.Cache.Close(); err != nil {
-		s.Logger.Error("[REDIS] close failed -> %v", err)
+		s.Logger.Errorf("[REDIS] close failed -> %v", err)
 		return err
 	}
 
@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+)
+
+// MAX_SCHEDULE_AHEAD caps how far in the future a payload may schedule
+// its own delivery.
+const MAX_SCHEDULE_AHEAD = 30 * 24 * time.Hour
+
+var errNoTimestamp = errors.New("expression did not yield a timestamp")
+
+// deliveryTime returns when a task should be delivered. A deliverAt
+// expression that yields a timestamp wins over the fixed delay; if it
+// yields nothing usable the fixed delay applies. A zero time means
+// deliver immediately. The expression runs under ctx and limits, like
+// the pipe's filter does in the worker.
+func deliveryTime(ctx context.Context, limits jsonfilter.Limits, delaySeconds int32, deliverAtExpr string, payload any, now time.Time) time.Time {
+	if deliverAtExpr != "" {
+		if at, err := evalTimestamp(ctx, limits, deliverAtExpr, payload); err == nil {
+			if at.After(now.Add(MAX_SCHEDULE_AHEAD)) {
+				at = now.Add(MAX_SCHEDULE_AHEAD)
+			}
+			if !at.After(now) {
+				return time.Time{}
+			}
+			return at
+		}
+	}
+	if delaySeconds > 0 {
+		return now.Add(time.Duration(delaySeconds) * time.Second)
+	}
+	return time.Time{}
+}
+
+// evalTimestamp runs expr against payload and accepts unix seconds,
+// unix milliseconds or an RFC 3339 string.
+func evalTimestamp(ctx context.Context, limits jsonfilter.Limits, expr string, payload any) (time.Time, error) {
+	prog, err := jsonfilter.Compile(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	v, err := prog.Run(ctx, payload, jsonfilter.Vars{}, limits)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch t := v.(type) {
+	case float64:
+		return fromUnix(t), nil
+	case int:
+		return fromUnix(float64(t)), nil
+	case string:
+		if ts, err := time.Parse(time.RFC3339, t); err == nil {
+			return ts, nil
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return fromUnix(f), nil
+		}
+	}
+	return time.Time{}, errNoTimestamp
+}
+
+// values past 1e12 can only be milliseconds (year 33658 in seconds)
+func fromUnix(f float64) time.Time {
+	if f > 1e12 {
+		return time.UnixMilli(int64(f))
+	}
+	return time.Unix(0, int64(f*float64(time.Second)))
+}
@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+)
+
+func TestDeliveryTime(t *testing.T) {
+	now := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	payload := map[string]any{
+		"remind_at": "2026-01-09T12:30:00Z",
+		"unix":      float64(now.Add(time.Hour).Unix()),
+		"millis":    float64(now.Add(2 * time.Hour).UnixMilli()),
+		"past":      "2020-01-01T00:00:00Z",
+		"far":       "2030-01-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name  string
+		delay int32
+		expr  string
+		want  time.Time
+	}{
+		{name: "immediate", want: time.Time{}},
+		{name: "fixed delay", delay: 600, want: now.Add(10 * time.Minute)},
+		{name: "rfc3339 expression", expr: ".remind_at", want: now.Add(30 * time.Minute)},
+		{name: "unix seconds", expr: ".unix", want: now.Add(time.Hour)},
+		{name: "unix millis", expr: ".millis", want: now.Add(2 * time.Hour)},
+		{name: "past timestamp delivers now", delay: 600, expr: ".past", want: time.Time{}},
+		{name: "capped ahead", expr: ".far", want: now.Add(MAX_SCHEDULE_AHEAD)},
+		{name: "missing field falls back to delay", delay: 60, expr: ".nope", want: now.Add(time.Minute)},
+		{name: "bad expression falls back to delay", delay: 60, expr: ".[", want: now.Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deliveryTime(context.Background(), jsonfilter.DefaultLimits, tt.delay, tt.expr, payload, now)
+			if !got.Equal(tt.want) {
+				t.Errorf("deliveryTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryTimeLimits(t *testing.T) {
+	now := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	limits := jsonfilter.DefaultLimits
+	limits.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	got := deliveryTime(context.Background(), limits, 60, "[range(1e9)] | length", nil, now)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("runaway expression ran for %v, want it stopped at the timeout", elapsed)
+	}
+	if want := now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("deliveryTime() = %v, want the fixed delay %v", got, want)
+	}
+}
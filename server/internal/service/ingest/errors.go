@@ -8,4 +8,7 @@ var (
 
 	// queue error code
 	ErrQueueErr = errors.New("failed to enqueue task")
+	// ErrBufferFull turns events away from a paused pipe whose buffer
+	// is at PAUSED_BUFFER_MAX.
+	ErrBufferFull = errors.New("paused pipe buffer is full")
 )
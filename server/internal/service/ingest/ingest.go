@@ -4,46 +4,83 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/MobasirSarkar/hookfilter/internal/cache"
 	db "github.com/MobasirSarkar/hookfilter/internal/database"
 	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+	"github.com/MobasirSarkar/hookfilter/pkg/utils"
 	"github.com/google/uuid"
 )
 
-const (
-	QUEUE_WEBOOK_KEY = "webhook_queue"
-)
-
 type Ingestor interface {
-	ProcessWebhook(ctx context.Context, slug string, payload any) error
+	ProcessWebhook(ctx context.Context, slug string, payload any, headers map[string]string) error
 }
 
 type IngestService struct {
-	querier db.Querier
-	cache   cache.Cacher
+	querier   db.Querier
+	cache     cache.Cacher
+	limits    jsonfilter.Limits
+	maxPaused int64
 }
 
-func NewIngestService(querier db.Querier, cache cache.Cacher) *IngestService {
+func NewIngestService(querier db.Querier, cache cache.Cacher, cfg *config.Config) *IngestService {
 	return &IngestService{
-		querier: querier,
-		cache:   cache,
+		querier:   querier,
+		cache:     cache,
+		limits:    jsonfilter.Limits(cfg.Filter),
+		maxPaused: int64(cfg.Worker.PausedBufferMax),
 	}
 }
 
-func (s *IngestService) ProcessWebhook(ctx context.Context, slug string, payload any) error {
+func (s *IngestService) ProcessWebhook(ctx context.Context, slug string, payload any, headers map[string]string) error {
 	pipe, err := s.querier.GetPipeBySlug(ctx, slug)
 	if err != nil {
 		return ErrPipeNotFound
 	}
 
+	if pipe.PausedAt != nil {
+		if err := s.checkBuffer(ctx, pipe.ID); err != nil {
+			return err
+		}
+	}
+
 	task := model.WorkerTask{
-		EventID:   uuid.NewString(),
-		PipeID:    pipe.ID,
-		UserID:    pipe.UserID,
-		TargetURL: pipe.TargetUrl,
-		JQFilter:  pipe.JqFilter,
-		Payload:   payload,
+		EventID:      uuid.NewString(),
+		PipeID:       pipe.ID,
+		UserID:       pipe.UserID,
+		PipeName:     pipe.Name,
+		PipeSlug:     pipe.Slug,
+		PipeRevision: pipe.Revision,
+		ReceivedAt:   time.Now().UTC(),
+		Headers:      headers,
+		TargetURL:    pipe.TargetUrl,
+		JQFilter:     pipe.JqFilter,
+		Payload:      payload,
+		TLSProfile: tlsprofile.Key(
+			utils.Deref(pipe.TlsClientCert),
+			utils.Deref(pipe.TlsClientKey),
+			utils.Deref(pipe.TlsCaBundle),
+			pipe.TlsSpkiPins,
+			utils.Deref(pipe.TlsMinVersion),
+		),
+		DestinationType:   pipe.DestinationType,
+		DestinationConfig: pipe.DestinationConfig,
+		OutputFormat:      pipe.OutputFormat,
+		OutputTemplate:    utils.Deref(pipe.OutputTemplate),
+		OutputContentType: utils.Deref(pipe.OutputContentType),
+		RateLimitPerSec:   pipe.RateLimitPerSec,
+		RateLimitBurst:    int(pipe.RateLimitBurst),
+		RateLimitScope:    pipe.RateLimitScope,
+		RedactionRules:    pipe.RedactionRules,
+	}
+
+	if mode := utils.Deref(pipe.WindowMode); mode != "" {
+		return s.addToWindow(ctx, pipe, task, mode)
 	}
 
 	taskJson, err := json.Marshal(task)
@@ -51,9 +88,31 @@ func (s *IngestService) ProcessWebhook(ctx context.Context, slug string, payload
 		return fmt.Errorf("marshaling error: %w", err)
 	}
 
-	if err := s.cache.QueuePush(ctx, QUEUE_WEBOOK_KEY, string(taskJson)); err != nil {
+	deliverAt := deliveryTime(ctx, s.limits, pipe.DeliveryDelaySeconds, utils.Deref(pipe.DeliverAtExpr), payload, task.ReceivedAt)
+	if !deliverAt.IsZero() {
+		if err := queue.Schedule(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipe.ID, task.EventID, deliverAt, string(taskJson)); err != nil {
+			return ErrQueueErr
+		}
+		return nil
+	}
+
+	if err := queue.Push(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipe.ID, string(taskJson)); err != nil {
 		return ErrQueueErr
 	}
 
 	return nil
 }
+
+// checkBuffer fails with ErrBufferFull once a paused pipe has
+// maxPaused tasks waiting. Concurrent requests can overshoot the cap
+// by a few; it is there to stop a forgotten pause from filling redis.
+func (s *IngestService) checkBuffer(ctx context.Context, pipeID uuid.UUID) error {
+	depth, err := queue.Depth(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipeID)
+	if err != nil {
+		return ErrQueueErr
+	}
+	if depth >= s.maxPaused {
+		return ErrBufferFull
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/google/uuid"
+)
+
+type slugQuerier struct {
+	db.Querier
+	pipe db.Pipe
+}
+
+func (q slugQuerier) GetPipeBySlug(context.Context, string) (db.Pipe, error) {
+	return q.pipe, nil
+}
+
+// listCache keeps queues in memory and ignores the active set.
+type listCache struct {
+	cache.Cacher
+	lists map[string][]string
+}
+
+func (c *listCache) QueuePush(_ context.Context, queue, val string) error {
+	c.lists[queue] = append(c.lists[queue], val)
+	return nil
+}
+
+func (c *listCache) QueueLen(_ context.Context, queue string) (int64, error) {
+	return int64(len(c.lists[queue])), nil
+}
+
+func (c *listCache) SetAdd(context.Context, string, ...string) error {
+	return nil
+}
+
+func TestProcessWebhookPausedBuffer(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Worker.PausedBufferMax = 2
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		pausedAt *time.Time
+		buffered int
+		wantErr  error
+	}{
+		{name: "paused with room", pausedAt: &now, buffered: 1},
+		{name: "paused and full", pausedAt: &now, buffered: 2, wantErr: ErrBufferFull},
+		{name: "running", buffered: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipe := db.Pipe{ID: uuid.New(), JqFilter: ".", PausedAt: tt.pausedAt}
+			c := &listCache{lists: map[string][]string{}}
+			key := queue.PipeKey(queue.WEBHOOK_QUEUE_KEY, pipe.ID)
+			for range tt.buffered {
+				c.lists[key] = append(c.lists[key], "{}")
+			}
+
+			s := NewIngestService(slugQuerier{pipe: pipe}, c, cfg)
+			err := s.ProcessWebhook(ctx, "orders", map[string]any{"a": 1}, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessWebhook() error = %v, want %v", err, tt.wantErr)
+			}
+
+			want := tt.buffered + 1
+			if tt.wantErr != nil {
+				want = tt.buffered
+			}
+			if got := len(c.lists[key]); got != want {
+				t.Errorf("queued %d tasks, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/MobasirSarkar/hookfilter/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// DEFAULT_WINDOW_TIMEOUT closes count-only windows that never fill up.
+	DEFAULT_WINDOW_TIMEOUT = time.Hour
+	// WINDOW_TTL_GRACE keeps window state around a while past its flush
+	// time in case the worker is briefly behind.
+	WINDOW_TTL_GRACE = time.Hour
+)
+
+// addToWindow adds the event to its pipe's open window. The event that
+// opens a window schedules the window's flush task; the event that
+// reaches the count limit pushes it for immediate delivery.
+func (s *IngestService) addToWindow(ctx context.Context, pipe db.Pipe, task model.WorkerTask, mode string) error {
+	key := queue.WindowKey(queue.WEBHOOK_QUEUE_KEY, pipe.ID, windowGroup(ctx, s.limits, utils.Deref(pipe.WindowKeyExpr), task.Payload))
+	payload, err := json.Marshal(task.Payload)
+	if err != nil {
+		return err
+	}
+
+	timeout := windowTimeout(pipe.WindowSeconds)
+	id, opened, count, err := queue.AddToWindow(ctx, s.cache, key, mode, uuid.NewString(), string(payload), timeout+WINDOW_TTL_GRACE)
+	if err != nil {
+		return ErrQueueErr
+	}
+
+	full := pipe.WindowMaxEvents > 0 && count == int64(pipe.WindowMaxEvents)
+	if !opened && !full {
+		return nil
+	}
+
+	flush := task
+	flush.EventID = id
+	flush.Payload = nil
+	flush.WindowKey = key
+	flush.WindowID = id
+	flush.WindowMode = mode
+	raw, err := json.Marshal(flush)
+	if err != nil {
+		return err
+	}
+
+	if full {
+		if !opened {
+			// the timed flush would find the window gone, drop it early
+			_, _ = queue.CancelFlush(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipe.ID, id)
+		}
+		if err := queue.Push(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipe.ID, string(raw)); err != nil {
+			return ErrQueueErr
+		}
+		return nil
+	}
+
+	if err := queue.ScheduleFlush(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipe.ID, id, time.Now().Add(timeout), string(raw)); err != nil {
+		return ErrQueueErr
+	}
+	return nil
+}
+
+// windowGroup evaluates the pipe's key expression. Events the expression
+// yields nothing for (or fails on) share a single window.
+func windowGroup(ctx context.Context, limits jsonfilter.Limits, expr string, payload any) string {
+	if expr == "" {
+		return ""
+	}
+	prog, err := jsonfilter.Compile(expr)
+	if err != nil {
+		return ""
+	}
+	v, err := prog.Run(ctx, payload, jsonfilter.Vars{}, limits)
+	if err != nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func windowTimeout(seconds int32) time.Duration {
+	if seconds <= 0 {
+		return DEFAULT_WINDOW_TIMEOUT
+	}
+	return time.Duration(seconds) * time.Second
+}
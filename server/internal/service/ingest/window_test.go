@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+)
+
+func TestWindowGroup(t *testing.T) {
+	payload := map[string]any{
+		"product": map[string]any{"id": float64(42), "sku": "A-1"},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{name: "no expression", expr: "", want: ""},
+		{name: "number key", expr: ".product.id", want: "42"},
+		{name: "string key", expr: ".product.sku", want: `"A-1"`},
+		{name: "composite key", expr: "[.product.id, .product.sku]", want: `[42,"A-1"]`},
+		{name: "empty output shares a window", expr: "empty", want: ""},
+		{name: "failing expression shares a window", expr: ".product.id.x", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowGroup(context.Background(), jsonfilter.DefaultLimits, tt.expr, payload); got != tt.want {
+				t.Errorf("windowGroup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowTimeout(t *testing.T) {
+	if got := windowTimeout(0); got != DEFAULT_WINDOW_TIMEOUT {
+		t.Errorf("count-only window timeout = %v, want %v", got, DEFAULT_WINDOW_TIMEOUT)
+	}
+	if got := windowTimeout(30); got != 30*time.Second {
+		t.Errorf("windowTimeout(30) = %v, want 30s", got)
+	}
+}
@@ -0,0 +1,243 @@
+package pipe
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// GetEventSample loads the original payload of an event on one of the
+// user's pipes, for trying filters against real traffic.
+func (s *PipeService) GetEventSample(ctx context.Context, eventID, userID uuid.UUID) (*EventSample, error) {
+	if eventID == uuid.Nil || userID == uuid.Nil {
+		return nil, ErrInvalidInput
+	}
+
+	row, err := s.querier.GetEventForUser(ctx, db.GetEventForUserParams{
+		ID:     eventID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	var payload any
+	if err := json.Unmarshal(row.RequestPayload, &payload); err != nil {
+		return nil, fmt.Errorf("decoding event payload: %w", err)
+	}
+
+	return &EventSample{
+		EventID:    row.ID,
+		PipeID:     row.PipeID,
+		PipeName:   row.PipeName,
+		PipeSlug:   row.PipeSlug,
+		ReceivedAt: row.CreatedAt,
+		Payload:    payload,
+	}, nil
+}
+
+const (
+	// an event's outcome follows from its recorded status code: 0 when
+	// it never reached the destination, the destination's status
+	// otherwise
+	OUTCOME_DELIVERED = "delivered"
+	OUTCOME_FAILED    = "failed"
+	OUTCOME_ERROR     = "error"
+
+	SEARCH_REQUEST     = "request"
+	SEARCH_TRANSFORMED = "transformed"
+	SEARCH_ANY         = "any"
+
+	MAX_EVENTS_PAGE = 100
+	// MAX_EVENT_PATH bounds the jsonpath a search may run against every
+	// stored payload of the pipe.
+	MAX_EVENT_PATH = 256
+)
+
+// pgErrInvalidJSONPath covers both codes postgres reports a malformed
+// jsonpath with.
+var pgErrInvalidJSONPath = map[string]bool{"42601": true, "22P02": true}
+
+// ListEvents returns a page of the pipe's event history, newest first.
+// The page's NextCursor continues where it stopped.
+func (s *PipeService) ListEvents(ctx context.Context, pipeID, userID uuid.UUID, query EventQuery) (*EventPage, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return nil, err
+	}
+
+	params := db.ListPipeEventsParams{
+		PipeID:     pipeID,
+		StatusCode: query.StatusCode,
+		Since:      query.Since,
+		Until:      query.Until,
+		SearchIn:   query.SearchIn,
+		Contains:   query.Contains,
+		PageLimit:  min(max(query.Limit, 1), MAX_EVENTS_PAGE) + 1,
+	}
+	if params.SearchIn == "" {
+		params.SearchIn = SEARCH_ANY
+	}
+	if query.Path != "" {
+		if err := validateEventPath(query.Path); err != nil {
+			return nil, err
+		}
+		params.Path = &query.Path
+	}
+
+	var err error
+	params.MinStatus, params.MaxStatus, err = outcomeRange(query.Outcome)
+	if err != nil {
+		return nil, err
+	}
+	if query.Cursor != "" {
+		at, id, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		params.CursorCreatedAt, params.CursorID = &at, &id
+	}
+
+	rows, err := s.querier.ListPipeEvents(ctx, params)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErrInvalidJSONPath[pgErr.Code] {
+			return nil, fmt.Errorf("%w: path: %s", ErrInvalidInput, pgErr.Message)
+		}
+		return nil, err
+	}
+
+	// one row past the page tells whether there is a next one
+	page := &EventPage{Events: make([]EventSummary, 0, len(rows))}
+	if len(rows) == int(params.PageLimit) {
+		rows = rows[:len(rows)-1]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	for _, row := range rows {
+		page.Events = append(page.Events, toEventSummary(row))
+	}
+	return page, nil
+}
+
+// validateEventPath rejects jsonpaths that are not worth sending to
+// postgres: ones that can't be valid, ones too long to be a search and
+// like_regex, whose patterns postgres runs without a time limit. The
+// rest of the syntax is left to postgres.
+func validateEventPath(path string) error {
+	if len(path) > MAX_EVENT_PATH {
+		return fmt.Errorf("%w: path must be at most %d characters", ErrInvalidInput, MAX_EVENT_PATH)
+	}
+	lower := strings.ToLower(strings.TrimSpace(path))
+	lower = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(lower, "strict"), "lax"))
+	if !strings.HasPrefix(lower, "$") {
+		return fmt.Errorf("%w: path must start with $", ErrInvalidInput)
+	}
+	if strings.Contains(lower, "like_regex") {
+		return fmt.Errorf("%w: path cannot use like_regex, use starts with or ==", ErrInvalidInput)
+	}
+	return nil
+}
+
+// GetEvent returns an event of the pipe with both payloads.
+func (s *PipeService) GetEvent(ctx context.Context, pipeID, userID, eventID uuid.UUID) (*EventRecord, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return nil, err
+	}
+	row, err := s.querier.GetPipeEvent(ctx, db.GetPipeEventParams{
+		ID:     eventID,
+		PipeID: pipeID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+	return &EventRecord{
+		EventSummary:       toEventSummary(row),
+		PipeID:             row.PipeID,
+		RequestPayload:     json.RawMessage(row.RequestPayload),
+		TransformedPayload: json.RawMessage(row.TransformedPayload),
+	}, nil
+}
+
+func toEventSummary(row db.Event) EventSummary {
+	return EventSummary{
+		ID:           row.ID,
+		StatusCode:   row.StatusCode,
+		Outcome:      outcomeOf(row.StatusCode),
+		PipeRevision: row.PipeRevision,
+		CreatedAt:    row.CreatedAt,
+	}
+}
+
+func outcomeOf(status int32) string {
+	switch {
+	case status == 0:
+		return OUTCOME_ERROR
+	case status >= 400:
+		return OUTCOME_FAILED
+	default:
+		return OUTCOME_DELIVERED
+	}
+}
+
+// outcomeRange is the status code range an outcome covers, the inverse
+// of outcomeOf. An empty outcome matches everything.
+func outcomeRange(outcome string) (*int32, *int32, error) {
+	bound := func(n int32) *int32 { return &n }
+	switch outcome {
+	case "":
+		return nil, nil, nil
+	case OUTCOME_ERROR:
+		return bound(0), bound(0), nil
+	case OUTCOME_FAILED:
+		return bound(400), nil, nil
+	case OUTCOME_DELIVERED:
+		return bound(1), bound(399), nil
+	}
+	return nil, nil, fmt.Errorf("%w: unknown outcome %q", ErrInvalidInput, outcome)
+}
+
+// encodeCursor packs the position of an event in the newest-first
+// order into an opaque token.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	micros, rest, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	n, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	id, err := uuid.Parse(rest)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	return time.UnixMicro(n).UTC(), id, nil
+}
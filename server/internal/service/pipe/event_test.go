@@ -0,0 +1,116 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestEventCursor(t *testing.T) {
+	at := time.Date(2026, 1, 15, 9, 30, 0, 123456000, time.UTC)
+	id := uuid.New()
+
+	gotAt, gotID, err := decodeCursor(encodeCursor(at, id))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if !gotAt.Equal(at) || gotID != id {
+		t.Errorf("decodeCursor() = %v, %v, want %v, %v", gotAt, gotID, at, id)
+	}
+
+	for _, bad := range []string{"%%%", "bm8tY29sb24", "eDoxMjM", "MTIzOm5vdC1hLXV1aWQ"} {
+		if _, _, err := decodeCursor(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidInput", bad, err)
+		}
+	}
+}
+
+func TestOutcomeRange(t *testing.T) {
+	inRange := func(status int32, lo, hi *int32) bool {
+		return (lo == nil || status >= *lo) && (hi == nil || status <= *hi)
+	}
+
+	for _, outcome := range []string{OUTCOME_DELIVERED, OUTCOME_FAILED, OUTCOME_ERROR} {
+		lo, hi, err := outcomeRange(outcome)
+		if err != nil {
+			t.Fatalf("outcomeRange(%q) error = %v", outcome, err)
+		}
+		for _, status := range []int32{0, 200, 204, 302, 399, 400, 429, 500, 503} {
+			if got, want := inRange(status, lo, hi), outcomeOf(status) == outcome; got != want {
+				t.Errorf("outcomeRange(%q) matches %d = %v, want %v", outcome, status, got, want)
+			}
+		}
+	}
+
+	if lo, hi, err := outcomeRange(""); lo != nil || hi != nil || err != nil {
+		t.Errorf("outcomeRange(\"\") = %v, %v, %v, want no bounds", lo, hi, err)
+	}
+	if _, _, err := outcomeRange("lost"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("outcomeRange(\"lost\") error = %v, want ErrInvalidInput", err)
+	}
+}
+
+// eventQuerier fails every event search the way postgres reports a
+// malformed jsonpath, counting the searches that reach it.
+type eventQuerier struct {
+	db.Querier
+	searches int
+}
+
+func (q *eventQuerier) VerifyPipeOwnership(context.Context, db.VerifyPipeOwnershipParams) (bool, error) {
+	return true, nil
+}
+
+func (q *eventQuerier) ListPipeEvents(context.Context, db.ListPipeEventsParams) ([]db.Event, error) {
+	q.searches++
+	return nil, &pgconn.PgError{Code: "42601", Message: `syntax error at or near "]" of jsonpath input`}
+}
+
+func TestListEventsPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		// sent reports whether the path should reach postgres
+		sent bool
+	}{
+		{name: "malformed", path: "$.items[", sent: true},
+		{name: "no root", path: ".total > 10"},
+		{name: "like_regex", path: `$.email ? (@ like_regex "(a+)+$")`},
+		{name: "like_regex in strict mode", path: `strict $.name ? (@ LIKE_REGEX "^a" flag "i")`},
+		{name: "too long", path: "$." + strings.Repeat("a", MAX_EVENT_PATH)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &eventQuerier{}
+			s := &PipeService{querier: q}
+			_, err := s.ListEvents(context.Background(), uuid.New(), uuid.New(), EventQuery{Path: tt.path})
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("ListEvents() error = %v, want ErrInvalidInput", err)
+			}
+			if sent := q.searches > 0; sent != tt.sent {
+				t.Errorf("path sent to postgres = %v, want %v", sent, tt.sent)
+			}
+		})
+	}
+}
+
+func TestValidateEventPath(t *testing.T) {
+	for _, path := range []string{
+		"$.type",
+		`$.customer ? (@.email starts with "ops@")`,
+		"lax $.items[*].sku",
+		"strict $.total",
+		`$.note ? (@ == "x")`,
+	} {
+		if err := validateEventPath(path); err != nil {
+			t.Errorf("validateEventPath(%q) error = %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/google/uuid"
+)
+
+// MAX_SAMPLE_PAYLOADS bounds how many samples a filter is checked against.
+const MAX_SAMPLE_PAYLOADS = 20
+
+// FilterError rejects a pipe's jq filter: either it does not compile
+// (Filter) or it fails on some of the sample payloads (Samples).
+type FilterError struct {
+	Filter  *jsonfilter.Diagnostic `json:"filter,omitempty"`
+	Samples []SampleFailure        `json:"samples,omitempty"`
+}
+
+// SampleFailure is a sample payload, by index, the filter failed on.
+type SampleFailure struct {
+	Index int `json:"index"`
+	jsonfilter.Diagnostic
+}
+
+func (e *FilterError) Error() string {
+	if e.Filter != nil {
+		if e.Filter.Line > 0 {
+			return fmt.Sprintf("jq_filter: line %d, column %d: %s", e.Filter.Line, e.Filter.Column, e.Filter.Message)
+		}
+		return "jq_filter: " + e.Filter.Message
+	}
+	return fmt.Sprintf("jq_filter: fails on %d of the sample payloads", len(e.Samples))
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// validateFilter compiles filter and runs it against samples the way
+// the worker would. A sample the filter drops is not a failure.
+func (s *PipeService) validateFilter(ctx context.Context, filter string, samples []any, pipe jsonfilter.PipeMeta) error {
+	prog, err := jsonfilter.Compile(filter)
+	if err != nil {
+		d := jsonfilter.Diagnose(err)
+		return &FilterError{Filter: &d}
+	}
+
+	if len(samples) > MAX_SAMPLE_PAYLOADS {
+		return fmt.Errorf("%w: at most %d sample payloads", ErrInvalidInput, MAX_SAMPLE_PAYLOADS)
+	}
+
+	var failures []SampleFailure
+	for i, sample := range samples {
+		vars := jsonfilter.Vars{Pipe: pipe, EventID: uuid.NewString(), ReceivedAt: time.Now().UTC()}
+		_, err := prog.Run(ctx, sample, vars, jsonfilter.Limits(s.Config.Filter))
+		if err == nil || errors.Is(err, jsonfilter.ErrEmptyOutput) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failures = append(failures, SampleFailure{Index: i, Diagnostic: jsonfilter.Diagnose(err)})
+	}
+	if len(failures) > 0 {
+		return &FilterError{Samples: failures}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package pipe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrFixtureNotFound = errors.New("fixture not found")
+	ErrFixturesFailed  = errors.New("filter fails pipe fixtures")
+)
+
+// MAX_FIXTURES bounds the test cases a pipe may hold.
+const MAX_FIXTURES = 50
+
+// FixturesFailedError rejects a filter update; Report says which
+// fixtures failed.
+type FixturesFailedError struct {
+	Report *FixtureReport
+}
+
+func (e *FixturesFailedError) Error() string {
+	return fmt.Sprintf("jq_filter fails %d of %d fixtures", e.Report.Failed, e.Report.Total)
+}
+
+func (e *FixturesFailedError) Unwrap() error {
+	return ErrFixturesFailed
+}
+
+func (s *PipeService) ListFixtures(ctx context.Context, pipeID, userID uuid.UUID) ([]Fixture, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return nil, err
+	}
+	rows, err := s.querier.ListPipeFixtures(ctx, pipeID)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make([]Fixture, 0, len(rows))
+	for _, row := range rows {
+		fixtures = append(fixtures, toFixture(row))
+	}
+	return fixtures, nil
+}
+
+// SaveFixture creates the named fixture or replaces it.
+func (s *PipeService) SaveFixture(ctx context.Context, pipeID, userID uuid.UUID, params SaveFixtureParams) (*Fixture, error) {
+	if params.Name == "" {
+		return nil, fmt.Errorf("%w: fixture name is required", ErrInvalidInput)
+	}
+	if params.ExpectDropped == (params.Expected == nil) {
+		return nil, fmt.Errorf("%w: set either expected or expect_dropped", ErrInvalidInput)
+	}
+	if params.Expected != nil && !json.Valid(params.Expected) {
+		return nil, fmt.Errorf("%w: expected is not valid JSON", ErrInvalidInput)
+	}
+
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return nil, err
+	}
+	existing, err := s.querier.ListPipeFixtures(ctx, pipeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MAX_FIXTURES && !hasFixture(existing, params.Name) {
+		return nil, fmt.Errorf("%w: a pipe holds at most %d fixtures", ErrInvalidInput, MAX_FIXTURES)
+	}
+
+	input, err := json.Marshal(params.Input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: input: %v", ErrInvalidInput, err)
+	}
+	headers := make(map[string]string, len(params.Headers))
+	for k, v := range params.Headers {
+		headers[strings.ToLower(k)] = v
+	}
+	headerBytes, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := s.querier.UpsertPipeFixture(ctx, db.UpsertPipeFixtureParams{
+		PipeID:        pipeID,
+		Name:          params.Name,
+		Input:         input,
+		Headers:       headerBytes,
+		Expected:      params.Expected,
+		ExpectDropped: params.ExpectDropped,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fixture := toFixture(row)
+	return &fixture, nil
+}
+
+func (s *PipeService) DeleteFixture(ctx context.Context, pipeID, userID, fixtureID uuid.UUID) error {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return err
+	}
+	rows, err := s.querier.DeletePipeFixture(ctx, db.DeletePipeFixtureParams{
+		ID:     fixtureID,
+		PipeID: pipeID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrFixtureNotFound
+	}
+	return nil
+}
+
+// RunFixtures runs the pipe's fixtures against filter, or against the
+// pipe's current filter when filter is nil.
+func (s *PipeService) RunFixtures(ctx context.Context, pipeID, userID uuid.UUID, filter *string) (*FixtureReport, error) {
+	pipe, err := s.getPipe(ctx, pipeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = &pipe.JqFilter
+	}
+	return s.runPipeFixtures(ctx, pipe, *filter)
+}
+
+// UpdateFilter replaces the pipe's jq filter. The update is refused
+// with a *FixturesFailedError when a fixture fails, unless force is set;
+// the report is returned either way.
+func (s *PipeService) UpdateFilter(ctx context.Context, pipeID, userID uuid.UUID, filter string, force bool) (*FixtureReport, error) {
+	if filter == "" {
+		filter = "."
+	}
+	pipe, err := s.getPipe(ctx, pipeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.runPipeFixtures(ctx, pipe, filter)
+	if err != nil {
+		return nil, err
+	}
+	if report.Failed > 0 && !force {
+		return report, &FixturesFailedError{Report: report}
+	}
+	if filter == pipe.JqFilter {
+		// nothing to save, and no empty revision
+		return report, nil
+	}
+
+	rows, err := s.querier.UpdatePipeFilter(ctx, db.UpdatePipeFilterParams{
+		ID:       pipeID,
+		UserID:   userID,
+		JqFilter: filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrPipeNotFound
+	}
+	s.invalidatePipeList(ctx, userID)
+	return report, nil
+}
+
+func (s *PipeService) getPipe(ctx context.Context, pipeID, userID uuid.UUID) (*db.Pipe, error) {
+	if pipeID == uuid.Nil || userID == uuid.Nil {
+		return nil, ErrInvalidInput
+	}
+	pipe, err := s.querier.GetPipeById(ctx, db.GetPipeByIdParams{
+		ID:     pipeID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPipeNotFound
+		}
+		return nil, err
+	}
+	return &pipe, nil
+}
+
+func (s *PipeService) runPipeFixtures(ctx context.Context, pipe *db.Pipe, filter string) (*FixtureReport, error) {
+	prog, err := jsonfilter.Compile(filter)
+	if err != nil {
+		d := jsonfilter.Diagnose(err)
+		return nil, &FilterError{Filter: &d}
+	}
+	fixtures, err := s.querier.ListPipeFixtures(ctx, pipe.ID)
+	if err != nil {
+		return nil, err
+	}
+	meta := jsonfilter.PipeMeta{ID: pipe.ID.String(), Name: pipe.Name, Slug: pipe.Slug}
+	report := runFixtures(ctx, prog, fixtures, meta, jsonfilter.Limits(s.Config.Filter))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	report.Filter = filter
+	return report, nil
+}
+
+// runFixtures runs every fixture through prog the way the worker runs
+// an event and compares the first output with the expectation.
+func runFixtures(ctx context.Context, prog *jsonfilter.Program, fixtures []db.PipeFixture, pipe jsonfilter.PipeMeta, limits jsonfilter.Limits) *FixtureReport {
+	report := &FixtureReport{Total: len(fixtures), Results: make([]FixtureResult, 0, len(fixtures))}
+	for _, f := range fixtures {
+		res := runFixture(ctx, prog, f, pipe, limits)
+		if res.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+func runFixture(ctx context.Context, prog *jsonfilter.Program, f db.PipeFixture, pipe jsonfilter.PipeMeta, limits jsonfilter.Limits) FixtureResult {
+	res := FixtureResult{FixtureID: f.ID, Name: f.Name, Expected: json.RawMessage(f.Expected)}
+
+	var input any
+	if err := json.Unmarshal(f.Input, &input); err != nil {
+		res.Error = &jsonfilter.Diagnostic{Kind: "runtime", Message: "stored input is not valid JSON"}
+		return res
+	}
+	var headers map[string]string
+	_ = json.Unmarshal(f.Headers, &headers)
+
+	vars := jsonfilter.Vars{
+		Pipe:       pipe,
+		EventID:    uuid.NewString(),
+		ReceivedAt: time.Now().UTC(),
+		Headers:    headers,
+	}
+	out, err := prog.Run(ctx, input, vars, limits)
+	switch {
+	case errors.Is(err, jsonfilter.ErrEmptyOutput):
+		res.Dropped = true
+		res.Passed = f.ExpectDropped
+	case err != nil:
+		d := jsonfilter.Diagnose(err)
+		res.Error = &d
+	default:
+		res.Output = out
+		res.Passed = !f.ExpectDropped && sameJSON(out, f.Expected)
+	}
+	return res
+}
+
+// sameJSON compares a filter output with an expected JSON document,
+// ignoring key order and number representation.
+func sameJSON(out any, expected []byte) bool {
+	if expected == nil {
+		return false
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return false
+	}
+	var got, want any
+	if json.Unmarshal(b, &got) != nil || json.Unmarshal(expected, &want) != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func hasFixture(fixtures []db.PipeFixture, name string) bool {
+	for _, f := range fixtures {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func toFixture(row db.PipeFixture) Fixture {
+	f := Fixture{
+		ID:            row.ID,
+		Name:          row.Name,
+		Input:         json.RawMessage(row.Input),
+		Expected:      json.RawMessage(row.Expected),
+		ExpectDropped: row.ExpectDropped,
+		UpdatedAt:     row.UpdatedAt,
+	}
+	_ = json.Unmarshal(row.Headers, &f.Headers)
+	return f
+}
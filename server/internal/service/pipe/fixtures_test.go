@@ -0,0 +1,44 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+)
+
+func TestRunFixtures(t *testing.T) {
+	prog, err := jsonfilter.Compile(`select(.action == "opened") | {id: .id, event: $headers["x-event"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []db.PipeFixture{
+		{Name: "match", Input: []byte(`{"action":"opened","id":1}`), Headers: []byte(`{"x-event":"issue"}`),
+			Expected: []byte(`{"event":"issue","id":1.0}`)},
+		{Name: "dropped", Input: []byte(`{"action":"closed","id":2}`), Headers: []byte(`{}`), ExpectDropped: true},
+		{Name: "wrong output", Input: []byte(`{"action":"opened","id":3}`), Headers: []byte(`{}`),
+			Expected: []byte(`{"event":null,"id":4}`)},
+		{Name: "not dropped", Input: []byte(`{"action":"opened","id":5}`), Headers: []byte(`{}`), ExpectDropped: true},
+		{Name: "null expected", Input: []byte(`{"action":"closed"}`), Headers: []byte(`{}`), Expected: []byte(`null`)},
+	}
+
+	report := runFixtures(context.Background(), prog, fixtures, jsonfilter.PipeMeta{}, jsonfilter.DefaultLimits)
+
+	want := map[string]bool{
+		"match":         true,
+		"dropped":       true,
+		"wrong output":  false,
+		"not dropped":   false,
+		"null expected": false,
+	}
+	if report.Total != 5 || report.Passed != 2 || report.Failed != 3 {
+		t.Errorf("report = %d/%d/%d, want 5 total, 2 passed, 3 failed", report.Total, report.Passed, report.Failed)
+	}
+	for _, res := range report.Results {
+		if res.Passed != want[res.Name] {
+			t.Errorf("%s: passed = %v, want %v (output %v, error %v)", res.Name, res.Passed, want[res.Name], res.Output, res.Error)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package pipe
 
 import (
+	"time"
+
+	"encoding/json"
+
 	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
 	"github.com/google/uuid"
 )
 
@@ -11,6 +18,200 @@ type CreatePipeParams struct {
 	Slug      string
 	TargetUrl string
 	JQFilter  string
+	// SamplePayloads, when set, are run through JQFilter before the
+	// pipe is saved; they are not stored.
+	SamplePayloads []any
+	Weight         int32
+	MaxConcurrency int32
+	TLS            *tlsprofile.Profile
+
+	// DestinationType defaults to http. http and the chat types deliver
+	// to TargetUrl.
+	DestinationType   string
+	DestinationConfig json.RawMessage
+
+	// Output encodes the transformed payload for http delivery,
+	// defaults to JSON.
+	OutputFormat      string
+	OutputTemplate    string
+	OutputContentType string
+
+	// DeliveryDelaySeconds holds every event back for a fixed time.
+	// DeliverAtExpr is a jq expression yielding a delivery timestamp
+	// (unix seconds/millis or RFC 3339) that takes precedence.
+	DeliveryDelaySeconds int32
+	DeliverAtExpr        string
+
+	// Window aggregates events grouped by WindowKeyExpr for
+	// WindowSeconds or until WindowMaxEvents arrive, then delivers once.
+	// Empty WindowMode disables windowing.
+	WindowMode      string
+	WindowKeyExpr   string
+	WindowSeconds   int32
+	WindowMaxEvents int32
+
+	// RateLimitPerSec paces deliveries through a token bucket shared by
+	// all workers, 0 disables it. RateLimitScope is pipe or destination.
+	RateLimitPerSec float64
+	RateLimitBurst  int32
+	RateLimitScope  string
+
+	// RedactionRules apply to the payloads stored on events and streamed
+	// to the realtime socket; deliveries are not redacted.
+	RedactionRules redact.Rules
+}
+
+// QueueStats describes the pending work for a single pipe.
+type QueueStats struct {
+	PipeID         uuid.UUID `json:"pipe_id"`
+	Pending        int64     `json:"pending"`
+	Weight         int32     `json:"weight"`
+	MaxConcurrency int32     `json:"max_concurrency"`
+
+	// while paused, Pending is the buffer, which ingest caps at
+	// PAUSED_BUFFER_MAX; OldestAt is when the task next in line was
+	// received
+	Paused           bool       `json:"paused"`
+	PausedAt         *time.Time `json:"paused_at,omitempty"`
+	OldestAt         *time.Time `json:"oldest_at,omitempty"`
+	OldestAgeSeconds float64    `json:"oldest_age_seconds"`
+}
+
+// ScheduledEvent is an event waiting for its delivery time.
+type ScheduledEvent struct {
+	EventID   string    `json:"event_id"`
+	DeliverAt time.Time `json:"deliver_at"`
+	Payload   any       `json:"payload"`
+}
+
+// EventSample is a stored event's original payload with the pipe
+// metadata a filter sees as variables.
+type EventSample struct {
+	EventID    uuid.UUID
+	PipeID     uuid.UUID
+	PipeName   string
+	PipeSlug   string
+	ReceivedAt time.Time
+	Payload    any
+}
+
+// EventQuery filters a pipe's event history. Zero fields do not filter.
+// Path is a jsonpath (without like_regex, see validateEventPath) and
+// Contains a JSON document matched against the payloads chosen by
+// SearchIn (request, transformed or any).
+type EventQuery struct {
+	Cursor     string
+	Limit      int32
+	StatusCode *int32
+	Outcome    string
+	Since      *time.Time
+	Until      *time.Time
+	Path       string
+	Contains   json.RawMessage
+	SearchIn   string
+}
+
+// EventPage is one page of event history.
+type EventPage struct {
+	Events     []EventSummary `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// EventSummary is an event without its payloads.
+type EventSummary struct {
+	ID           uuid.UUID `json:"id"`
+	StatusCode   int32     `json:"status_code"`
+	Outcome      string    `json:"outcome"`
+	PipeRevision int32     `json:"pipe_revision"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// EventRecord is the full stored record of an event.
+type EventRecord struct {
+	EventSummary
+	PipeID             uuid.UUID       `json:"pipe_id"`
+	RequestPayload     json.RawMessage `json:"request_payload"`
+	TransformedPayload json.RawMessage `json:"transformed_payload"`
+}
+
+// Fixture is a named test case for a pipe's filter: Input with
+// Headers should produce Expected, or be dropped when ExpectDropped.
+type Fixture struct {
+	ID            uuid.UUID         `json:"id"`
+	Name          string            `json:"name"`
+	Input         json.RawMessage   `json:"input"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Expected      json.RawMessage   `json:"expected,omitempty"`
+	ExpectDropped bool              `json:"expect_dropped"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// UpdatePipeParams is a partial update of a pipe; nil fields are left
+// as they are.
+type UpdatePipeParams struct {
+	Name      *string
+	Slug      *string
+	TargetUrl *string
+	JQFilter  *string
+	IsActive  *bool
+	// RedactionRules replaces the pipe's rules, an empty list clears them.
+	RedactionRules *redact.Rules
+	// Force saves a new JQFilter even when fixtures fail.
+	Force bool
+}
+
+type SaveFixtureParams struct {
+	Name    string
+	Input   any
+	Headers map[string]string
+	// Expected is nil when the fixture expects the event dropped.
+	Expected      json.RawMessage
+	ExpectDropped bool
+}
+
+// FixtureReport is the outcome of running a filter against every
+// fixture of a pipe.
+type FixtureReport struct {
+	Filter  string          `json:"filter"`
+	Total   int             `json:"total"`
+	Passed  int             `json:"passed"`
+	Failed  int             `json:"failed"`
+	Results []FixtureResult `json:"results"`
+}
+
+type FixtureResult struct {
+	FixtureID uuid.UUID              `json:"fixture_id"`
+	Name      string                 `json:"name"`
+	Passed    bool                   `json:"passed"`
+	Dropped   bool                   `json:"dropped"`
+	Output    any                    `json:"output,omitempty"`
+	Expected  json.RawMessage        `json:"expected,omitempty"`
+	Error     *jsonfilter.Diagnostic `json:"error,omitempty"`
+}
+
+// Revision is an immutable snapshot of a pipe's configuration. Changed
+// names the fields that differ from the previous revision; Config is
+// only set when a single revision is fetched.
+type Revision struct {
+	Revision       int32          `json:"revision"`
+	UserID         uuid.UUID      `json:"user_id"`
+	Action         string         `json:"action"`
+	SourceRevision *int32         `json:"source_revision,omitempty"`
+	Changed        []string       `json:"changed"`
+	Config         map[string]any `json:"config,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type RevisionDiff struct {
+	From    int32         `json:"from"`
+	To      int32         `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
 }
 
 type cachedPipeList struct {
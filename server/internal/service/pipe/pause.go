@@ -0,0 +1,60 @@
+package pipe
+
+import (
+	"context"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/google/uuid"
+)
+
+// PausePipe stops deliveries for the pipe. Unlike deactivating it,
+// ingestion keeps accepting its events, which wait in the pipe's queue
+// until ResumePipe or until PAUSED_BUFFER_MAX of them are waiting.
+// Deliveries already in flight still complete.
+func (s *PipeService) PausePipe(ctx context.Context, pipeID, userID uuid.UUID) error {
+	if pipeID == uuid.Nil || userID == uuid.Nil {
+		return ErrInvalidInput
+	}
+	rows, err := s.querier.PausePipe(ctx, db.PausePipeParams{
+		ID:     pipeID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPipeNotFound
+	}
+
+	// the database is the record, the redis set is what the worker reads
+	if err := queue.Pause(ctx, s.cache, pipeID); err != nil {
+		return err
+	}
+	s.invalidatePipeList(ctx, userID)
+	return nil
+}
+
+// ResumePipe restarts deliveries for a paused pipe; the events buffered
+// meanwhile are delivered first, in the order they arrived.
+func (s *PipeService) ResumePipe(ctx context.Context, pipeID, userID uuid.UUID) error {
+	if pipeID == uuid.Nil || userID == uuid.Nil {
+		return ErrInvalidInput
+	}
+	rows, err := s.querier.ResumePipe(ctx, db.ResumePipeParams{
+		ID:     pipeID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPipeNotFound
+	}
+
+	if err := queue.Resume(ctx, s.cache, pipeID); err != nil {
+		return err
+	}
+	s.invalidatePipeList(ctx, userID)
+	return nil
+}
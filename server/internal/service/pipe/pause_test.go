@@ -0,0 +1,152 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/google/uuid"
+)
+
+// ownedQuerier acts on a single pipe of owner.
+type ownedQuerier struct {
+	db.Querier
+	pipeID, owner uuid.UUID
+}
+
+func (q ownedQuerier) rows(id, userID uuid.UUID) int64 {
+	if id == q.pipeID && userID == q.owner {
+		return 1
+	}
+	return 0
+}
+
+func (q ownedQuerier) PausePipe(_ context.Context, arg db.PausePipeParams) (int64, error) {
+	return q.rows(arg.ID, arg.UserID), nil
+}
+
+func (q ownedQuerier) ResumePipe(_ context.Context, arg db.ResumePipeParams) (int64, error) {
+	return q.rows(arg.ID, arg.UserID), nil
+}
+
+func (q ownedQuerier) DeletePipe(_ context.Context, arg db.DeletePipeParams) (int64, error) {
+	return q.rows(arg.ID, arg.UserID), nil
+}
+
+// setCache keeps the sets and queues the pause paths touch in memory.
+type setCache struct {
+	cache.Cacher
+	sets  map[string]map[string]bool
+	lists map[string][]string
+}
+
+func newSetCache() *setCache {
+	return &setCache{sets: map[string]map[string]bool{}, lists: map[string][]string{}}
+}
+
+func (c *setCache) SetAdd(_ context.Context, key string, members ...string) error {
+	if c.sets[key] == nil {
+		c.sets[key] = map[string]bool{}
+	}
+	for _, m := range members {
+		c.sets[key][m] = true
+	}
+	return nil
+}
+
+func (c *setCache) SetRemove(_ context.Context, key string, members ...string) error {
+	for _, m := range members {
+		delete(c.sets[key], m)
+	}
+	return nil
+}
+
+func (c *setCache) SetMembers(_ context.Context, key string) ([]string, error) {
+	members := make([]string, 0, len(c.sets[key]))
+	for m := range c.sets[key] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (c *setCache) QueuePush(_ context.Context, queue, val string) error {
+	c.lists[queue] = append(c.lists[queue], val)
+	return nil
+}
+
+func (c *setCache) Delete(_ context.Context, key string) error {
+	delete(c.lists, key)
+	return nil
+}
+
+func (c *setCache) DeletePattern(context.Context, string) error {
+	return nil
+}
+
+func newPauseService(c cache.Cacher) (*PipeService, ownedQuerier) {
+	q := ownedQuerier{pipeID: uuid.New(), owner: uuid.New()}
+	return &PipeService{querier: q, Config: &config.Config{}, cache: c}, q
+}
+
+func TestPauseResume(t *testing.T) {
+	ctx := context.Background()
+	c := newSetCache()
+	s, q := newPauseService(c)
+
+	if err := s.PausePipe(ctx, q.pipeID, uuid.New()); !errors.Is(err, ErrPipeNotFound) {
+		t.Fatalf("PausePipe() of another user's pipe error = %v, want ErrPipeNotFound", err)
+	}
+	if paused, _ := queue.Paused(ctx, c); len(paused) != 0 {
+		t.Fatalf("paused = %v after a rejected pause, want none", paused)
+	}
+
+	if err := s.PausePipe(ctx, q.pipeID, q.owner); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := queue.Paused(ctx, c); !paused[q.pipeID] {
+		t.Fatal("pipe missing from the paused set after PausePipe")
+	}
+
+	if err := s.ResumePipe(ctx, q.pipeID, q.owner); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := queue.Paused(ctx, c); paused[q.pipeID] {
+		t.Error("pipe still in the paused set after ResumePipe")
+	}
+}
+
+func TestDeletePipeDropsPausedBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	for _, paused := range []bool{true, false} {
+		c := newSetCache()
+		s, q := newPauseService(c)
+		if err := queue.Push(ctx, c, queue.WEBHOOK_QUEUE_KEY, q.pipeID, "task"); err != nil {
+			t.Fatal(err)
+		}
+		if paused {
+			if err := s.PausePipe(ctx, q.pipeID, q.owner); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := s.DeletePipe(ctx, q.pipeID, q.owner); err != nil {
+			t.Fatal(err)
+		}
+
+		buffered := len(c.lists[queue.PipeKey(queue.WEBHOOK_QUEUE_KEY, q.pipeID)])
+		active := c.sets[queue.ActiveKey(queue.WEBHOOK_QUEUE_KEY)][q.pipeID.String()]
+		stillPaused := c.sets[queue.PAUSED_KEY][q.pipeID.String()]
+		if paused && (buffered != 0 || active || stillPaused) {
+			t.Errorf("deleted paused pipe left buffered=%d active=%v paused=%v, want it all gone", buffered, active, stillPaused)
+		}
+		// a running pipe's tasks are still delivered
+		if !paused && (buffered != 1 || !active) {
+			t.Errorf("deleted running pipe left buffered=%d active=%v, want its task kept", buffered, active)
+		}
+	}
+}
@@ -9,8 +9,16 @@ import (
 
 	"github.com/MobasirSarkar/hookfilter/internal/cache"
 	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
 	"github.com/MobasirSarkar/hookfilter/pkg/config"
 	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
+	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
+	"github.com/MobasirSarkar/hookfilter/pkg/netguard"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+	"github.com/MobasirSarkar/hookfilter/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -21,7 +29,22 @@ var (
 	ErrPipeNotFound = errors.New("pipe not found")
 	ErrInvalidInput = errors.New("invalid input")
 	ErrPipeExists   = errors.New("pipe already exists")
+	ErrPipeConflict = errors.New("pipe was modified concurrently")
 	UniqueConstCode = "23505"
+
+	ErrTargetNotAllowed = errors.New("target url is not allowed")
+
+	ErrScheduledNotFound = errors.New("scheduled event not found")
+)
+
+const (
+	// MAX_DELIVERY_DELAY is the longest fixed delay a pipe may configure.
+	MAX_DELIVERY_DELAY = 7 * 24 * time.Hour
+	// MAX_WINDOW and MAX_WINDOW_EVENTS bound aggregation windows.
+	MAX_WINDOW        = 24 * time.Hour
+	MAX_WINDOW_EVENTS = 10000
+	// MAX_RATE_LIMIT bounds a pipe's rate limit and burst.
+	MAX_RATE_LIMIT = 10000
 )
 
 type Piper interface {
@@ -29,12 +52,31 @@ type Piper interface {
 	ListPipeByUser(ctx context.Context, userID uuid.UUID, page, pageSize int32) (int64, []db.Pipe, error)
 	DeletePipe(ctx context.Context, pipeID, userID uuid.UUID) error
 	GetPipeById(ctx context.Context, pipeID, userID uuid.UUID) (*db.Pipe, error)
+	UpdatePipe(ctx context.Context, pipeID, userID uuid.UUID, params UpdatePipeParams) (*db.Pipe, error)
+	PausePipe(ctx context.Context, pipeID, userID uuid.UUID) error
+	ResumePipe(ctx context.Context, pipeID, userID uuid.UUID) error
+	GetQueueStats(ctx context.Context, pipeID, userID uuid.UUID) (*QueueStats, error)
+	ListScheduled(ctx context.Context, pipeID, userID uuid.UUID, page, pageSize int32) (int64, []ScheduledEvent, error)
+	CancelScheduled(ctx context.Context, pipeID, userID uuid.UUID, eventID string) error
+	GetEventSample(ctx context.Context, eventID, userID uuid.UUID) (*EventSample, error)
+	ListEvents(ctx context.Context, pipeID, userID uuid.UUID, query EventQuery) (*EventPage, error)
+	GetEvent(ctx context.Context, pipeID, userID, eventID uuid.UUID) (*EventRecord, error)
+	ListFixtures(ctx context.Context, pipeID, userID uuid.UUID) ([]Fixture, error)
+	SaveFixture(ctx context.Context, pipeID, userID uuid.UUID, params SaveFixtureParams) (*Fixture, error)
+	DeleteFixture(ctx context.Context, pipeID, userID, fixtureID uuid.UUID) error
+	RunFixtures(ctx context.Context, pipeID, userID uuid.UUID, filter *string) (*FixtureReport, error)
+	UpdateFilter(ctx context.Context, pipeID, userID uuid.UUID, filter string, force bool) (*FixtureReport, error)
+	ListRevisions(ctx context.Context, pipeID, userID uuid.UUID, page, pageSize int32) (int64, []Revision, error)
+	GetRevision(ctx context.Context, pipeID, userID uuid.UUID, revision int32) (*Revision, error)
+	DiffRevisions(ctx context.Context, pipeID, userID uuid.UUID, from, to int32) (*RevisionDiff, error)
+	RollbackPipe(ctx context.Context, pipeID, userID uuid.UUID, revision int32, force bool) error
 }
 
 type PipeService struct {
 	querier db.Querier
 	Config  *config.Config
 	cache   cache.Cacher
+	guard   *netguard.Guard
 
 	group singleflight.Group
 }
@@ -44,30 +86,133 @@ func NewPipeService(db db.Querier, cfg *config.Config, cache cache.Cacher) *Pipe
 		querier: db,
 		Config:  cfg,
 		cache:   cache,
+		guard:   netguard.New(cfg.Security.SSRFAllowlist),
 	}
 }
 
 func (s *PipeService) CreatePipe(ctx context.Context, params CreatePipeParams) error {
-	if params.Slug == "" || params.TargetUrl == "" {
-		return errors.New("slug and target_url are required")
+	if params.DestinationType == "" {
+		params.DestinationType = model.DestinationHTTP
+	}
+	if len(params.DestinationConfig) == 0 {
+		params.DestinationConfig = json.RawMessage("{}")
+	}
+
+	if params.Slug == "" {
+		return fmt.Errorf("%w: slug is required", ErrInvalidInput)
+	}
+
+	if err := s.validateTarget(ctx, params.DestinationType, params.TargetUrl); err != nil {
+		return err
+	}
+
+	if err := model.ValidateDestination(params.DestinationType, params.DestinationConfig, s.Config.Worker.FileSinkDir != ""); err != nil {
+		return err
+	}
+
+	if params.OutputFormat == "" {
+		params.OutputFormat = string(outbound.FormatJSON)
+	}
+	if params.OutputFormat != string(outbound.FormatJSON) && params.DestinationType != model.DestinationHTTP {
+		return fmt.Errorf("%w: output encodings only apply to http destinations", ErrInvalidInput)
+	}
+	if _, err := outbound.New(outbound.Format(params.OutputFormat), params.OutputTemplate, params.OutputContentType); err != nil {
+		return err
+	}
+
+	if params.DeliveryDelaySeconds < 0 || time.Duration(params.DeliveryDelaySeconds)*time.Second > MAX_DELIVERY_DELAY {
+		return fmt.Errorf("%w: delivery_delay_seconds must be between 0 and %d", ErrInvalidInput, int(MAX_DELIVERY_DELAY.Seconds()))
+	}
+	if params.DeliverAtExpr != "" {
+		if err := jsonfilter.Validate(params.DeliverAtExpr); err != nil {
+			return fmt.Errorf("%w: deliver_at_expr: %v", ErrInvalidInput, err)
+		}
+	}
+
+	if err := validateWindow(params); err != nil {
+		return err
+	}
+
+	if params.RateLimitScope == "" {
+		params.RateLimitScope = model.RateScopePipe
+	}
+	if err := validateRateLimit(params.RateLimitPerSec, params.RateLimitBurst, params.RateLimitScope); err != nil {
+		return err
 	}
 
 	if params.JQFilter == "" {
 		params.JQFilter = "."
 	}
+	meta := jsonfilter.PipeMeta{Name: params.Name, Slug: params.Slug}
+	if err := s.validateFilter(ctx, params.JQFilter, params.SamplePayloads, meta); err != nil {
+		return err
+	}
+
+	if params.Weight < 1 {
+		params.Weight = 1
+	}
+
+	redactionRules, err := encodeRedactionRules(params.RedactionRules)
+	if err != nil {
+		return err
+	}
 
 	encryptedURL, err := encryption.Encrypt(params.TargetUrl, s.Config.Aes.EncryptionKey)
 	if err != nil {
 		return err
 	}
 
+	var tlsProfile tlsprofile.Profile
+	if params.TLS != nil {
+		tlsProfile = *params.TLS
+	}
+	if err := tlsProfile.Validate(); err != nil {
+		return err
+	}
+	var encryptedKey *string
+	if tlsProfile.ClientKey != "" {
+		key, err := encryption.Encrypt(tlsProfile.ClientKey, s.Config.Aes.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		encryptedKey = &key
+	}
+
 	err = s.querier.CreatePipe(ctx, db.CreatePipeParams{
-		ID:        uuid.New(),
-		UserID:    params.UserID,
-		Name:      params.Name,
-		Slug:      params.Slug,
-		TargetUrl: encryptedURL,
-		JqFilter:  params.JQFilter,
+		ID:             uuid.New(),
+		UserID:         params.UserID,
+		Name:           params.Name,
+		Slug:           params.Slug,
+		TargetUrl:      encryptedURL,
+		JqFilter:       params.JQFilter,
+		Weight:         params.Weight,
+		MaxConcurrency: params.MaxConcurrency,
+		TlsClientCert:  utils.PtrOrNil(tlsProfile.ClientCert),
+		TlsClientKey:   encryptedKey,
+		TlsCaBundle:    utils.PtrOrNil(tlsProfile.CABundle),
+		TlsSpkiPins:    append([]string{}, tlsProfile.SPKIPins...),
+		TlsMinVersion:  utils.PtrOrNil(tlsProfile.MinVersion),
+
+		DestinationType:   params.DestinationType,
+		DestinationConfig: params.DestinationConfig,
+
+		OutputFormat:      params.OutputFormat,
+		OutputTemplate:    utils.PtrOrNil(params.OutputTemplate),
+		OutputContentType: utils.PtrOrNil(params.OutputContentType),
+
+		DeliveryDelaySeconds: params.DeliveryDelaySeconds,
+		DeliverAtExpr:        utils.PtrOrNil(params.DeliverAtExpr),
+
+		WindowMode:      utils.PtrOrNil(params.WindowMode),
+		WindowKeyExpr:   utils.PtrOrNil(params.WindowKeyExpr),
+		WindowSeconds:   params.WindowSeconds,
+		WindowMaxEvents: params.WindowMaxEvents,
+
+		RateLimitPerSec: params.RateLimitPerSec,
+		RateLimitBurst:  params.RateLimitBurst,
+		RateLimitScope:  params.RateLimitScope,
+
+		RedactionRules: redactionRules,
 	})
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -79,6 +224,7 @@ func (s *PipeService) CreatePipe(ctx context.Context, params CreatePipeParams) e
 		return err
 	}
 
+	s.invalidatePipeList(ctx, params.UserID)
 	return nil
 }
 
@@ -177,6 +323,120 @@ func (s *PipeService) GetPipeById(ctx context.Context, pipeID, userID uuid.UUID)
 	return &pipe, nil
 }
 
+// UpdatePipe applies the fields set in params and returns the updated
+// pipe. A new filter must pass the pipe's fixtures unless params.Force
+// is set, failing with a *FixturesFailedError otherwise.
+func (s *PipeService) UpdatePipe(ctx context.Context, pipeID, userID uuid.UUID, params UpdatePipeParams) (*db.Pipe, error) {
+	pipe, err := s.getPipe(ctx, pipeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := db.UpdatePipeParams{
+		ID:        pipe.ID,
+		UserID:    pipe.UserID,
+		Name:      pipe.Name,
+		Slug:      pipe.Slug,
+		TargetUrl: pipe.TargetUrl,
+		JqFilter:  pipe.JqFilter,
+		IsActive:  pipe.IsActive,
+		Revision:  pipe.Revision,
+
+		RedactionRules: pipe.RedactionRules,
+	}
+
+	if params.Name != nil {
+		if *params.Name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+		}
+		update.Name = *params.Name
+	}
+	if params.Slug != nil {
+		if *params.Slug == "" {
+			return nil, fmt.Errorf("%w: slug cannot be empty", ErrInvalidInput)
+		}
+		update.Slug = *params.Slug
+	}
+
+	if params.TargetUrl != nil {
+		target := *params.TargetUrl
+		if err := s.validateTarget(ctx, pipe.DestinationType, target); err != nil {
+			return nil, err
+		}
+		// the ciphertext differs on every encryption, only replace it on
+		// a real change so the revision is not bumped for nothing
+		current, err := encryption.Decrypt(pipe.TargetUrl, s.Config.Aes.EncryptionKey)
+		if err != nil || current != target {
+			encrypted, err := encryption.Encrypt(target, s.Config.Aes.EncryptionKey)
+			if err != nil {
+				return nil, err
+			}
+			update.TargetUrl = encrypted
+		}
+	}
+
+	if params.JQFilter != nil {
+		filter := *params.JQFilter
+		if filter == "" {
+			filter = "."
+		}
+		if filter != pipe.JqFilter {
+			report, err := s.runPipeFixtures(ctx, pipe, filter)
+			if err != nil {
+				return nil, err
+			}
+			if report.Failed > 0 && !params.Force {
+				return nil, &FixturesFailedError{Report: report}
+			}
+			update.JqFilter = filter
+		}
+	}
+
+	if params.IsActive != nil {
+		update.IsActive = *params.IsActive
+	}
+
+	if params.RedactionRules != nil {
+		rules, err := encodeRedactionRules(*params.RedactionRules)
+		if err != nil {
+			return nil, err
+		}
+		update.RedactionRules = rules
+	}
+
+	updated, err := s.querier.UpdatePipe(ctx, update)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == UniqueConstCode {
+			return nil, ErrPipeExists
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			// deleted, or another update moved the revision on
+			if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+				return nil, err
+			}
+			return nil, ErrPipeConflict
+		}
+		return nil, err
+	}
+
+	s.invalidatePipeList(ctx, userID)
+
+	updated.TargetUrl, err = encryption.Decrypt(updated.TargetUrl, s.Config.Aes.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// invalidatePipeList drops every cached page of the user's pipe list.
+// Ingest reads pipes straight from the database, so there is no slug
+// lookup to invalidate; a failure only leaves pages stale until their
+// TTL runs out.
+func (s *PipeService) invalidatePipeList(ctx context.Context, userID uuid.UUID) {
+	_ = s.cache.DeletePattern(ctx, fmt.Sprintf("pipes:%s:page:*", userID.String()))
+}
+
 func (s *PipeService) DeletePipe(ctx context.Context, pipeID, userID uuid.UUID) error {
 	rows, err := s.querier.DeletePipe(ctx, db.DeletePipeParams{
 		ID:     pipeID,
@@ -188,5 +448,212 @@ func (s *PipeService) DeletePipe(ctx context.Context, pipeID, userID uuid.UUID)
 	if rows == 0 {
 		return ErrPipeNotFound
 	}
+	s.invalidatePipeList(ctx, userID)
+
+	// a paused pipe's buffer would otherwise sit in redis for good;
+	// tasks of a running pipe are still delivered as before
+	paused, err := queue.Paused(ctx, s.cache)
+	if err != nil || !paused[pipeID] {
+		return err
+	}
+	for _, base := range s.queueBases() {
+		if err := queue.Drop(ctx, s.cache, base, pipeID); err != nil {
+			return err
+		}
+	}
+	return queue.Resume(ctx, s.cache, pipeID)
+}
+
+// queueBases returns the base queues the worker consumes, each of which
+// may hold a sub-queue for any pipe.
+func (s *PipeService) queueBases() []string {
+	if len(s.Config.Worker.Queues) == 0 {
+		return []string{queue.WEBHOOK_QUEUE_KEY}
+	}
+	return s.Config.Worker.Queues
+}
+
+// GetQueueStats reports how many tasks are waiting in the pipe's
+// sub-queues, and how long the oldest has waited, along with its
+// scheduling settings.
+func (s *PipeService) GetQueueStats(ctx context.Context, pipeID, userID uuid.UUID) (*QueueStats, error) {
+	if pipeID == uuid.Nil || userID == uuid.Nil {
+		return nil, ErrInvalidInput
+	}
+
+	pipe, err := s.querier.GetPipeById(ctx, db.GetPipeByIdParams{
+		ID:     pipeID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPipeNotFound
+		}
+		return nil, err
+	}
+
+	stats := &QueueStats{
+		PipeID:         pipe.ID,
+		Weight:         pipe.Weight,
+		MaxConcurrency: pipe.MaxConcurrency,
+		Paused:         pipe.PausedAt != nil,
+		PausedAt:       pipe.PausedAt,
+	}
+
+	for _, base := range s.queueBases() {
+		pending, err := queue.Depth(ctx, s.cache, base, pipe.ID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Pending += pending
+
+		raw, ok, err := queue.Oldest(ctx, s.cache, base, pipe.ID)
+		if err != nil {
+			return nil, err
+		}
+		var task model.WorkerTask
+		// tasks queued before ReceivedAt existed have no age to report
+		if !ok || json.Unmarshal([]byte(raw), &task) != nil || task.ReceivedAt.IsZero() {
+			continue
+		}
+		if stats.OldestAt == nil || task.ReceivedAt.Before(*stats.OldestAt) {
+			stats.OldestAt = &task.ReceivedAt
+		}
+	}
+	if stats.OldestAt != nil {
+		stats.OldestAgeSeconds = time.Since(*stats.OldestAt).Seconds()
+	}
+	return stats, nil
+}
+
+// ListScheduled returns a page of the pipe's events that are waiting
+// for their delivery time, earliest first.
+func (s *PipeService) ListScheduled(ctx context.Context, pipeID, userID uuid.UUID, page, pageSize int32) (int64, []ScheduledEvent, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return 0, nil, err
+	}
+
+	offset := int64((page - 1) * pageSize)
+	total, items, err := queue.ListScheduled(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipeID, offset, int64(pageSize))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	events := make([]ScheduledEvent, 0, len(items))
+	for _, item := range items {
+		var task model.WorkerTask
+		if err := json.Unmarshal([]byte(item.Raw), &task); err != nil {
+			return 0, nil, fmt.Errorf("failed to decode scheduled task: %w", err)
+		}
+		events = append(events, ScheduledEvent{
+			EventID:   item.EventID,
+			DeliverAt: item.DeliverAt,
+			Payload:   task.Payload,
+		})
+	}
+	return total, events, nil
+}
+
+// CancelScheduled drops a scheduled event before it is delivered.
+func (s *PipeService) CancelScheduled(ctx context.Context, pipeID, userID uuid.UUID, eventID string) error {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return err
+	}
+
+	ok, err := queue.CancelScheduled(ctx, s.cache, queue.WEBHOOK_QUEUE_KEY, pipeID, eventID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrScheduledNotFound
+	}
+	return nil
+}
+
+func (s *PipeService) checkOwner(ctx context.Context, pipeID, userID uuid.UUID) error {
+	if pipeID == uuid.Nil || userID == uuid.Nil {
+		return ErrInvalidInput
+	}
+	ok, err := s.querier.VerifyPipeOwnership(ctx, db.VerifyPipeOwnershipParams{
+		ID:     pipeID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPipeNotFound
+	}
 	return nil
 }
+
+// validateTarget checks target_url against what the destination kind
+// delivers to. Sinks don't use it, so it must be left empty rather than
+// stored unchecked.
+func (s *PipeService) validateTarget(ctx context.Context, kind, target string) error {
+	if !model.UsesTargetURL(kind) {
+		if target != "" {
+			return fmt.Errorf("%w: target_url does not apply to %s destinations", ErrInvalidInput, kind)
+		}
+		return nil
+	}
+	if target == "" {
+		return fmt.Errorf("%w: target_url is required for %s destinations", ErrInvalidInput, kind)
+	}
+	if err := s.guard.ValidateURL(ctx, target); err != nil {
+		return fmt.Errorf("%w: %w", ErrTargetNotAllowed, err)
+	}
+	return nil
+}
+
+func validateWindow(params CreatePipeParams) error {
+	if params.WindowMode == "" {
+		return nil
+	}
+	if !model.IsWindowMode(params.WindowMode) {
+		return fmt.Errorf("%w: window mode must be one of latest, first, collect", ErrInvalidInput)
+	}
+	if params.WindowSeconds <= 0 && params.WindowMaxEvents <= 0 {
+		return fmt.Errorf("%w: a window needs window_seconds or max_events", ErrInvalidInput)
+	}
+	if params.WindowSeconds < 0 || time.Duration(params.WindowSeconds)*time.Second > MAX_WINDOW {
+		return fmt.Errorf("%w: window_seconds must be between 0 and %d", ErrInvalidInput, int(MAX_WINDOW.Seconds()))
+	}
+	if params.WindowMaxEvents < 0 || params.WindowMaxEvents > MAX_WINDOW_EVENTS {
+		return fmt.Errorf("%w: max_events must be between 0 and %d", ErrInvalidInput, MAX_WINDOW_EVENTS)
+	}
+	// a collect window holds every event until it flushes
+	if params.WindowMode == model.WindowCollect && params.WindowMaxEvents == 0 {
+		return fmt.Errorf("%w: a collect window needs max_events", ErrInvalidInput)
+	}
+	if params.DeliveryDelaySeconds > 0 || params.DeliverAtExpr != "" {
+		return fmt.Errorf("%w: windows cannot be combined with delayed delivery", ErrInvalidInput)
+	}
+	if params.WindowKeyExpr != "" {
+		if err := jsonfilter.Validate(params.WindowKeyExpr); err != nil {
+			return fmt.Errorf("%w: window key_expr: %v", ErrInvalidInput, err)
+		}
+	}
+	return nil
+}
+
+func validateRateLimit(perSec float64, burst int32, scope string) error {
+	if !model.IsRateScope(scope) {
+		return fmt.Errorf("%w: rate limit scope must be pipe or destination", ErrInvalidInput)
+	}
+	if perSec < 0 || perSec > MAX_RATE_LIMIT || burst < 0 || burst > MAX_RATE_LIMIT {
+		return fmt.Errorf("%w: rate limit and burst must be between 0 and %d", ErrInvalidInput, MAX_RATE_LIMIT)
+	}
+	return nil
+}
+
+// encodeRedactionRules validates rules and encodes them for storage.
+func encodeRedactionRules(rules redact.Rules) ([]byte, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = redact.Rules{}
+	}
+	return json.Marshal(rules)
+}
@@ -0,0 +1,319 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
+	"github.com/MobasirSarkar/hookfilter/pkg/netguard"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestValidateWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  CreatePipeParams
+		wantErr bool
+	}{
+		{name: "no window", params: CreatePipeParams{}},
+		{name: "timed latest", params: CreatePipeParams{WindowMode: model.WindowLatest, WindowSeconds: 60}},
+		{name: "capped collect", params: CreatePipeParams{WindowMode: model.WindowCollect, WindowSeconds: 60, WindowMaxEvents: 100}},
+		{name: "uncapped collect", params: CreatePipeParams{WindowMode: model.WindowCollect, WindowSeconds: 60}, wantErr: true},
+		{name: "unknown mode", params: CreatePipeParams{WindowMode: "sum", WindowSeconds: 60}, wantErr: true},
+		{name: "too many events", params: CreatePipeParams{WindowMode: model.WindowFirst, WindowMaxEvents: MAX_WINDOW_EVENTS + 1}, wantErr: true},
+		{name: "combined with delay", params: CreatePipeParams{WindowMode: model.WindowLatest, WindowSeconds: 60, DeliveryDelaySeconds: 10}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWindow(tt.params)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("validateWindow() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+// updateQuerier holds one pipe and records the updates made to it.
+type updateQuerier struct {
+	db.Querier
+	pipe     db.Pipe
+	fixtures []db.PipeFixture
+	// stale fails updates as if another one moved the revision on
+	stale   bool
+	updates []db.UpdatePipeParams
+}
+
+func (q *updateQuerier) GetPipeById(_ context.Context, arg db.GetPipeByIdParams) (db.Pipe, error) {
+	if arg.ID != q.pipe.ID || arg.UserID != q.pipe.UserID {
+		return db.Pipe{}, pgx.ErrNoRows
+	}
+	return q.pipe, nil
+}
+
+func (q *updateQuerier) VerifyPipeOwnership(_ context.Context, arg db.VerifyPipeOwnershipParams) (bool, error) {
+	return arg.ID == q.pipe.ID && arg.UserID == q.pipe.UserID, nil
+}
+
+func (q *updateQuerier) ListPipeFixtures(context.Context, uuid.UUID) ([]db.PipeFixture, error) {
+	return q.fixtures, nil
+}
+
+func (q *updateQuerier) UpdatePipe(_ context.Context, arg db.UpdatePipeParams) (db.Pipe, error) {
+	q.updates = append(q.updates, arg)
+	if q.stale {
+		return db.Pipe{}, pgx.ErrNoRows
+	}
+	updated := q.pipe
+	updated.Name, updated.Slug, updated.TargetUrl = arg.Name, arg.Slug, arg.TargetUrl
+	updated.JqFilter, updated.IsActive, updated.RedactionRules = arg.JqFilter, arg.IsActive, arg.RedactionRules
+	return updated, nil
+}
+
+// patternCache records the cache patterns deleted.
+type patternCache struct {
+	cache.Cacher
+	deleted []string
+}
+
+func (c *patternCache) DeletePattern(_ context.Context, pattern string) error {
+	c.deleted = append(c.deleted, pattern)
+	return nil
+}
+
+func newUpdateService(t *testing.T, kind string) (*PipeService, *updateQuerier, *patternCache) {
+	t.Helper()
+	target := ""
+	if model.UsesTargetURL(kind) {
+		target = "https://93.184.216.34/hook"
+	}
+	encrypted, err := encryption.Encrypt(target, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &updateQuerier{
+		pipe: db.Pipe{
+			ID:              uuid.New(),
+			UserID:          uuid.New(),
+			Name:            "orders",
+			Slug:            "orders",
+			TargetUrl:       encrypted,
+			JqFilter:        ".total",
+			IsActive:        true,
+			Revision:        3,
+			DestinationType: kind,
+			RedactionRules:  []byte(`[{"path":"card","action":"drop"}]`),
+		},
+		fixtures: []db.PipeFixture{
+			{Name: "total", Input: []byte(`{"total":5}`), Expected: []byte(`5`)},
+		},
+	}
+	c := &patternCache{}
+	cfg := &config.Config{}
+	cfg.Aes.EncryptionKey = testKey
+	return &PipeService{querier: q, Config: cfg, cache: c, guard: netguard.New(nil)}, q, c
+}
+
+func TestUpdatePipe(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	badRules := redact.Rules{{Key: "(", Action: "mask"}}
+
+	tests := []struct {
+		name    string
+		kind    string
+		params  UpdatePipeParams
+		wantErr error
+		// wantFailed expects a *FixturesFailedError
+		wantFailed bool
+		check      func(t *testing.T, before db.Pipe, got db.UpdatePipeParams)
+	}{
+		{
+			name:   "name only",
+			kind:   model.DestinationHTTP,
+			params: UpdatePipeParams{Name: ptr("invoices")},
+			check: func(t *testing.T, before db.Pipe, got db.UpdatePipeParams) {
+				if got.Name != "invoices" || got.Slug != before.Slug || got.JqFilter != before.JqFilter ||
+					got.TargetUrl != before.TargetUrl || string(got.RedactionRules) != string(before.RedactionRules) {
+					t.Errorf("update = %+v, want only the name changed", got)
+				}
+				if got.Revision != before.Revision {
+					t.Errorf("revision = %d, want the one read, %d", got.Revision, before.Revision)
+				}
+			},
+		},
+		{
+			name:    "empty name",
+			kind:    model.DestinationHTTP,
+			params:  UpdatePipeParams{Name: ptr("")},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:   "same target keeps the ciphertext",
+			kind:   model.DestinationHTTP,
+			params: UpdatePipeParams{TargetUrl: ptr("https://93.184.216.34/hook")},
+			check: func(t *testing.T, before db.Pipe, got db.UpdatePipeParams) {
+				if got.TargetUrl != before.TargetUrl {
+					t.Error("target_url re-encrypted without a change")
+				}
+			},
+		},
+		{
+			name:   "new target",
+			kind:   model.DestinationSlack,
+			params: UpdatePipeParams{TargetUrl: ptr("https://93.184.216.35/hook")},
+			check: func(t *testing.T, _ db.Pipe, got db.UpdatePipeParams) {
+				if target, _ := encryption.Decrypt(got.TargetUrl, testKey); target != "https://93.184.216.35/hook" {
+					t.Errorf("target_url = %q", target)
+				}
+			},
+		},
+		{
+			name:    "blocked target",
+			kind:    model.DestinationHTTP,
+			params:  UpdatePipeParams{TargetUrl: ptr("http://127.0.0.1/hook")},
+			wantErr: ErrTargetNotAllowed,
+		},
+		{
+			name:    "cleared target",
+			kind:    model.DestinationHTTP,
+			params:  UpdatePipeParams{TargetUrl: ptr("")},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "target on a sink",
+			kind:    model.DestinationLog,
+			params:  UpdatePipeParams{TargetUrl: ptr("https://93.184.216.34/hook")},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:   "empty target on a sink",
+			kind:   model.DestinationLog,
+			params: UpdatePipeParams{TargetUrl: ptr("")},
+		},
+		{
+			name:       "filter failing fixtures",
+			kind:       model.DestinationHTTP,
+			params:     UpdatePipeParams{JQFilter: ptr(".count")},
+			wantFailed: true,
+		},
+		{
+			name:   "forced filter",
+			kind:   model.DestinationHTTP,
+			params: UpdatePipeParams{JQFilter: ptr(".count"), Force: true},
+			check: func(t *testing.T, _ db.Pipe, got db.UpdatePipeParams) {
+				if got.JqFilter != ".count" {
+					t.Errorf("jq_filter = %q, want .count", got.JqFilter)
+				}
+			},
+		},
+		{
+			name:   "empty filter",
+			kind:   model.DestinationHTTP,
+			params: UpdatePipeParams{JQFilter: ptr(""), Force: true},
+			check: func(t *testing.T, _ db.Pipe, got db.UpdatePipeParams) {
+				if got.JqFilter != "." {
+					t.Errorf("jq_filter = %q, want .", got.JqFilter)
+				}
+			},
+		},
+		{
+			name:   "cleared redaction rules",
+			kind:   model.DestinationHTTP,
+			params: UpdatePipeParams{RedactionRules: &redact.Rules{}},
+			check: func(t *testing.T, _ db.Pipe, got db.UpdatePipeParams) {
+				if string(got.RedactionRules) != "[]" {
+					t.Errorf("redaction_rules = %s, want []", got.RedactionRules)
+				}
+			},
+		},
+		{
+			name:    "invalid redaction rules",
+			kind:    model.DestinationHTTP,
+			params:  UpdatePipeParams{RedactionRules: &badRules},
+			wantErr: redact.ErrInvalidRules,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, q, _ := newUpdateService(t, tt.kind)
+			_, err := s.UpdatePipe(context.Background(), q.pipe.ID, q.pipe.UserID, tt.params)
+
+			var failed *FixturesFailedError
+			if tt.wantFailed && !errors.As(err, &failed) {
+				t.Fatalf("UpdatePipe() error = %v, want failed fixtures", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdatePipe() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil || tt.wantFailed {
+				if len(q.updates) != 0 {
+					t.Error("a rejected update reached the database")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdatePipe() error = %v", err)
+			}
+			if len(q.updates) != 1 {
+				t.Fatalf("%d updates, want 1", len(q.updates))
+			}
+			if tt.check != nil {
+				tt.check(t, q.pipe, q.updates[0])
+			}
+		})
+	}
+}
+
+func TestUpdatePipeInvalidatesPipeList(t *testing.T) {
+	ctx := context.Background()
+	name := "invoices"
+
+	s, q, c := newUpdateService(t, model.DestinationHTTP)
+	updated, err := s.UpdatePipe(ctx, q.pipe.ID, q.pipe.UserID, UpdatePipeParams{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pipes:" + q.pipe.UserID.String() + ":page:*"
+	if len(c.deleted) != 1 || c.deleted[0] != want {
+		t.Errorf("deleted patterns = %v, want [%s]", c.deleted, want)
+	}
+	if updated.TargetUrl != "https://93.184.216.34/hook" {
+		t.Errorf("returned target_url = %q, want it decrypted", updated.TargetUrl)
+	}
+
+	// a lost race or a rejected update leaves the cached pages alone
+	s, q, c = newUpdateService(t, model.DestinationHTTP)
+	q.stale = true
+	if _, err := s.UpdatePipe(ctx, q.pipe.ID, q.pipe.UserID, UpdatePipeParams{Name: &name}); !errors.Is(err, ErrPipeConflict) {
+		t.Errorf("UpdatePipe() error = %v, want ErrPipeConflict", err)
+	}
+	empty := ""
+	if _, err := s.UpdatePipe(ctx, q.pipe.ID, q.pipe.UserID, UpdatePipeParams{Name: &empty}); err == nil {
+		t.Error("UpdatePipe() accepted an empty name")
+	}
+	if len(c.deleted) != 0 {
+		t.Errorf("deleted patterns = %v, want none", c.deleted)
+	}
+
+	// someone else's pipe
+	s, q, c = newUpdateService(t, model.DestinationHTTP)
+	if _, err := s.UpdatePipe(ctx, q.pipe.ID, uuid.New(), UpdatePipeParams{Name: &name}); !errors.Is(err, ErrPipeNotFound) {
+		t.Errorf("UpdatePipe() error = %v, want ErrPipeNotFound", err)
+	}
+	if len(q.updates) != 0 || len(c.deleted) != 0 {
+		t.Error("an update of another user's pipe went through")
+	}
+}
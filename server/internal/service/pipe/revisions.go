@@ -0,0 +1,363 @@
+package pipe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+	"github.com/MobasirSarkar/hookfilter/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ListRevisions pages through the pipe's revisions, newest first, each
+// with the fields it changed relative to the one before it.
+func (s *PipeService) ListRevisions(ctx context.Context, pipeID, userID uuid.UUID, page, pageSize int32) (int64, []Revision, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return 0, nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	total, err := s.querier.CountPipeRevisions(ctx, pipeID)
+	if err != nil {
+		return 0, nil, err
+	}
+	// one extra row to diff the oldest revision on the page against
+	rows, err := s.querier.ListPipeRevisions(ctx, db.ListPipeRevisionsParams{
+		PipeID: pipeID,
+		Limit:  pageSize + 1,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	configs := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		if configs[i], err = s.decodeConfig(row.Config); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	revisions := make([]Revision, 0, min(len(rows), int(pageSize)))
+	for i := 0; i < len(rows) && i < int(pageSize); i++ {
+		var prev map[string]any
+		if i+1 < len(rows) {
+			prev = configs[i+1]
+		}
+		rev := toRevision(rows[i])
+		for _, c := range diffConfigs(prev, configs[i]) {
+			rev.Changed = append(rev.Changed, c.Field)
+		}
+		revisions = append(revisions, rev)
+	}
+	return total, revisions, nil
+}
+
+// GetRevision returns one revision with its full configuration.
+func (s *PipeService) GetRevision(ctx context.Context, pipeID, userID uuid.UUID, revision int32) (*Revision, error) {
+	row, config, err := s.loadRevision(ctx, pipeID, userID, revision)
+	if err != nil {
+		return nil, err
+	}
+	rev := toRevision(*row)
+	rev.Config = config
+	return &rev, nil
+}
+
+// DiffRevisions lists the fields that differ between two revisions. A
+// zero to is the current revision, a zero from the one before to.
+func (s *PipeService) DiffRevisions(ctx context.Context, pipeID, userID uuid.UUID, from, to int32) (*RevisionDiff, error) {
+	if to == 0 {
+		pipe, err := s.getPipe(ctx, pipeID, userID)
+		if err != nil {
+			return nil, err
+		}
+		to = pipe.Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	_, fromConfig, err := s.loadRevision(ctx, pipeID, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	_, toConfig, err := s.loadRevision(ctx, pipeID, userID, to)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{From: from, To: to, Changes: diffConfigs(fromConfig, toConfig)}, nil
+}
+
+// RollbackPipe restores the configuration of an earlier revision,
+// recorded as a new revision. A restored filter must pass the pipe's
+// fixtures unless force is set, the same as UpdateFilter.
+func (s *PipeService) RollbackPipe(ctx context.Context, pipeID, userID uuid.UUID, revision int32, force bool) error {
+	row, _, err := s.loadRevision(ctx, pipeID, userID, revision)
+	if err != nil {
+		return err
+	}
+	pipe, err := s.getPipe(ctx, pipeID, userID)
+	if err != nil {
+		return err
+	}
+
+	// the allowlist, the server's settings and the validation rules may
+	// all have changed since the revision was made
+	if err := s.validateRestored(ctx, pipe, row.Config, force); err != nil {
+		return err
+	}
+
+	rows, err := s.querier.RollbackPipe(ctx, db.RollbackPipeParams{
+		ID:       pipeID,
+		UserID:   userID,
+		Revision: revision,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == UniqueConstCode {
+			return ErrPipeExists
+		}
+		return err
+	}
+	if rows == 0 {
+		return ErrPipeNotFound
+	}
+	s.invalidatePipeList(ctx, userID)
+	return nil
+}
+
+// restoredConfig is the part of a pipe a rollback validates again.
+// Decoding a revision over the pipe's current values keeps the columns
+// the revision predates, the same as the RollbackPipe query.
+type restoredConfig struct {
+	TargetUrl            string          `json:"target_url"`
+	JqFilter             string          `json:"jq_filter"`
+	TlsClientCert        *string         `json:"tls_client_cert"`
+	TlsClientKey         *string         `json:"tls_client_key"`
+	TlsCaBundle          *string         `json:"tls_ca_bundle"`
+	TlsSpkiPins          []string        `json:"tls_spki_pins"`
+	TlsMinVersion        *string         `json:"tls_min_version"`
+	DestinationType      string          `json:"destination_type"`
+	DestinationConfig    json.RawMessage `json:"destination_config"`
+	OutputFormat         string          `json:"output_format"`
+	OutputTemplate       *string         `json:"output_template"`
+	OutputContentType    *string         `json:"output_content_type"`
+	DeliveryDelaySeconds int32           `json:"delivery_delay_seconds"`
+	DeliverAtExpr        *string         `json:"deliver_at_expr"`
+	WindowMode           *string         `json:"window_mode"`
+	WindowKeyExpr        *string         `json:"window_key_expr"`
+	WindowSeconds        int32           `json:"window_seconds"`
+	WindowMaxEvents      int32           `json:"window_max_events"`
+	RateLimitPerSec      float64         `json:"rate_limit_per_sec"`
+	RateLimitBurst       int32           `json:"rate_limit_burst"`
+	RateLimitScope       string          `json:"rate_limit_scope"`
+	RedactionRules       json.RawMessage `json:"redaction_rules"`
+}
+
+// validateRestored runs the create and update time checks on the
+// configuration pipe would have after rolling back to the revision
+// snapshot raw.
+func (s *PipeService) validateRestored(ctx context.Context, pipe *db.Pipe, raw []byte, force bool) error {
+	restored := restoredConfig{
+		TargetUrl:            pipe.TargetUrl,
+		JqFilter:             pipe.JqFilter,
+		TlsClientCert:        pipe.TlsClientCert,
+		TlsClientKey:         pipe.TlsClientKey,
+		TlsCaBundle:          pipe.TlsCaBundle,
+		TlsSpkiPins:          pipe.TlsSpkiPins,
+		TlsMinVersion:        pipe.TlsMinVersion,
+		DestinationType:      pipe.DestinationType,
+		DestinationConfig:    pipe.DestinationConfig,
+		OutputFormat:         pipe.OutputFormat,
+		OutputTemplate:       pipe.OutputTemplate,
+		OutputContentType:    pipe.OutputContentType,
+		DeliveryDelaySeconds: pipe.DeliveryDelaySeconds,
+		DeliverAtExpr:        pipe.DeliverAtExpr,
+		WindowMode:           pipe.WindowMode,
+		WindowKeyExpr:        pipe.WindowKeyExpr,
+		WindowSeconds:        pipe.WindowSeconds,
+		WindowMaxEvents:      pipe.WindowMaxEvents,
+		RateLimitPerSec:      pipe.RateLimitPerSec,
+		RateLimitBurst:       pipe.RateLimitBurst,
+		RateLimitScope:       pipe.RateLimitScope,
+		RedactionRules:       pipe.RedactionRules,
+	}
+	if err := json.Unmarshal(raw, &restored); err != nil {
+		return fmt.Errorf("decoding revision: %w", err)
+	}
+
+	if model.UsesTargetURL(restored.DestinationType) {
+		target, err := encryption.Decrypt(restored.TargetUrl, s.Config.Aes.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			return fmt.Errorf("%w: target_url is required for %s destinations", ErrInvalidInput, restored.DestinationType)
+		}
+		if err := s.guard.ValidateURL(ctx, target); err != nil {
+			return fmt.Errorf("%w: %w", ErrTargetNotAllowed, err)
+		}
+	}
+
+	if err := model.ValidateDestination(restored.DestinationType, restored.DestinationConfig, s.Config.Worker.FileSinkDir != ""); err != nil {
+		return err
+	}
+
+	if restored.OutputFormat != string(outbound.FormatJSON) && restored.DestinationType != model.DestinationHTTP {
+		return fmt.Errorf("%w: output encodings only apply to http destinations", ErrInvalidInput)
+	}
+	if _, err := outbound.New(outbound.Format(restored.OutputFormat), utils.Deref(restored.OutputTemplate), utils.Deref(restored.OutputContentType)); err != nil {
+		return err
+	}
+
+	if err := validateWindow(CreatePipeParams{
+		DeliveryDelaySeconds: restored.DeliveryDelaySeconds,
+		DeliverAtExpr:        utils.Deref(restored.DeliverAtExpr),
+		WindowMode:           utils.Deref(restored.WindowMode),
+		WindowKeyExpr:        utils.Deref(restored.WindowKeyExpr),
+		WindowSeconds:        restored.WindowSeconds,
+		WindowMaxEvents:      restored.WindowMaxEvents,
+	}); err != nil {
+		return err
+	}
+
+	if err := validateRateLimit(restored.RateLimitPerSec, restored.RateLimitBurst, restored.RateLimitScope); err != nil {
+		return err
+	}
+
+	profile := tlsprofile.Profile{
+		ClientCert: utils.Deref(restored.TlsClientCert),
+		CABundle:   utils.Deref(restored.TlsCaBundle),
+		SPKIPins:   restored.TlsSpkiPins,
+		MinVersion: utils.Deref(restored.TlsMinVersion),
+	}
+	if restored.TlsClientKey != nil {
+		key, err := encryption.Decrypt(*restored.TlsClientKey, s.Config.Aes.EncryptionKey)
+		if err != nil {
+			return err
+		}
+		profile.ClientKey = key
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	if len(restored.RedactionRules) > 0 && string(restored.RedactionRules) != "null" {
+		var rules redact.Rules
+		if err := json.Unmarshal(restored.RedactionRules, &rules); err != nil {
+			return fmt.Errorf("%w: %v", redact.ErrInvalidRules, err)
+		}
+		if _, err := encodeRedactionRules(rules); err != nil {
+			return err
+		}
+	}
+
+	if restored.JqFilter == pipe.JqFilter {
+		return nil
+	}
+	report, err := s.runPipeFixtures(ctx, pipe, restored.JqFilter)
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 && !force {
+		return &FixturesFailedError{Report: report}
+	}
+	return nil
+}
+
+func (s *PipeService) loadRevision(ctx context.Context, pipeID, userID uuid.UUID, revision int32) (*db.PipeRevision, map[string]any, error) {
+	if err := s.checkOwner(ctx, pipeID, userID); err != nil {
+		return nil, nil, err
+	}
+	row, err := s.querier.GetPipeRevision(ctx, db.GetPipeRevisionParams{
+		PipeID:   pipeID,
+		Revision: revision,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrRevisionNotFound
+		}
+		return nil, nil, err
+	}
+	config, err := s.decodeConfig(row.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &row, config, nil
+}
+
+// decodeConfig turns a stored snapshot into what the API shows: the
+// target url decrypted and the TLS client key as a fingerprint, so a
+// diff shows that it changed but never the key.
+func (s *PipeService) decodeConfig(raw []byte) (map[string]any, error) {
+	var config map[string]any
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("decoding revision: %w", err)
+	}
+	if target, ok := config["target_url"].(string); ok && target != "" {
+		decrypted, err := encryption.Decrypt(target, s.Config.Aes.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		config["target_url"] = decrypted
+	}
+	if key, ok := config["tls_client_key"].(string); ok {
+		decrypted, err := encryption.Decrypt(key, s.Config.Aes.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(decrypted))
+		config["tls_client_key"] = "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return config, nil
+}
+
+// diffConfigs lists the fields whose values differ, by name. A nil
+// from reports every field of to as added.
+func diffConfigs(from, to map[string]any) []FieldChange {
+	fields := make(map[string]bool, len(to))
+	for k := range from {
+		fields[k] = true
+	}
+	for k := range to {
+		fields[k] = true
+	}
+
+	changes := []FieldChange{}
+	for field := range fields {
+		a, b := from[field], to[field]
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func toRevision(row db.PipeRevision) Revision {
+	return Revision{
+		Revision:       row.Revision,
+		UserID:         row.UserID,
+		Action:         row.Action,
+		SourceRevision: row.SourceRevision,
+		Changed:        []string{},
+		CreatedAt:      row.CreatedAt,
+	}
+}
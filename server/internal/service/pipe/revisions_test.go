@@ -0,0 +1,111 @@
+package pipe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/netguard"
+	"github.com/google/uuid"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	from := map[string]any{
+		"jq_filter":     ".",
+		"target_url":    "https://a.example",
+		"weight":        float64(1),
+		"tls_spki_pins": []any{"a"},
+	}
+	to := map[string]any{
+		"jq_filter":        ".body",
+		"target_url":       "https://a.example",
+		"weight":           float64(1),
+		"tls_spki_pins":    []any{"a", "b"},
+		"rate_limit_burst": float64(5),
+	}
+
+	got := diffConfigs(from, to)
+	want := []FieldChange{
+		{Field: "jq_filter", From: ".", To: ".body"},
+		{Field: "rate_limit_burst", From: nil, To: float64(5)},
+		{Field: "tls_spki_pins", From: []any{"a"}, To: []any{"a", "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffConfigs() = %#v, want %#v", got, want)
+	}
+
+	if got := diffConfigs(to, to); len(got) != 0 {
+		t.Errorf("diffConfigs() of equal configs = %#v, want none", got)
+	}
+	if got := diffConfigs(nil, from); len(got) != len(from) {
+		t.Errorf("diffConfigs(nil, ...) = %d changes, want %d", len(got), len(from))
+	}
+}
+
+// fixtureQuerier serves a pipe's fixtures to runPipeFixtures.
+type fixtureQuerier struct {
+	db.Querier
+	fixtures []db.PipeFixture
+}
+
+func (q fixtureQuerier) ListPipeFixtures(context.Context, uuid.UUID) ([]db.PipeFixture, error) {
+	return q.fixtures, nil
+}
+
+func TestValidateRestored(t *testing.T) {
+	s := &PipeService{
+		querier: fixtureQuerier{fixtures: []db.PipeFixture{
+			{Name: "total", Input: []byte(`{"total":5}`), Expected: []byte(`5`)},
+		}},
+		Config: &config.Config{},
+		guard:  netguard.New(nil),
+	}
+	window := "latest"
+	pipe := &db.Pipe{
+		JqFilter:          ".total",
+		DestinationType:   model.DestinationLog,
+		DestinationConfig: []byte(`{}`),
+		OutputFormat:      "json",
+		WindowMode:        &window,
+		WindowSeconds:     30,
+		RateLimitScope:    model.RateScopePipe,
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		force   bool
+		wantErr bool
+	}{
+		{name: "unchanged", config: `{}`},
+		{name: "redis sink", config: `{"destination_type":"redis_list","destination_config":{"key":"out"}}`},
+		{name: "invalid sink key", config: `{"destination_type":"redis_list","destination_config":{"key":"a b"}}`, wantErr: true},
+		// file sinks were switched off since the revision was made
+		{name: "file sink disabled", config: `{"destination_type":"file","destination_config":{"path":"out.ndjson"}}`, wantErr: true},
+		{name: "encoding on a sink", config: `{"output_format":"form"}`, wantErr: true},
+		// the revision predates windows, the pipe's window is kept
+		{name: "delay with kept window", config: `{"delivery_delay_seconds":10}`, wantErr: true},
+		{name: "delay without window", config: `{"delivery_delay_seconds":10,"window_mode":null}`},
+		{name: "passing filter", config: `{"jq_filter":"{total: .total} | .total"}`},
+		{name: "invalid filter", config: `{"jq_filter":".["}`, force: true, wantErr: true},
+		{name: "failing fixtures", config: `{"jq_filter":".count"}`, wantErr: true},
+		{name: "failing fixtures forced", config: `{"jq_filter":".count"}`, force: true},
+		{name: "rate limit too high", config: `{"rate_limit_per_sec":100000}`, wantErr: true},
+		{name: "unknown rate limit scope", config: `{"rate_limit_scope":"global"}`, wantErr: true},
+		{name: "invalid tls version", config: `{"tls_min_version":"1.0"}`, wantErr: true},
+		{name: "invalid redaction rule", config: `{"redaction_rules":[{"key":"(","action":"mask"}]}`, wantErr: true},
+		{name: "redaction rules", config: `{"redaction_rules":[{"path":"card","action":"drop"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validateRestored(context.Background(), pipe, []byte(tt.config), tt.force)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("validateRestored(%s) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+			}
+		})
+	}
+}
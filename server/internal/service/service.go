@@ -22,7 +22,7 @@ type Service struct {
 
 func NewServicer(db db.Querier, cache cache.Cacher, cfg *config.Config) *Service {
 	jwtManager := jwt.NewJWTManager(cfg)
-	ingestService := ingest.NewIngestService(db, cache)
+	ingestService := ingest.NewIngestService(db, cache, cfg)
 	realtimeService := realtime.NewRealtimeService(cache, db)
 	pipeLineService := pipe.NewPipeService(db, cfg, cache)
 	authService := auth.NewAuthService(db, jwtManager, cfg, cache)
@@ -9,3 +9,13 @@ type UserProfile struct {
 	AvatarURL *string   `json:"avatar_url,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Retention is how long a user's events are kept. PlanDays is the
+// limit set by the user's plan, or the server default when they have
+// none; EventRetentionDays, when set, shortens it.
+type Retention struct {
+	Plan               *string `json:"plan"`
+	PlanDays           int32   `json:"plan_days"`
+	EventRetentionDays *int32  `json:"event_retention_days"`
+	EffectiveDays      int32   `json:"effective_days"`
+}
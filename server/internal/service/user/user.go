@@ -13,22 +13,30 @@ import (
 
 var (
 	ErrUserNotFound = errors.New("user not found")
+	// ErrRetentionTooLong is returned for a retention longer than the
+	// user's plan allows.
+	ErrRetentionTooLong = errors.New("retention exceeds the plan's limit")
 )
 
 type Service interface {
 	GetProfile(ctx context.Context, userID string) (*UserProfile, error)
+	GetRetention(ctx context.Context, userID string) (*Retention, error)
+	UpdateRetention(ctx context.Context, userID string, days *int32) (*Retention, error)
 }
 
 type UserService struct {
 	Querier db.Querier
 	log     *logger.Logger
+	// defaultDays is the retention of users without a plan
+	defaultDays int32
 }
 
 func NewUserService(db db.Querier, cfg *config.Config) *UserService {
 	log := logger.NewLogger(cfg)
 	return &UserService{
-		Querier: db,
-		log:     log,
+		Querier:     db,
+		log:         log,
+		defaultDays: int32(cfg.Retention.DefaultDays),
 	}
 }
 
@@ -55,3 +63,71 @@ func (s *UserService) GetProfile(ctx context.Context, userID string) (*UserProfi
 
 	return profile, nil
 }
+
+func (s *UserService) GetRetention(ctx context.Context, userID string) (*Retention, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.retention(ctx, id)
+}
+
+// UpdateRetention sets how many days the user's events are kept, up to
+// what their plan allows. nil goes back to the plan's retention. Plans
+// themselves are assigned by operators.
+func (s *UserService) UpdateRetention(ctx context.Context, userID string, days *int32) (*Retention, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.retention(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if days != nil && *days > current.PlanDays {
+		return nil, ErrRetentionTooLong
+	}
+
+	n, err := s.Querier.SetUserEventRetention(ctx, db.SetUserEventRetentionParams{
+		ID:                 id,
+		EventRetentionDays: days,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	current.EventRetentionDays = days
+	current.EffectiveDays = current.PlanDays
+	if days != nil {
+		current.EffectiveDays = *days
+	}
+	return current, nil
+}
+
+// retention resolves the user's retention the way the purge job does.
+func (s *UserService) retention(ctx context.Context, id uuid.UUID) (*Retention, error) {
+	row, err := s.Querier.GetUserRetention(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	r := &Retention{
+		Plan:               row.Plan,
+		PlanDays:           s.defaultDays,
+		EventRetentionDays: row.EventRetentionDays,
+	}
+	if row.PlanRetentionDays != nil {
+		r.PlanDays = *row.PlanRetentionDays
+	}
+	r.EffectiveDays = r.PlanDays
+	if row.EventRetentionDays != nil {
+		r.EffectiveDays = *row.EventRetentionDays
+	}
+	return r, nil
+}
@@ -0,0 +1,95 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/google/uuid"
+)
+
+// retentionQuerier holds one user's retention settings.
+type retentionQuerier struct {
+	db.Querier
+	row db.GetUserRetentionRow
+	set bool
+}
+
+func (q *retentionQuerier) GetUserRetention(context.Context, uuid.UUID) (db.GetUserRetentionRow, error) {
+	return q.row, nil
+}
+
+func (q *retentionQuerier) SetUserEventRetention(_ context.Context, arg db.SetUserEventRetentionParams) (int64, error) {
+	q.set = true
+	q.row.EventRetentionDays = arg.EventRetentionDays
+	return 1, nil
+}
+
+func days(n int32) *int32 { return &n }
+
+func TestUpdateRetention(t *testing.T) {
+	pro := "pro"
+	tests := []struct {
+		name      string
+		row       db.GetUserRetentionRow
+		days      *int32
+		err       error
+		effective int32
+	}{
+		{
+			name:      "no plan, within the default",
+			days:      days(14),
+			effective: 14,
+		},
+		{
+			name: "no plan, past the default",
+			days: days(31),
+			err:  ErrRetentionTooLong,
+		},
+		{
+			name:      "within the plan",
+			row:       db.GetUserRetentionRow{Plan: &pro, PlanRetentionDays: days(90)},
+			days:      days(60),
+			effective: 60,
+		},
+		{
+			name: "past the plan",
+			row:  db.GetUserRetentionRow{Plan: &pro, PlanRetentionDays: days(90)},
+			days: days(91),
+			err:  ErrRetentionTooLong,
+		},
+		{
+			name:      "cleared",
+			row:       db.GetUserRetentionRow{Plan: &pro, EventRetentionDays: days(3), PlanRetentionDays: days(90)},
+			effective: 90,
+		},
+	}
+
+	cfg := &config.Config{}
+	cfg.Retention.DefaultDays = 30
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &retentionQuerier{row: tt.row}
+			s := NewUserService(q, cfg)
+
+			got, err := s.UpdateRetention(context.Background(), uuid.NewString(), tt.days)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateRetention() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if q.set {
+					t.Error("retention saved despite the error")
+				}
+				return
+			}
+			if got.EffectiveDays != tt.effective {
+				t.Errorf("effective days = %d, want %d", got.EffectiveDays, tt.effective)
+			}
+			if (q.row.EventRetentionDays == nil) != (tt.days == nil) {
+				t.Errorf("saved retention = %v, want %v", q.row.EventRetentionDays, tt.days)
+			}
+		})
+	}
+}
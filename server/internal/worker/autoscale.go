@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+)
+
+const (
+	// LATENCY_EWMA_WEIGHT is the divisor used for the delivery latency
+	// moving average; each sample moves the average by 1/weight.
+	LATENCY_EWMA_WEIGHT = 5
+)
+
+// scaleDecision returns the desired pool size given the current size,
+// the queue depth and the moving average delivery latency.
+//
+// Behavior:
+//   - A backlog larger than backlogPerWorker per worker grows the pool by half.
+//   - Slow deliveries with work waiting grow the pool by one, since workers
+//     are blocked on I/O rather than CPU.
+//   - An empty queue with healthy latency shrinks the pool by one.
+//   - The result is always clamped to [minSize, maxSize].
+func scaleDecision(size, minSize, maxSize int, depth int64, latency, target time.Duration, backlogPerWorker int) int {
+	desired := size
+	switch {
+	case depth > int64(size*backlogPerWorker):
+		desired = size + max(1, size/2)
+	case depth > 0 && latency > target:
+		desired = size + 1
+	case depth == 0 && latency <= target:
+		desired = size - 1
+	}
+	return min(max(desired, minSize), maxSize)
+}
+
+// observeLatency folds a delivery duration into the moving average.
+func (r *Runner) observeLatency(d time.Duration) {
+	for {
+		old := r.latency.Load()
+		next := old + (int64(d)-old)/LATENCY_EWMA_WEIGHT
+		if old == 0 {
+			next = int64(d)
+		}
+		if r.latency.CompareAndSwap(old, next) {
+			latencyGauge.Set(time.Duration(next).Milliseconds())
+			return
+		}
+	}
+}
+
+// queueDepth returns the number of tasks waiting in redis across all
+// consumed queues and their per-pipe sub-queues plus those buffered locally.
+func (r *Runner) queueDepth(ctx context.Context) (int64, error) {
+	depth := int64(len(r.jobs))
+	for _, base := range r.queues {
+		n, err := r.cache.QueueLen(ctx, base)
+		if err != nil {
+			return 0, err
+		}
+		depth += n
+
+		pipes, err := r.activePipes(ctx, base)
+		if err != nil {
+			return 0, err
+		}
+		for _, pipeID := range pipes {
+			n, err := queue.Depth(ctx, r.cache, base, pipeID)
+			if err != nil {
+				return 0, err
+			}
+			depth += n
+		}
+	}
+	return depth, nil
+}
+
+// autoscaler periodically resizes the worker pool between the configured
+// bounds. It exits when ctx is cancelled.
+func (r *Runner) autoscaler(ctx context.Context) {
+	defer r.wg.Done()
+
+	cfg := r.cfg.Worker
+	if r.minWorkers == r.maxWorkers {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.ScaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		depth, err := r.queueDepth(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.log.Warnf("[WORKER] autoscaler failed to read queue depth -> %v", err)
+			}
+			continue
+		}
+		queueDepthGauge.Set(depth)
+
+		size := r.poolSize()
+		latency := time.Duration(r.latency.Load())
+		desired := scaleDecision(size, r.minWorkers, r.maxWorkers, depth, latency, cfg.TargetLatency, cfg.BacklogPerWorker)
+		if desired == size {
+			continue
+		}
+
+		if desired > size {
+			scaleUpCounter.Inc()
+		} else {
+			scaleDownCounter.Inc()
+		}
+		r.log.Infof("[WORKER] autoscaling %d -> %d workers (depth: %d, latency: %s)", size, desired, depth, latency)
+		r.resize(desired)
+	}
+}
+
+// resize grows or shrinks the pool to n workers. Removed workers finish
+// their current task before exiting.
+func (r *Runner) resize(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.pool) < n {
+		quit := make(chan struct{})
+		r.pool = append(r.pool, quit)
+		r.wg.Add(1)
+		go r.worker(r.workCtx, quit)
+	}
+	for len(r.pool) > n {
+		last := len(r.pool) - 1
+		close(r.pool[last])
+		r.pool = r.pool[:last]
+	}
+	poolSizeGauge.Set(int64(len(r.pool)))
+}
+
+func (r *Runner) poolSize() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pool)
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScaleDecision(t *testing.T) {
+	target := 2 * time.Second
+
+	tests := []struct {
+		name    string
+		size    int
+		depth   int64
+		latency time.Duration
+		want    int
+	}{
+		{name: "Backlog grows by half", size: 4, depth: 100, latency: time.Second, want: 6},
+		{name: "Backlog capped at max", size: 15, depth: 1000, latency: time.Second, want: 16},
+		{name: "Slow deliveries grow by one", size: 4, depth: 5, latency: 3 * time.Second, want: 5},
+		{name: "Idle shrinks by one", size: 4, depth: 0, latency: time.Second, want: 3},
+		{name: "Idle floored at min", size: 2, depth: 0, latency: 0, want: 2},
+		{name: "Steady state holds", size: 4, depth: 10, latency: time.Second, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scaleDecision(tt.size, 2, 16, tt.depth, tt.latency, target, 10)
+			if got != tt.want {
+				t.Errorf("scaleDecision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
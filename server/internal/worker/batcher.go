@@ -2,71 +2,212 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
 	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/logger"
 	"github.com/google/uuid"
 )
 
 const (
-	MAX_BATCH_BUFFER = 20
+	// BATCH_RETRY_BASE is the first backoff between insert attempts,
+	// doubled on each further attempt.
+	BATCH_RETRY_BASE = 200 * time.Millisecond
+	// SPILL_REPLAY_INTERVAL is how often spilled events are retried.
+	SPILL_REPLAY_INTERVAL = 30 * time.Second
+	// BATCH_QUEUE_DEPTH is how many full batches may wait for the
+	// flusher before add keeps buffering instead.
+	BATCH_QUEUE_DEPTH = 4
 )
 
+var errBufferFull = errors.New("event buffer full")
+
+// EventBatcher buffers event records and inserts them in batches. Full
+// batches are written by a background flusher, so recording an event
+// never waits on the database. A batch the database keeps rejecting is
+// retried with backoff and then spilled, to be replayed once the
+// database is back. Inserts are idempotent on the event id and receive
+// time, so a replayed batch that partly landed before is safe.
 type EventBatcher struct {
 	mu    sync.Mutex
 	buf   []db.CreateEventParams
 	timer *time.Timer
 	db    db.Querier
+	spill eventSpill
+	log   *logger.Logger
+
+	full chan []db.CreateEventParams
+	done chan struct{}
+
+	size        int
+	maxBuffered int
+	interval    time.Duration
+	timeout     time.Duration
+	retries     int
 }
 
-func NewEventBatcher(q db.Querier) *EventBatcher {
+func NewEventBatcher(q db.Querier, c cache.Cacher, cfg *config.Config, log *logger.Logger) *EventBatcher {
 	b := &EventBatcher{
-		db:  q,
-		buf: make([]db.CreateEventParams, 0, MAX_BATCH_BUFFER),
+		db:       q,
+		log:      log,
+		size:     max(cfg.Events.BatchSize, 1),
+		interval: cfg.Events.BatchInterval,
+		timeout:  cfg.Events.BatchTimeout,
+		retries:  cfg.Events.BatchRetries,
+	}
+	b.maxBuffered = cfg.Events.MaxBuffered
+	if b.maxBuffered <= 0 {
+		b.maxBuffered = 10000
+	}
+	b.maxBuffered = max(b.maxBuffered, b.size)
+	if b.interval <= 0 {
+		b.interval = 2 * time.Second
 	}
-	b.timer = time.AfterFunc(2*time.Second, func() {
-		b.flush(context.Background())
+	if b.timeout <= 0 {
+		b.timeout = 10 * time.Second
+	}
+	if cfg.Events.Spill == "file" {
+		b.spill = &fileSpill{path: cfg.Events.SpillFile}
+	} else {
+		b.spill = &redisSpill{cache: c, key: EVENT_SPILL_KEY}
+	}
+	b.buf = make([]db.CreateEventParams, 0, b.size)
+	b.full = make(chan []db.CreateEventParams, BATCH_QUEUE_DEPTH)
+	b.done = make(chan struct{})
+	go b.flusher()
+	b.timer = time.AfterFunc(b.interval, func() {
+		if err := b.flush(context.Background()); err != nil {
+			b.log.Errorf("[WORKER] event batch flush failed -> %v", err)
+		}
 	})
 	return b
 }
 
-func (b *EventBatcher) add(ctx context.Context, e db.CreateEventParams) error {
+// add buffers e and hands the buffer to the flusher once it is full.
+// It never blocks on the database: while the flusher is behind, events
+// keep collecting in the buffer and go out as one larger batch. Once
+// maxBuffered events are waiting the buffer is spilled instead.
+func (b *EventBatcher) add(e db.CreateEventParams) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-
 	b.buf = append(b.buf, e)
-
 	if b.timer != nil {
-		b.timer.Reset(2 * time.Second)
+		b.timer.Reset(b.interval)
+	}
+	if len(b.buf) < b.size {
+		b.mu.Unlock()
+		return
 	}
+	select {
+	case b.full <- b.buf:
+		b.buf = make([]db.CreateEventParams, 0, b.size)
+		b.mu.Unlock()
+		return
+	default:
+		batchBacklogCounter.Inc()
+	}
+	if len(b.buf) < b.maxBuffered {
+		b.mu.Unlock()
+		return
+	}
+	batch := b.takeLocked()
+	b.mu.Unlock()
 
-	if len(b.buf) >= MAX_BATCH_BUFFER {
-		return b.flushLocked(ctx)
+	// the flusher is too far behind to catch up, so the buffer goes
+	// where the replay picks it up rather than growing without bound
+	if err := b.spillBatch(context.Background(), batch, errBufferFull); err != nil {
+		b.log.Errorf("[WORKER] event buffer overflow -> %v", err)
 	}
-	return nil
 }
 
-func (b *EventBatcher) flush(ctx context.Context) {
+// flusher writes the batches add hands over until close.
+func (b *EventBatcher) flusher() {
+	defer close(b.done)
+	for batch := range b.full {
+		if err := b.write(context.Background(), batch); err != nil {
+			b.log.Errorf("[WORKER] event batch flush failed -> %v", err)
+		}
+	}
+}
+
+// close waits for the flusher to write the batches handed to it, then
+// flushes what is still buffered. add must not be called afterwards.
+func (b *EventBatcher) close(ctx context.Context) error {
+	b.timer.Stop()
+	close(b.full)
+	<-b.done
+	return b.flush(ctx)
+}
+
+// flush writes whatever is buffered. The returned error means events
+// were lost: the database and the spill both refused them.
+func (b *EventBatcher) flush(ctx context.Context) error {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-	_ = b.flushLocked(ctx)
+	batch := b.takeLocked()
+	b.mu.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	return b.write(ctx, batch)
 }
 
-func (b *EventBatcher) flushLocked(ctx context.Context) error {
+func (b *EventBatcher) takeLocked() []db.CreateEventParams {
 	if len(b.buf) == 0 {
 		return nil
 	}
-
 	batch := b.buf
-	b.buf = b.buf[:0]
+	b.buf = make([]db.CreateEventParams, 0, b.size)
+	return batch
+}
+
+// write inserts batch, retrying with backoff, and spills it if the
+// database still refuses.
+func (b *EventBatcher) write(ctx context.Context, batch []db.CreateEventParams) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = b.insert(ctx, batch); err == nil {
+			return nil
+		}
+		batchFailureCounter.Inc()
+		if attempt >= b.retries || ctx.Err() != nil {
+			break
+		}
+		if sleepCtx(ctx, BATCH_RETRY_BASE<<attempt) != nil {
+			break
+		}
+	}
+
+	return b.spillBatch(ctx, batch, err)
+}
 
+// spillBatch hands a batch that could not be inserted, because of
+// cause, to the spill. It runs even if ctx is cancelled.
+func (b *EventBatcher) spillBatch(ctx context.Context, batch []db.CreateEventParams, cause error) error {
+	b.log.Warnf("[WORKER] event batch insert failed, spilling %d events -> %v", len(batch), cause)
+	sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeout)
+	defer cancel()
+	if err := b.spill.push(sctx, batch); err != nil {
+		batchLostCounter.Add(int64(len(batch)))
+		return fmt.Errorf("failed to spill %d events after insert error %v: %w", len(batch), cause, err)
+	}
+	batchSpilledCounter.Add(int64(len(batch)))
+	return nil
+}
+
+func (b *EventBatcher) insert(ctx context.Context, batch []db.CreateEventParams) error {
 	params := db.CreateEventsBatchParams{
-		Ids:                 make([]uuid.UUID, 0, len(b.buf)),
-		PipeIds:             make([]uuid.UUID, 0, len(b.buf)),
-		StatusCodes:         make([]int32, 0, len(b.buf)),
-		RequestPayloads:     make([][]byte, 0, len(b.buf)),
-		TransformedPayloads: make([][]byte, 0, len(b.buf)),
+		Ids:                 make([]uuid.UUID, 0, len(batch)),
+		PipeIds:             make([]uuid.UUID, 0, len(batch)),
+		StatusCodes:         make([]int32, 0, len(batch)),
+		RequestPayloads:     make([][]byte, 0, len(batch)),
+		TransformedPayloads: make([][]byte, 0, len(batch)),
+		PipeRevisions:       make([]int32, 0, len(batch)),
+		CreatedAts:          make([]time.Time, 0, len(batch)),
 	}
 
 	for _, e := range batch {
@@ -75,9 +216,49 @@ func (b *EventBatcher) flushLocked(ctx context.Context) error {
 		params.StatusCodes = append(params.StatusCodes, e.StatusCode)
 		params.RequestPayloads = append(params.RequestPayloads, e.RequestPayload)
 		params.TransformedPayloads = append(params.TransformedPayloads, e.TransformedPayload)
+		params.PipeRevisions = append(params.PipeRevisions, e.PipeRevision)
+		// records spilled before created_at was set carry none
+		createdAt := e.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now().UTC()
+		}
+		params.CreatedAts = append(params.CreatedAts, createdAt)
 	}
-	if err := b.db.CreateEventsBatch(ctx, params); err != nil {
-		return err
+
+	// a hung connection must not hold the flusher, and with it every
+	// batch behind this one
+	ictx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	start := time.Now()
+	err := b.db.CreateEventsBatch(ictx, params)
+	batchLatencyGauge.Set(time.Since(start).Milliseconds())
+	if err == nil {
+		batchFlushCounter.Inc()
+		batchEventsCounter.Add(int64(len(batch)))
+	}
+	return err
+}
+
+// replaySpilled periodically moves spilled events back into the
+// database until ctx is cancelled.
+func (b *EventBatcher) replaySpilled(ctx context.Context) {
+	ticker := time.NewTicker(SPILL_REPLAY_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := b.spill.replay(ctx, b.size, b.insert)
+		if n > 0 {
+			batchReplayedCounter.Add(int64(n))
+			b.log.Infof("[WORKER] replayed %d spilled events", n)
+		}
+		if err != nil && ctx.Err() == nil {
+			b.log.Warnf("[WORKER] spilled event replay stopped -> %v", err)
+		}
 	}
-	return nil
 }
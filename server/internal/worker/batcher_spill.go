@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+)
+
+// EVENT_SPILL_KEY is the redis list holding events the database refused.
+const EVENT_SPILL_KEY = "events:spill"
+
+type insertFunc func(ctx context.Context, batch []db.CreateEventParams) error
+
+// eventSpill holds event records while the database is unavailable.
+type eventSpill interface {
+	push(ctx context.Context, batch []db.CreateEventParams) error
+	// replay feeds spilled events to insert in chunks of size and
+	// returns how many were inserted.
+	replay(ctx context.Context, size int, insert insertFunc) (int, error)
+}
+
+// redisSpill keeps one JSON encoded event per list entry.
+type redisSpill struct {
+	cache cache.Cacher
+	key   string
+}
+
+func (s *redisSpill) push(ctx context.Context, batch []db.CreateEventParams) error {
+	for _, e := range batch {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := s.cache.QueuePush(ctx, s.key, string(raw)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *redisSpill) replay(ctx context.Context, size int, insert insertFunc) (int, error) {
+	replayed := 0
+	for {
+		var raws []string
+		var batch []db.CreateEventParams
+		for len(batch) < size {
+			raw, ok, err := s.cache.QueueTryPop(ctx, s.key)
+			if err != nil {
+				return replayed, s.putBack(ctx, raws, err)
+			}
+			if !ok {
+				break
+			}
+			var e db.CreateEventParams
+			if err := json.Unmarshal([]byte(raw), &e); err != nil {
+				// not ours to fix, drop it rather than block the list
+				continue
+			}
+			raws = append(raws, raw)
+			batch = append(batch, e)
+		}
+		if len(batch) == 0 {
+			return replayed, nil
+		}
+		if err := insert(ctx, batch); err != nil {
+			return replayed, s.putBack(ctx, raws, err)
+		}
+		replayed += len(batch)
+	}
+}
+
+// putBack returns popped entries to the consuming end in their
+// original order.
+func (s *redisSpill) putBack(ctx context.Context, raws []string, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(raws) - 1; i >= 0; i-- {
+		if err := s.cache.QueuePushFront(ctx, s.key, raws[i]); err != nil {
+			return errors.Join(cause, fmt.Errorf("failed to return spilled events: %w", err))
+		}
+	}
+	return cause
+}
+
+// fileSpill appends NDJSON events to a local file. Replay renames the
+// file aside first, so new spills never mix with a replay in progress.
+type fileSpill struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSpill) push(_ context.Context, batch []db.CreateEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range batch {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileSpill) replay(ctx context.Context, size int, insert insertFunc) (int, error) {
+	replaying := s.path + ".replay"
+
+	// a leftover replay file from an interrupted run goes first
+	if _, err := os.Stat(replaying); errors.Is(err, fs.ErrNotExist) {
+		s.mu.Lock()
+		err := os.Rename(s.path, replaying)
+		s.mu.Unlock()
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	f, err := os.Open(replaying)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	replayed := 0
+	batch := make([]db.CreateEventParams, 0, size)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e db.CreateEventParams
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) == size {
+			// inserts are idempotent, so a failure here simply leaves the
+			// whole file for the next round
+			if err := insert(ctx, batch); err != nil {
+				return replayed, err
+			}
+			replayed += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return replayed, err
+	}
+	if len(batch) > 0 {
+		if err := insert(ctx, batch); err != nil {
+			return replayed, err
+		}
+		replayed += len(batch)
+	}
+	return replayed, os.Remove(replaying)
+}
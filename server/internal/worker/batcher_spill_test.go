@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestFileSpillReplay(t *testing.T) {
+	spill := &fileSpill{path: filepath.Join(t.TempDir(), "spill.ndjson")}
+	ctx := context.Background()
+
+	var events []db.CreateEventParams
+	for range 5 {
+		events = append(events, db.CreateEventParams{
+			ID:                 uuid.New(),
+			PipeID:             uuid.New(),
+			StatusCode:         200,
+			RequestPayload:     []byte(`{"a":1}`),
+			TransformedPayload: []byte(`{"b":2}`),
+		})
+	}
+	if err := spill.push(ctx, events[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := spill.push(ctx, events[3:]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		failAfter int
+		wantN     int
+		wantErr   bool
+		wantFiles bool
+	}{
+		// the first chunk lands, the second fails and the file is kept
+		{name: "database still down", failAfter: 1, wantN: 2, wantErr: true, wantFiles: true},
+		// the whole file is replayed again; re-inserting is idempotent
+		{name: "database back", failAfter: -1, wantN: 5, wantFiles: false},
+		{name: "nothing left", failAfter: -1, wantN: 0, wantFiles: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var got []db.CreateEventParams
+			insert := func(_ context.Context, batch []db.CreateEventParams) error {
+				calls++
+				if tt.failAfter >= 0 && calls > tt.failAfter {
+					return errors.New("connection refused")
+				}
+				got = append(got, batch...)
+				return nil
+			}
+
+			n, err := spill.replay(ctx, 2, insert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != tt.wantN || len(got) != tt.wantN {
+				t.Fatalf("replayed %d (%d inserted), want %d", n, len(got), tt.wantN)
+			}
+			for i, e := range got {
+				if e.ID != events[i].ID || string(e.RequestPayload) != `{"a":1}` {
+					t.Errorf("event %d did not round trip: %+v", i, e)
+				}
+			}
+			_, statErr := os.Stat(spill.path + ".replay")
+			if exists := statErr == nil; exists != tt.wantFiles {
+				t.Errorf("replay file exists = %v, want %v", exists, tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// stallingQuerier holds every batch insert until release is closed.
+type stallingQuerier struct {
+	db.Querier
+	release  chan struct{}
+	inserted chan int
+}
+
+func (q *stallingQuerier) CreateEventsBatch(ctx context.Context, arg db.CreateEventsBatchParams) error {
+	<-q.release
+	q.inserted <- len(arg.Ids)
+	return nil
+}
+
+func TestEventBatcherAddDoesNotBlock(t *testing.T) {
+	q := &stallingQuerier{release: make(chan struct{}), inserted: make(chan int, 100)}
+	cfg := &config.Config{}
+	cfg.Events.BatchSize = 2
+	cfg.Events.BatchInterval = time.Hour
+	cfg.Events.Spill = "file"
+	cfg.Events.SpillFile = filepath.Join(t.TempDir(), "spill.ndjson")
+	b := NewEventBatcher(q, nil, cfg, logger.NewLogger(cfg))
+
+	// far more full batches than the flusher queue holds, while the
+	// database is stalled on the first one
+	const events = 2 * (BATCH_QUEUE_DEPTH + 4)
+	start := time.Now()
+	for range events {
+		b.add(db.CreateEventParams{ID: uuid.New(), PipeID: uuid.New()})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("add() blocked for %s while the database was stalled", elapsed)
+	}
+
+	close(q.release)
+	if err := b.close(context.Background()); err != nil {
+		t.Fatalf("close() unexpected error: %v", err)
+	}
+	close(q.inserted)
+	total := 0
+	for n := range q.inserted {
+		total += n
+	}
+	if total != events {
+		t.Errorf("inserted %d events, want %d", total, events)
+	}
+}
+
+// hangingQuerier never answers a batch insert, like a dead connection.
+type hangingQuerier struct {
+	db.Querier
+}
+
+func (hangingQuerier) CreateEventsBatch(ctx context.Context, _ db.CreateEventsBatchParams) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// spilled replays every event b spilled and returns how many there were.
+func spilled(t *testing.T, b *EventBatcher) int {
+	t.Helper()
+	total := 0
+	_, err := b.spill.replay(context.Background(), 100, func(_ context.Context, batch []db.CreateEventParams) error {
+		total += len(batch)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay() unexpected error: %v", err)
+	}
+	return total
+}
+
+func TestEventBatcherSpillsWhenBufferFull(t *testing.T) {
+	q := &stallingQuerier{release: make(chan struct{}), inserted: make(chan int, 100)}
+	cfg := &config.Config{}
+	cfg.Events.BatchSize = 2
+	cfg.Events.BatchInterval = time.Hour
+	cfg.Events.MaxBuffered = 10
+	cfg.Events.Spill = "file"
+	cfg.Events.SpillFile = filepath.Join(t.TempDir(), "spill.ndjson")
+	b := NewEventBatcher(q, nil, cfg, logger.NewLogger(cfg))
+
+	add := func(n int) {
+		for range n {
+			b.add(db.CreateEventParams{ID: uuid.New(), PipeID: uuid.New()})
+		}
+	}
+	// one batch held by the stalled insert, BATCH_QUEUE_DEPTH waiting
+	// for it, then the buffer reaches its cap twice
+	add(2)
+	for len(b.full) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	add(2*BATCH_QUEUE_DEPTH + 25)
+
+	b.mu.Lock()
+	buffered := len(b.buf)
+	b.mu.Unlock()
+	if buffered != 5 {
+		t.Errorf("buffered %d events, want the 5 past the last spill", buffered)
+	}
+	if got := spilled(t, b); got != 20 {
+		t.Errorf("spilled %d events, want 20", got)
+	}
+
+	close(q.release)
+	if err := b.close(context.Background()); err != nil {
+		t.Fatalf("close() unexpected error: %v", err)
+	}
+}
+
+func TestEventBatcherInsertTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Events.BatchSize = 10
+	cfg.Events.BatchInterval = time.Hour
+	cfg.Events.BatchTimeout = 50 * time.Millisecond
+	cfg.Events.Spill = "file"
+	cfg.Events.SpillFile = filepath.Join(t.TempDir(), "spill.ndjson")
+	b := NewEventBatcher(hangingQuerier{}, nil, cfg, logger.NewLogger(cfg))
+	defer b.timer.Stop()
+
+	batch := []db.CreateEventParams{{ID: uuid.New(), PipeID: uuid.New()}}
+	start := time.Now()
+	if err := b.write(context.Background(), batch); err != nil {
+		t.Fatalf("write() unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("write() hung for %s on a dead connection", elapsed)
+	}
+	if got := spilled(t, b); got != 1 {
+		t.Errorf("spilled %d events, want the timed out batch", got)
+	}
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+	"github.com/google/uuid"
+)
+
+var ErrFileSinksDisabled = errors.New("file destinations are disabled on this worker")
+
+// Destination delivers a transformed payload to wherever a pipe sends
+// its events. The returned status is recorded on the event: HTTP
+// destinations report the receiver's status code, other sinks report
+// 200 on success. A non-nil error is treated as retryable unless it
+// wraps a permanentError.
+type Destination interface {
+	Deliver(ctx context.Context, task model.WorkerTask, payload any) (int, error)
+}
+
+// destinationFor builds the destination selected by the task's pipe.
+func (r *Runner) destinationFor(ctx context.Context, task model.WorkerTask) (Destination, error) {
+	switch task.DestinationType {
+	case "", model.DestinationHTTP:
+		realUrl, err := encryption.Decrypt(task.TargetURL, r.cfg.Aes.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt target URL: %w", err)
+		}
+		client, err := r.clientFor(ctx, task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare tls profile: %w", err)
+		}
+		enc, err := outbound.New(outbound.Format(task.OutputFormat), task.OutputTemplate, task.OutputContentType)
+		if err != nil {
+			return nil, err
+		}
+		return &httpDestination{client: client, url: realUrl, enc: enc}, nil
+
+	case model.DestinationSlack, model.DestinationDiscord, model.DestinationTeams:
+		var cfg model.ChatDestinationConfig
+		if err := json.Unmarshal(task.DestinationConfig, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid chat destination: %w", err)
+		}
+		tmpl, err := cfg.Compile()
+		if err != nil {
+			return nil, err
+		}
+		realUrl, err := encryption.Decrypt(task.TargetURL, r.cfg.Aes.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt target URL: %w", err)
+		}
+		client, err := r.clientFor(ctx, task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare tls profile: %w", err)
+		}
+		return &chatDestination{
+			client:   client,
+			url:      realUrl,
+			platform: task.DestinationType,
+			tmpl:     tmpl,
+			limits:   r.chatLimits,
+		}, nil
+
+	case model.DestinationRedisStream, model.DestinationRedisList:
+		var cfg model.RedisDestinationConfig
+		if err := json.Unmarshal(task.DestinationConfig, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid redis destination: %w", err)
+		}
+		return &redisDestination{
+			cache:  r.cache,
+			key:    model.SinkKey(task.UserID.String(), cfg.Key),
+			stream: task.DestinationType == model.DestinationRedisStream,
+			maxLen: cfg.MaxLen,
+		}, nil
+
+	case model.DestinationFile:
+		dir := r.cfg.Worker.FileSinkDir
+		if dir == "" {
+			return nil, ErrFileSinksDisabled
+		}
+		var cfg model.FileDestinationConfig
+		if err := json.Unmarshal(task.DestinationConfig, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid file destination: %w", err)
+		}
+		if task.UserID == uuid.Nil {
+			return nil, fmt.Errorf("%w: task has no owner", model.ErrInvalidDestination)
+		}
+		path, err := cfg.Resolve(dir, task.UserID.String())
+		if err != nil {
+			return nil, err
+		}
+		return r.files.get(path, cfg), nil
+
+	case model.DestinationLog:
+		return &logDestination{log: r.log}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown destination type %q", task.DestinationType)
+	}
+}
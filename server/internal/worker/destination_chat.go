@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/chatfmt"
+)
+
+const (
+	// CHAT_MAX_INLINE_WAIT is the longest rate limit pause a delivery
+	// waits out in place; longer limits go back through the retry path.
+	CHAT_MAX_INLINE_WAIT = 5 * time.Second
+	CHAT_ERROR_BODY_MAX  = 512
+)
+
+// chatLimiter remembers, per webhook URL, when a platform told us to
+// back off so later deliveries don't hammer an already limited hook.
+type chatLimiter struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func (l *chatLimiter) wait(url string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d := time.Until(l.until[url])
+	if d <= 0 {
+		delete(l.until, url)
+		return 0
+	}
+	return d
+}
+
+func (l *chatLimiter) block(url string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t := time.Now().Add(d); t.After(l.until[url]) {
+		l.until[url] = t
+	}
+}
+
+// chatDestination renders the pipe's message template and posts it in
+// the format Slack, Discord or Teams incoming webhooks expect.
+type chatDestination struct {
+	client   *http.Client
+	url      string
+	platform string
+	tmpl     *chatfmt.Compiled
+	limits   *chatLimiter
+}
+
+func (d *chatDestination) Deliver(ctx context.Context, _ model.WorkerTask, payload any) (int, error) {
+	msg, err := d.tmpl.Render(payload)
+	if err != nil {
+		return 0, &permanentError{err: err}
+	}
+
+	var body any
+	switch d.platform {
+	case model.DestinationSlack:
+		body = chatfmt.Slack(msg)
+	case model.DestinationDiscord:
+		body = chatfmt.Discord(msg)
+	default:
+		body = chatfmt.Teams(msg)
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal chat message: %w", err)
+	}
+
+	// one inline retry for short rate limits, then defer to the retry queue
+	for attempt := 0; ; attempt++ {
+		if wait := d.limits.wait(d.url); wait > 0 {
+			if wait > CHAT_MAX_INLINE_WAIT {
+				return http.StatusTooManyRequests, nil
+			}
+			if err := sleepCtx(ctx, wait); err != nil {
+				return 0, err
+			}
+		}
+
+		status, retryAfter, err := d.post(ctx, reqBody)
+		if status != http.StatusTooManyRequests {
+			return status, err
+		}
+		d.limits.block(d.url, retryAfter)
+		if attempt > 0 || retryAfter > CHAT_MAX_INLINE_WAIT {
+			return status, nil
+		}
+	}
+}
+
+// retryDelay reports how long the platform asked us to back off, so
+// the retry of a 429 honours Retry-After.
+func (d *chatDestination) retryDelay() time.Duration {
+	return d.limits.wait(d.url)
+}
+
+// post sends one request and returns the status and, when rate limited,
+// how long the platform asked us to wait.
+func (d *chatDestination) post(ctx context.Context, reqBody []byte) (int, time.Duration, error) {
+	reqctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqctx, "POST", d.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HookFilter-Worker/1.0")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, CHAT_ERROR_BODY_MAX))
+
+	// discord reports an exhausted bucket before we hit a 429
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		d.limits.block(d.url, parseSeconds(resp.Header.Get("X-RateLimit-Reset-After")))
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return resp.StatusCode, retryAfter(resp.Header, respBody), nil
+	case resp.StatusCode >= 500:
+		return resp.StatusCode, 0, nil
+	case resp.StatusCode >= 400:
+		// the platform rejected the message itself, retrying won't help
+		return resp.StatusCode, 0, &permanentError{
+			err: fmt.Errorf("%s rejected message (status %d): %s", d.platform, resp.StatusCode, platformError(respBody)),
+		}
+	}
+
+	// teams connectors answer 200 with an error string instead of a status
+	if d.platform == model.DestinationTeams && bytes.Contains(respBody, []byte("returned HTTP error")) {
+		if bytes.Contains(respBody, []byte("429")) {
+			return http.StatusTooManyRequests, time.Second, nil
+		}
+		return http.StatusBadGateway, 0, &permanentError{
+			err: fmt.Errorf("teams rejected message: %s", platformError(respBody)),
+		}
+	}
+	return resp.StatusCode, 0, nil
+}
+
+// retryAfter reads the Retry-After header (seconds), falling back to the
+// retry_after field Discord puts in its JSON body.
+func retryAfter(h http.Header, body []byte) time.Duration {
+	if d := parseSeconds(h.Get("Retry-After")); d > 0 {
+		return d
+	}
+	var discord struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &discord) == nil && discord.RetryAfter > 0 {
+		return time.Duration(discord.RetryAfter * float64(time.Second))
+	}
+	return time.Second
+}
+
+func parseSeconds(s string) time.Duration {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+// platformError extracts a readable message from a chat platform error
+// body: Discord sends {"message": ...}, Slack and Teams plain text.
+func platformError(body []byte) string {
+	var discord struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &discord) == nil && discord.Message != "" {
+		return discord.Message
+	}
+	if s := strings.TrimSpace(string(body)); s != "" {
+		return s
+	}
+	return "no error details"
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+)
+
+const (
+	DEFAULT_FILE_MAX_BYTES = 100 << 20
+	DEFAULT_FILE_MAX_FILES = 5
+)
+
+// fileSink appends one JSON document per line to a file and rotates it
+// to path.1, path.2, ... once it grows past maxBytes. A worker process
+// must be the only writer of a given path.
+type fileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	f        *os.File
+	size     int64
+	// lastUsed is guarded by the pool's lock; evicted sinks close their
+	// file again after a late write
+	lastUsed time.Time
+	evicted  bool
+}
+
+func (s *fileSink) Deliver(_ context.Context, _ model.WorkerTask, payload any) (int, error) {
+	line, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if s.evicted {
+		_ = s.closeLocked()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return http.StatusOK, nil
+}
+
+func (s *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts path.N-1 -> path.N, ..., path -> path.1, dropping the
+// oldest file, then reopens an empty path.
+func (s *fileSink) rotate() error {
+	if err := s.closeLocked(); err != nil {
+		return err
+	}
+	if s.maxFiles < 1 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.open()
+	}
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		to := fmt.Sprintf("%s.%d", s.path, i+1)
+		if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) closeLocked() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	s.size = 0
+	return err
+}
+
+// filePool shares one sink per path between workers.
+type filePool struct {
+	mu    sync.Mutex
+	sinks map[string]*fileSink
+}
+
+func (p *filePool) get(path string, cfg model.FileDestinationConfig) *fileSink {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.sinks[path]
+	if !ok {
+		s = &fileSink{path: path}
+		p.sinks[path] = s
+	}
+	s.lastUsed = time.Now()
+
+	s.mu.Lock()
+	s.maxBytes = cfg.MaxBytes
+	if s.maxBytes == 0 {
+		s.maxBytes = DEFAULT_FILE_MAX_BYTES
+	}
+	s.maxFiles = cfg.MaxFiles
+	if s.maxFiles == 0 {
+		s.maxFiles = DEFAULT_FILE_MAX_FILES
+	}
+	s.mu.Unlock()
+	return s
+}
+
+// evictIdle closes and drops the sinks unused since before.
+func (p *filePool) evictIdle(before time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for path, s := range p.sinks {
+		if !s.lastUsed.Before(before) {
+			continue
+		}
+		s.mu.Lock()
+		_ = s.closeLocked()
+		s.evicted = true
+		s.mu.Unlock()
+		delete(p.sinks, path)
+		n++
+	}
+	return n
+}
+
+func (p *filePool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.sinks {
+		s.mu.Lock()
+		_ = s.closeLocked()
+		s.mu.Unlock()
+	}
+}
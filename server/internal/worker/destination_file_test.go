@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestFileSinkRotation(t *testing.T) {
+	dir := t.TempDir()
+	pool := &filePool{sinks: make(map[string]*fileSink)}
+	defer pool.closeAll()
+
+	cfg := model.FileDestinationConfig{Path: "events/out.ndjson", MaxBytes: 20, MaxFiles: 2}
+	userID := uuid.NewString()
+	path, err := cfg.Resolve(dir, userID)
+	if err != nil {
+		t.Fatalf("Resolve() unexpected error: %v", err)
+	}
+	sink := pool.get(path, cfg)
+
+	// each line is `{"n":N}\n`, 8 bytes, so two fit per file
+	for n := range 7 {
+		if _, err := sink.Deliver(context.Background(), model.WorkerTask{}, map[string]int{"n": n}); err != nil {
+			t.Fatalf("Deliver() unexpected error: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		"out.ndjson":   `{"n":6}`,
+		"out.ndjson.1": `{"n":4}` + "\n" + `{"n":5}`,
+		"out.ndjson.2": `{"n":2}` + "\n" + `{"n":3}`,
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, userID, "events", name))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) unexpected error: %v", name, err)
+		}
+		if strings.TrimSpace(string(got)) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, userID, "events", "out.ndjson.3")); !os.IsNotExist(err) {
+		t.Errorf("expected oldest file to be dropped, stat error = %v", err)
+	}
+}
+
+func TestFileDestinationResolve(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "out.ndjson"},
+		{path: "team/out.ndjson"},
+		{path: "../out.ndjson", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+		{path: "team/../../out.ndjson", wantErr: true},
+		{path: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := model.FileDestinationConfig{Path: tt.path}.Resolve("/var/sinks", "user")
+			if tt.wantErr != (err != nil) {
+				t.Errorf("Resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileDestinationPerUser(t *testing.T) {
+	dir := t.TempDir()
+	pool := &filePool{sinks: make(map[string]*fileSink)}
+	defer pool.closeAll()
+
+	cfg := model.FileDestinationConfig{Path: "events/out.ndjson"}
+	alice, bob := uuid.New(), uuid.New()
+	for i, owner := range []uuid.UUID{alice, bob} {
+		path, err := cfg.Resolve(dir, owner.String())
+		if err != nil {
+			t.Fatalf("Resolve() unexpected error: %v", err)
+		}
+		if _, err := pool.get(path, cfg).Deliver(context.Background(), model.WorkerTask{UserID: owner}, map[string]int{"n": i}); err != nil {
+			t.Fatalf("Deliver() unexpected error: %v", err)
+		}
+	}
+
+	for i, owner := range []uuid.UUID{alice, bob} {
+		got, err := os.ReadFile(filepath.Join(dir, owner.String(), "events", "out.ndjson"))
+		if err != nil {
+			t.Fatalf("ReadFile() unexpected error: %v", err)
+		}
+		if want := fmt.Sprintf(`{"n":%d}`, i); strings.TrimSpace(string(got)) != want {
+			t.Errorf("user %d file = %q, want %q", i, got, want)
+		}
+	}
+
+	if _, err := cfg.Resolve(dir, ""); err == nil {
+		t.Errorf("Resolve() without an owner should fail")
+	}
+}
+
+func TestFilePoolEvictIdle(t *testing.T) {
+	dir := t.TempDir()
+	pool := &filePool{sinks: make(map[string]*fileSink)}
+	defer pool.closeAll()
+
+	cfg := model.FileDestinationConfig{Path: "out.ndjson"}
+	path := filepath.Join(dir, "out.ndjson")
+	sink := pool.get(path, cfg)
+	if _, err := sink.Deliver(context.Background(), model.WorkerTask{}, map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Deliver() unexpected error: %v", err)
+	}
+
+	if n := pool.evictIdle(time.Now().Add(-time.Minute)); n != 0 {
+		t.Fatalf("evictIdle() = %d for a sink just used, want 0", n)
+	}
+	if n := pool.evictIdle(time.Now().Add(time.Minute)); n != 1 || sink.f != nil {
+		t.Fatalf("evictIdle() = %d, file open = %v, want the sink closed", n, sink.f != nil)
+	}
+
+	// a write through a sink handed out before eviction still lands and
+	// does not leave the file open
+	if _, err := sink.Deliver(context.Background(), model.WorkerTask{}, map[string]int{"n": 2}); err != nil {
+		t.Fatalf("Deliver() after eviction unexpected error: %v", err)
+	}
+	if sink.f != nil {
+		t.Errorf("evicted sink kept its file open")
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"n\":1}\n{\"n\":2}\n"; string(got) != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	if pool.get(path, cfg) == sink {
+		t.Errorf("get() returned the evicted sink")
+	}
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/outbound"
+)
+
+// httpDestination POSTs the payload to the pipe's target URL, encoded
+// with the pipe's output format.
+type httpDestination struct {
+	client *http.Client
+	url    string
+	enc    *outbound.Encoder
+}
+
+func (d *httpDestination) Deliver(ctx context.Context, _ model.WorkerTask, payload any) (int, error) {
+	reqBody, err := d.enc.Encode(payload)
+	if err != nil {
+		return 0, &permanentError{err: fmt.Errorf("failed to encode payload: %w", err)}
+	}
+
+	// create a request with a short timeout
+	reqctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqctx, "POST", d.url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", d.enc.ContentType())
+	req.Header.Set("User-Agent", "HookFilter-Worker/1.0")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
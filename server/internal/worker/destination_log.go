@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+)
+
+// logDestination writes the payload to the worker's structured log,
+// mostly useful for debugging a pipe without a receiver.
+type logDestination struct {
+	log *logger.Logger
+}
+
+func (d *logDestination) Deliver(_ context.Context, task model.WorkerTask, payload any) (int, error) {
+	d.log.Infow("[WORKER] log sink delivery",
+		"pipe_id", task.PipeID,
+		"event_id", task.EventID,
+		"payload", payload,
+	)
+	return http.StatusOK, nil
+}
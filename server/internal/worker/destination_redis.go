@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+)
+
+// redisDestination appends the payload to a redis stream or list
+// owned by the pipe's user.
+type redisDestination struct {
+	cache  cache.Cacher
+	key    string
+	stream bool
+	maxLen int64
+}
+
+func (d *redisDestination) Deliver(ctx context.Context, task model.WorkerTask, payload any) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	if d.stream {
+		_, err = d.cache.StreamAdd(ctx, d.key, d.maxLen, map[string]any{
+			"event_id": task.EventID,
+			"pipe_id":  task.PipeID.String(),
+			"payload":  string(body),
+		})
+	} else {
+		err = d.cache.QueuePush(ctx, d.key, string(body))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return http.StatusOK, nil
+}
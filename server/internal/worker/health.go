@@ -0,0 +1,36 @@
+package worker
+
+import "time"
+
+const (
+	// HEALTH_POLL_WINDOW is how long the dispatcher may go without polling
+	// redis before the worker is reported unhealthy.
+	HEALTH_POLL_WINDOW = 10 * time.Second
+)
+
+type HealthStatus struct {
+	Healthy    bool      `json:"healthy"`
+	Workers    int       `json:"workers"`
+	InFlight   int64     `json:"in_flight"`
+	Buffered   int       `json:"buffered"`
+	Queues     []string  `json:"queues"`
+	LastPollAt time.Time `json:"last_poll_at"`
+}
+
+// Health reports whether the dispatcher is still polling redis along
+// with a snapshot of the worker pool.
+func (r *Runner) Health() HealthStatus {
+	var lastPoll time.Time
+	if ns := r.lastPoll.Load(); ns > 0 {
+		lastPoll = time.Unix(0, ns)
+	}
+
+	return HealthStatus{
+		Healthy:    !lastPoll.IsZero() && time.Since(lastPoll) < HEALTH_POLL_WINDOW,
+		Workers:    r.poolSize(),
+		InFlight:   r.inFlight.Load(),
+		Buffered:   len(r.jobs),
+		Queues:     r.queues,
+		LastPollAt: lastPoll,
+	}
+}
@@ -1,17 +1,51 @@
 package worker
 
 import (
+	"errors"
 	"math/rand"
+	"net/http"
 	"time"
 )
 
-func shouldRetry(status int, err error) bool {
+// permanentError marks a delivery failure that retrying cannot fix,
+// such as a receiver rejecting the payload format.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func shouldRetry(dest Destination, status int, err error) bool {
 	if err != nil {
-		return true
+		var perm *permanentError
+		return !errors.As(err, &perm)
+	}
+	if status == http.StatusTooManyRequests {
+		// only a destination that knows when the limit lifts can retry a
+		// 429 without running straight into it again
+		_, ok := dest.(retryDelayer)
+		return ok
 	}
 	return status >= 500
 }
 
+// retryDelayer is implemented by destinations that were told how long
+// to back off, so a retry isn't scheduled before the limit lifts.
+type retryDelayer interface {
+	retryDelay() time.Duration
+}
+
+// retryDue returns when the next attempt should run: after the usual
+// backoff, or later if the destination was asked to wait longer.
+func retryDue(dest Destination, retry int) time.Time {
+	delay := backOff(retry)
+	if rd, ok := dest.(retryDelayer); ok {
+		delay = max(delay, rd.retryDelay())
+	}
+	return time.Now().Add(delay)
+}
+
 // backOff returns an exponentially increasing delay with jitter.
 //
 // Purpose:
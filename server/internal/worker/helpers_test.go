@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	chat := &chatDestination{url: "https://hooks.example.com/x", limits: &chatLimiter{until: make(map[string]time.Time)}}
+	webhook := &httpDestination{}
+
+	tests := []struct {
+		name   string
+		dest   Destination
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "Server error", dest: webhook, status: http.StatusBadGateway, want: true},
+		{name: "Client error", dest: webhook, status: http.StatusBadRequest, want: false},
+		{name: "Webhook rate limited", dest: webhook, status: http.StatusTooManyRequests, want: false},
+		{name: "Chat rate limited", dest: chat, status: http.StatusTooManyRequests, want: true},
+		{name: "Transport error", dest: webhook, err: errors.New("connection reset"), want: true},
+		{name: "Permanent error", dest: chat, err: &permanentError{err: errors.New("bad payload")}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(tt.dest, tt.status, tt.err); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDueHonoursRetryAfter(t *testing.T) {
+	chat := &chatDestination{url: "https://hooks.example.com/x", limits: &chatLimiter{until: make(map[string]time.Time)}}
+	chat.limits.block(chat.url, time.Minute)
+
+	// backOff(1) is at most 3s, the platform asked for a minute
+	if got := time.Until(retryDue(chat, 1)); got < 50*time.Second {
+		t.Errorf("retryDue() in %v, want at least the Retry-After of 1m", got)
+	}
+}
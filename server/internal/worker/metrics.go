@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/metrics"
+)
+
+var (
+	poolSizeGauge = metrics.Default.Gauge(
+		"hookfilter_worker_pool_size",
+		"Current number of worker goroutines.",
+	)
+	queueDepthGauge = metrics.Default.Gauge(
+		"hookfilter_worker_queue_depth",
+		"Tasks waiting in redis and the local buffer at the last scaling check.",
+	)
+	latencyGauge = metrics.Default.Gauge(
+		"hookfilter_worker_delivery_latency_ms",
+		"Moving average of delivery latency in milliseconds.",
+	)
+	rateDeferredCounter = metrics.Default.Counter(
+		"hookfilter_worker_rate_limit_deferred_total",
+		"Tasks handed back to the deferred store to wait for a rate limit token.",
+	)
+	rateWaitingGauge = metrics.Default.Gauge(
+		"hookfilter_worker_rate_limit_waiting",
+		"Deliveries currently pausing in a worker for a rate limit token.",
+	)
+	batchFlushCounter = metrics.Default.Counter(
+		"hookfilter_event_batches_total",
+		"Event batches inserted into the database.",
+	)
+	batchEventsCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_events_total",
+		"Event records inserted into the database in batches.",
+	)
+	batchFailureCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_failures_total",
+		"Failed event batch insert attempts.",
+	)
+	batchLatencyGauge = metrics.Default.Gauge(
+		"hookfilter_event_batch_latency_ms",
+		"Duration of the last event batch insert in milliseconds.",
+	)
+	batchSpilledCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_spilled_total",
+		"Event records spilled because the database refused them.",
+	)
+	batchReplayedCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_replayed_total",
+		"Spilled event records later inserted into the database.",
+	)
+	batchBacklogCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_backlog_total",
+		"Full event batches kept buffered because the flusher was behind.",
+	)
+	batchLostCounter = metrics.Default.Counter(
+		"hookfilter_event_batch_lost_total",
+		"Event records lost because neither the database nor the spill took them.",
+	)
+	pausedGauge = metrics.Default.Gauge(
+		"hookfilter_worker_paused_pipes",
+		"Pipes whose deliveries are paused and buffered.",
+	)
+	redactionFailedCounter = metrics.Default.Counter(
+		"hookfilter_worker_redaction_failures_total",
+		"Payloads fully masked because their pipe's redaction rules could not be applied.",
+	)
+	retentionPurgedCounter = metrics.Default.Counter(
+		"hookfilter_retention_events_purged_total",
+		"Expired events deleted by the retention job.",
+	)
+	retentionArchivedCounter = metrics.Default.Counter(
+		"hookfilter_retention_events_archived_total",
+		"Expired events written to the archive before deletion.",
+	)
+	partitionsDroppedCounter = metrics.Default.Counter(
+		"hookfilter_retention_partitions_dropped_total",
+		"Monthly event partitions dropped once past every retention period.",
+	)
+	scaleUpCounter = metrics.Default.Counter(
+		"hookfilter_worker_scale_decisions_total",
+		"Autoscaler decisions that changed the pool size.",
+		"direction", "up",
+	)
+	scaleDownCounter = metrics.Default.Counter(
+		"hookfilter_worker_scale_decisions_total",
+		"Autoscaler decisions that changed the pool size.",
+		"direction", "down",
+	)
+)
+
+// transformLimitCounter counts transforms stopped by a jq execution
+// limit, by which limit was hit.
+func transformLimitCounter(limit string) *metrics.Counter {
+	return metrics.Default.Counter(
+		"hookfilter_worker_transform_limited_total",
+		"Transforms aborted by a jq execution limit, by limit.",
+		"limit", limit,
+	)
+}
+
+// templateLimitCounter counts deliveries whose output template was
+// stopped by an execution limit, by which limit was hit.
+func templateLimitCounter(limit string) *metrics.Counter {
+	return metrics.Default.Counter(
+		"hookfilter_worker_template_limited_total",
+		"Output templates aborted by an execution limit, by limit.",
+		"limit", limit,
+	)
+}
+
+// rateLimitedCounter counts deliveries that had to wait for a rate
+// limit token, by limit scope. Pipe ids would make the series unbounded
+// and expose them on /metrics.
+func rateLimitedCounter(scope string) *metrics.Counter {
+	if scope != model.RateScopeDestination {
+		scope = model.RateScopePipe
+	}
+	return metrics.Default.Counter(
+		"hookfilter_worker_rate_limited_total",
+		"Deliveries delayed by a rate limit, by limit scope.",
+		"scope", scope,
+	)
+}
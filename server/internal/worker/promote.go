@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+)
+
+const (
+	PROMOTE_INTERVAL = time.Second
+	PROMOTE_BATCH    = 100
+)
+
+// promoter moves scheduled tasks onto their pipe sub-queues once their
+// delivery time has passed. Moving happens inside redis, so a task is
+// never lost or duplicated if the worker dies mid-way.
+func (r *Runner) promoter(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(PROMOTE_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, base := range r.queues {
+			for {
+				n, err := queue.PromoteDue(ctx, r.cache, base, time.Now(), PROMOTE_BATCH)
+				if errors.Is(err, cache.ErrMalformedDelayed) {
+					r.log.Warnf("[WORKER] dropped malformed scheduled tasks on %s -> %v", base, err)
+					err = nil
+				}
+				if err != nil {
+					if ctx.Err() == nil {
+						r.log.Errorf("[WORKER] failed to promote scheduled tasks on %s -> %v", base, err)
+					}
+					break
+				}
+				if n > 0 {
+					r.log.Debugf("[WORKER] promoted %d scheduled tasks on %s", n, base)
+				}
+				if n < PROMOTE_BATCH {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+)
+
+// RATE_LIMIT_INLINE_WAIT is the longest a worker pauses for a token;
+// longer waits hand the task back to the internal deferred store so the worker
+// can move on to other pipes.
+const RATE_LIMIT_INLINE_WAIT = time.Second
+
+// targeted is implemented by destinations that deliver to a remote
+// host, so destination scoped limits can share a bucket per host.
+type targeted interface {
+	host() string
+}
+
+func (d *httpDestination) host() string { return urlHost(d.url) }
+func (d *chatDestination) host() string { return urlHost(d.url) }
+
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func rateLimitKey(task model.WorkerTask, dest Destination) string {
+	if task.RateLimitScope == model.RateScopeDestination {
+		if t, ok := dest.(targeted); ok && t.host() != "" {
+			return fmt.Sprintf("ratelimit:dest:%s:%s", task.UserID, t.host())
+		}
+	}
+	return fmt.Sprintf("ratelimit:pipe:%s", task.PipeID)
+}
+
+// pace reserves a rate limit token for the task and waits for it. When
+// the wait is too long for a worker to sit out, the task is scheduled
+// for its token's time instead and deferred is true. Limiter errors
+// fail open: pacing is best effort, delivery is not.
+func (r *Runner) pace(ctx context.Context, j job, task model.WorkerTask, dest Destination) (bool, error) {
+	if task.RateLimitPerSec <= 0 {
+		return false, nil
+	}
+	burst := int64(task.RateLimitBurst)
+	if burst < 1 {
+		burst = max(1, int64(math.Ceil(task.RateLimitPerSec)))
+	}
+
+	wait, err := r.cache.TokenBucketReserve(ctx, rateLimitKey(task, dest), task.RateLimitPerSec, burst)
+	if err != nil {
+		r.log.Warnf("[WORKER] rate limiter unavailable, delivering unpaced -> %v", err)
+		return false, nil
+	}
+	if wait == 0 {
+		return false, nil
+	}
+	rateLimitedCounter(task.RateLimitScope).Inc()
+
+	if wait > RATE_LIMIT_INLINE_WAIT {
+		task.RateReserved = true
+		raw, err := json.Marshal(task)
+		if err != nil {
+			return false, err
+		}
+		if err := queue.Defer(ctx, r.cache, j.base, task.PipeID, task.EventID, time.Now().Add(wait), string(raw)); err != nil {
+			return false, err
+		}
+		rateDeferredCounter.Inc()
+		return true, nil
+	}
+
+	rateWaitingGauge.Add(1)
+	defer rateWaitingGauge.Add(-1)
+	return false, sleepCtx(ctx, wait)
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	pipeID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	userID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	tests := []struct {
+		name  string
+		scope string
+		dest  Destination
+		want  string
+	}{
+		{
+			name:  "pipe scope",
+			scope: model.RateScopePipe,
+			dest:  &httpDestination{url: "https://api.example.com/hook"},
+			want:  "ratelimit:pipe:" + pipeID.String(),
+		},
+		{
+			name:  "destination scope keys by host",
+			scope: model.RateScopeDestination,
+			dest:  &httpDestination{url: "https://api.example.com:8443/hook"},
+			want:  "ratelimit:dest:" + userID.String() + ":api.example.com:8443",
+		},
+		{
+			name:  "destination scope without a host falls back to the pipe",
+			scope: model.RateScopeDestination,
+			dest:  &logDestination{},
+			want:  "ratelimit:pipe:" + pipeID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := model.WorkerTask{PipeID: pipeID, UserID: userID, RateLimitScope: tt.scope}
+			if got := rateLimitKey(task, tt.dest); got != tt.want {
+				t.Errorf("rateLimitKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
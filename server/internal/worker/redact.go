@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/lru"
+	"github.com/MobasirSarkar/hookfilter/pkg/redact"
+)
+
+// MAX_REDACTORS bounds the compiled rule sets kept by a worker.
+const MAX_REDACTORS = 1024
+
+// redactorPool keeps one compiled Redactor per distinct rule set, so
+// pipes are not recompiled for every event.
+type redactorPool struct {
+	hashKey   []byte
+	redactors *lru.Cache[[sha256.Size]byte, *redact.Redactor]
+}
+
+// newRedactorPool hashes under hashKey, or a key derived from
+// encryptionKey when it is empty.
+func newRedactorPool(hashKey, encryptionKey string) *redactorPool {
+	key := []byte(hashKey)
+	if len(key) == 0 {
+		mac := hmac.New(sha256.New, []byte(encryptionKey))
+		mac.Write([]byte("hookfilter redaction"))
+		key = mac.Sum(nil)
+	}
+	return &redactorPool{
+		hashKey:   key,
+		redactors: lru.New[[sha256.Size]byte, *redact.Redactor](MAX_REDACTORS),
+	}
+}
+
+func (p *redactorPool) get(raw []byte) (*redact.Redactor, error) {
+	key := sha256.Sum256(raw)
+	if r, ok := p.redactors.Get(key); ok {
+		return r, nil
+	}
+
+	rules, err := redact.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	r, err := rules.Compile(p.hashKey)
+	if err != nil {
+		return nil, err
+	}
+	return p.redactors.Add(key, r), nil
+}
+
+// redact returns v with the task's redaction rules applied, for storing
+// or streaming; v itself is left as it is for delivery. Rules that
+// cannot be applied mask v entirely rather than leak it.
+func (r *Runner) redact(task model.WorkerTask, v any) any {
+	raw := bytes.TrimSpace(task.RedactionRules)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("[]")) || bytes.Equal(raw, []byte("null")) {
+		return v
+	}
+	red, err := r.redactors.get(raw)
+	if err != nil {
+		redactionFailedCounter.Inc()
+		r.log.Errorf("[WORKER] invalid redaction rules, masking payload -> pipe_id : %s -> %v", task.PipeID, err)
+		return redact.MASK
+	}
+
+	// filter output and error details are not always plain decoded
+	// JSON, normalise them so every rule sees the same shapes
+	b, err := json.Marshal(v)
+	if err != nil {
+		redactionFailedCounter.Inc()
+		return redact.MASK
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var plain any
+	if err := dec.Decode(&plain); err != nil {
+		redactionFailedCounter.Inc()
+		return redact.MASK
+	}
+	return red.Apply(plain)
+}
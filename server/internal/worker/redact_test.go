@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+)
+
+func TestRunnerRedact(t *testing.T) {
+	r := &Runner{redactors: newRedactorPool("", "encryption-key")}
+	task := model.WorkerTask{
+		RedactionRules: []byte(`[{"key":"^email$","action":"mask"},{"pattern":"phone","action":"drop"}]`),
+	}
+
+	// filter output carries Go types that plain decoding never yields
+	payload := map[string]any{"email": "a@b.io", "count": 3, "meta": map[string]string{"phone": "+1 555 010 9999"}}
+	got := r.redact(task, payload)
+	want := map[string]any{"email": "[REDACTED]", "meta": map[string]any{}}
+	if g, ok := got.(map[string]any); !ok || g["email"] != want["email"] || !reflect.DeepEqual(g["meta"], want["meta"]) {
+		t.Errorf("redact() = %#v, want %#v", got, want)
+	}
+	if payload["email"] != "a@b.io" {
+		t.Errorf("redact() modified the delivered payload")
+	}
+
+	if got := r.redact(model.WorkerTask{}, payload); !reflect.DeepEqual(got, payload) {
+		t.Errorf("redact() without rules = %#v, want the payload unchanged", got)
+	}
+}
@@ -0,0 +1,360 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// RETENTION_LOCK_KEY makes one worker at a time run the retention job.
+	RETENTION_LOCK_KEY = "retention:lock"
+	// PARTITION_LOCK_KEY makes one worker at a time create partitions.
+	PARTITION_LOCK_KEY = "partitions:lock"
+	// PARTITION_PREFIX names the monthly events partitions, events_pYYYYMM.
+	PARTITION_PREFIX = "events_p"
+	// PARTITION_LOOKAHEAD is how many months of partitions are kept
+	// created past the current one.
+	PARTITION_LOOKAHEAD = 2
+	// PARTITION_INTERVAL is how often partitions are checked, whether
+	// or not retention is enabled.
+	PARTITION_INTERVAL = time.Hour
+
+	RETENTION_ARCHIVE = "archive"
+)
+
+// archivedEvent is the line an archived event is written as.
+type archivedEvent struct {
+	ID                 uuid.UUID       `json:"id"`
+	PipeID             uuid.UUID       `json:"pipe_id"`
+	StatusCode         int32           `json:"status_code"`
+	PipeRevision       int32           `json:"pipe_revision"`
+	CreatedAt          time.Time       `json:"created_at"`
+	RequestPayload     json.RawMessage `json:"request_payload"`
+	TransformedPayload json.RawMessage `json:"transformed_payload"`
+}
+
+// retainer runs the retention job every Retention.Interval, starting
+// right away. It is a no-op when the interval is 0.
+func (r *Runner) retainer(ctx context.Context) {
+	defer r.wg.Done()
+
+	interval := r.cfg.Retention.Interval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.enforceRetention(ctx, time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// partitioner runs ensurePartitions every PARTITION_INTERVAL, starting
+// right away. Events past the last monthly partition land in
+// events_default, so this runs even when retention is disabled.
+func (r *Runner) partitioner(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(PARTITION_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		r.ensurePartitions(ctx, time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensurePartitions creates the partitions of the coming months, and of
+// any month whose events landed in events_default, which moves those
+// events into their partition. Failures are logged and retried on the
+// next run.
+func (r *Runner) ensurePartitions(ctx context.Context, now time.Time) {
+	ok, err := r.cache.SetNX(ctx, PARTITION_LOCK_KEY, PARTITION_INTERVAL*9/10)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to take the partition lock -> %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	stray, err := r.querier.ListDefaultEventMonths(ctx)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to check the default event partition -> %v", err)
+	}
+	for _, row := range stray {
+		r.log.Warnf("[WORKER] %d events for %s landed in events_default, moving them into their own partition",
+			row.Events, row.Month.Format("2006-01"))
+	}
+
+	for _, month := range partitionMonths(now, stray) {
+		if _, err := r.querier.CreateEventPartition(ctx, month); err != nil {
+			r.log.Warnf("[WORKER] failed to create event partition for %s -> %v", month.Format("2006-01"), err)
+		}
+	}
+}
+
+// enforceRetention drops the months past every retention period and
+// purges the remaining expired events in bounded batches. Failures are
+// logged and retried on the next run.
+func (r *Runner) enforceRetention(ctx context.Context, now time.Time) {
+	cfg := r.cfg.Retention
+
+	// the lock lapses before the next run, so a dead worker never
+	// holds it for long
+	ok, err := r.cache.SetNX(ctx, RETENTION_LOCK_KEY, cfg.Interval*9/10)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to take the retention lock -> %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	bounds, err := r.querier.GetRetentionBounds(ctx)
+	if err != nil {
+		r.log.Errorf("[WORKER] failed to load retention settings -> %v", err)
+		return
+	}
+	shortest, longest := retentionWindow(bounds.MinDays, bounds.MaxDays, cfg.DefaultDays)
+	scanBefore := now.AddDate(0, 0, -shortest)
+	dropBefore := now.AddDate(0, 0, -longest)
+
+	// whole expired months are cheapest to drop before deleting row by
+	// row; when archiving they can only go once purging emptied them
+	archive := cfg.Mode == RETENTION_ARCHIVE
+	if !archive {
+		r.dropPartitions(ctx, dropBefore, false)
+	}
+
+	n, err := r.purgeExpired(ctx, now, scanBefore)
+	if n > 0 {
+		r.log.Infof("[WORKER] retention removed %d expired events", n)
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Errorf("[WORKER] failed to purge expired events -> %v", err)
+		}
+		return
+	}
+
+	if archive {
+		r.dropPartitions(ctx, dropBefore, true)
+	}
+}
+
+// dropPartitions drops the monthly partitions that end before cutoff,
+// or with onlyEmpty, those of them that hold no events any more.
+func (r *Runner) dropPartitions(ctx context.Context, cutoff time.Time, onlyEmpty bool) {
+	names, err := r.querier.ListEventPartitions(ctx)
+	if err != nil {
+		r.log.Errorf("[WORKER] failed to list event partitions -> %v", err)
+		return
+	}
+	for _, name := range expiredPartitions(names, cutoff) {
+		if onlyEmpty {
+			month, _ := partitionMonth(name)
+			found, err := r.querier.HasEventsBetween(ctx, db.HasEventsBetweenParams{
+				Since: month,
+				Until: month.AddDate(0, 1, 0),
+			})
+			if err != nil || found {
+				continue
+			}
+		}
+		if err := r.querier.DropEventPartition(ctx, name); err != nil {
+			r.log.Errorf("[WORKER] failed to drop event partition %s -> %v", name, err)
+			continue
+		}
+		partitionsDroppedCounter.Inc()
+		r.log.Infof("[WORKER] dropped expired event partition %s", name)
+	}
+}
+
+// purgeExpired removes up to MaxBatches batches of expired events,
+// archiving each batch first in archive mode. It stops early once a
+// batch comes back short.
+func (r *Runner) purgeExpired(ctx context.Context, now, scanBefore time.Time) (int64, error) {
+	cfg := r.cfg.Retention
+	var total int64
+	for range cfg.MaxBatches {
+		var n int64
+		var err error
+		if cfg.Mode == RETENTION_ARCHIVE {
+			n, err = r.archiveBatch(ctx, now, scanBefore)
+		} else {
+			n, err = r.querier.DeleteExpiredEvents(ctx, db.DeleteExpiredEventsParams{
+				ScanBefore:  scanBefore,
+				Now:         now,
+				DefaultDays: int32(cfg.DefaultDays),
+				BatchSize:   int32(cfg.BatchSize),
+			})
+		}
+		if err != nil {
+			return total, err
+		}
+		total += n
+		retentionPurgedCounter.Add(n)
+		if n < int64(cfg.BatchSize) {
+			return total, nil
+		}
+
+		select {
+		case <-time.After(cfg.BatchPause):
+		case <-ctx.Done():
+			return total, ctx.Err()
+		}
+	}
+	return total, nil
+}
+
+// archiveBatch writes a batch of expired events to the archive and only
+// then deletes them, so a failed write loses nothing.
+func (r *Runner) archiveBatch(ctx context.Context, now, scanBefore time.Time) (int64, error) {
+	cfg := r.cfg.Retention
+	events, err := r.querier.ListExpiredEvents(ctx, db.ListExpiredEventsParams{
+		ScanBefore:  scanBefore,
+		Now:         now,
+		DefaultDays: int32(cfg.DefaultDays),
+		BatchSize:   int32(cfg.BatchSize),
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	if _, err := archiveEvents(cfg.ArchiveDir, events, now); err != nil {
+		return 0, err
+	}
+	retentionArchivedCounter.Add(int64(len(events)))
+
+	params := db.DeleteEventsParams{
+		Ids:        make([]uuid.UUID, 0, len(events)),
+		CreatedAts: make([]time.Time, 0, len(events)),
+	}
+	for _, e := range events {
+		params.Ids = append(params.Ids, e.ID)
+		params.CreatedAts = append(params.CreatedAts, e.CreatedAt)
+	}
+	return r.querier.DeleteEvents(ctx, params)
+}
+
+// archiveEvents writes events as gzipped NDJSON to a new file in dir and
+// returns its path. The file only gets its final name once complete.
+func archiveEvents(dir string, events []db.Event, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create archive dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".events-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(gz)
+	for _, e := range events {
+		if err := enc.Encode(archivedEvent{
+			ID:                 e.ID,
+			PipeID:             e.PipeID,
+			StatusCode:         e.StatusCode,
+			PipeRevision:       e.PipeRevision,
+			CreatedAt:          e.CreatedAt,
+			RequestPayload:     json.RawMessage(e.RequestPayload),
+			TransformedPayload: json.RawMessage(e.TransformedPayload),
+		}); err != nil {
+			return "", fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("events-%s-%s.ndjson.gz", now.Format("20060102T150405Z"), uuid.NewString()[:8]))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return path, nil
+}
+
+// retentionWindow returns the shortest and longest retention in effect,
+// in days, from the bounds set in the database and the default for
+// plans without a retention_plans row.
+func retentionWindow(minDays, maxDays int32, defaultDays int) (int, int) {
+	shortest, longest := defaultDays, defaultDays
+	if minDays > 0 {
+		shortest = min(shortest, int(minDays))
+	}
+	if maxDays > 0 {
+		longest = max(longest, int(maxDays))
+	}
+	return shortest, longest
+}
+
+// partitionMonths returns the months that need a partition, oldest
+// first: those events_default holds events for and the current month
+// with PARTITION_LOOKAHEAD more.
+func partitionMonths(now time.Time, stray []db.ListDefaultEventMonthsRow) []time.Time {
+	months := make([]time.Time, 0, len(stray)+PARTITION_LOOKAHEAD+1)
+	for _, row := range stray {
+		months = append(months, monthStart(row.Month))
+	}
+	for i := range PARTITION_LOOKAHEAD + 1 {
+		months = append(months, monthStart(now).AddDate(0, i, 0))
+	}
+	slices.SortFunc(months, time.Time.Compare)
+	return slices.CompactFunc(months, time.Time.Equal)
+}
+
+// expiredPartitions returns the monthly partitions whose whole month is
+// before cutoff.
+func expiredPartitions(names []string, cutoff time.Time) []string {
+	var out []string
+	for _, name := range names {
+		month, ok := partitionMonth(name)
+		if ok && !month.AddDate(0, 1, 0).After(cutoff) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// partitionMonth parses the month an events_pYYYYMM partition holds.
+func partitionMonth(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, PARTITION_PREFIX)
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("200601", rest)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPartitionMonth(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{"events_p202601", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"events_p202612", time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), true},
+		{"events_default", time.Time{}, false},
+		{"events_p2026", time.Time{}, false},
+		{"events_p202613", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := partitionMonth(tt.name)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("partitionMonth(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	names := []string{"events_p202510", "events_p202511", "events_p202512", "events_default"}
+	cutoff := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	got := expiredPartitions(names, cutoff)
+	want := []string{"events_p202510", "events_p202511"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expiredPartitions() = %v, want %v", got, want)
+	}
+
+	// a month that ends after the cutoff still holds retained events
+	got = expiredPartitions(names, cutoff.Add(-time.Hour))
+	want = []string{"events_p202510"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expiredPartitions() = %v, want %v", got, want)
+	}
+}
+
+func TestPartitionMonths(t *testing.T) {
+	month := func(y int, m time.Month) time.Time { return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC) }
+	now := time.Date(2026, 1, 20, 15, 0, 0, 0, time.UTC)
+
+	// months the worker was down for, and one that overlaps the lookahead
+	stray := []db.ListDefaultEventMonthsRow{
+		{Month: month(2025, 11), Events: 3},
+		{Month: month(2026, 2), Events: 1},
+	}
+	got := partitionMonths(now, stray)
+	want := []time.Time{month(2025, 11), month(2026, 1), month(2026, 2), month(2026, 3)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("partitionMonths() = %v, want %v", got, want)
+	}
+
+	if got := partitionMonths(now, nil); len(got) != PARTITION_LOOKAHEAD+1 || !got[0].Equal(month(2026, 1)) {
+		t.Errorf("partitionMonths() without stray events = %v", got)
+	}
+}
+
+func TestRetentionWindow(t *testing.T) {
+	tests := []struct {
+		name             string
+		minDays, maxDays int32
+		defaultDays      int
+		shortest         int
+		longest          int
+	}{
+		{"no plans", 0, 0, 30, 30, 30},
+		{"plans around default", 7, 90, 30, 7, 90},
+		{"plans above default", 60, 90, 30, 30, 90},
+		{"plans below default", 1, 7, 30, 1, 30},
+	}
+	for _, tt := range tests {
+		shortest, longest := retentionWindow(tt.minDays, tt.maxDays, tt.defaultDays)
+		if shortest != tt.shortest || longest != tt.longest {
+			t.Errorf("%s: retentionWindow() = %d, %d, want %d, %d", tt.name, shortest, longest, tt.shortest, tt.longest)
+		}
+	}
+}
+
+func TestArchiveEvents(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 16, 9, 0, 0, 0, time.UTC)
+	events := []db.Event{
+		{ID: uuid.New(), PipeID: uuid.New(), StatusCode: 200, RequestPayload: []byte(`{"a":1}`), TransformedPayload: []byte(`{"b":2}`), CreatedAt: now.AddDate(0, 0, -40)},
+		{ID: uuid.New(), PipeID: uuid.New(), StatusCode: 502, RequestPayload: []byte(`{"c":3}`), TransformedPayload: []byte(`null`), CreatedAt: now.AddDate(0, 0, -35)},
+	}
+
+	path, err := archiveEvents(dir, events, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("archive dir holds %d files, want 1", len(entries))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []archivedEvent
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var e archivedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("archived %d events, want %d", len(got), len(events))
+	}
+	for i, e := range got {
+		if e.ID != events[i].ID || e.StatusCode != events[i].StatusCode || !e.CreatedAt.Equal(events[i].CreatedAt) {
+			t.Errorf("archived event %d = %+v, want %+v", i, e, events[i])
+		}
+		if string(e.RequestPayload) != string(events[i].RequestPayload) {
+			t.Errorf("archived event %d payload = %s, want %s", i, e.RequestPayload, events[i].RequestPayload)
+		}
+	}
+}
@@ -1,27 +1,29 @@
 package worker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/MobasirSarkar/hookfilter/internal/cache"
 	db "github.com/MobasirSarkar/hookfilter/internal/database"
 	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
 	"github.com/MobasirSarkar/hookfilter/pkg/config"
-	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
 	"github.com/MobasirSarkar/hookfilter/pkg/jsonfilter"
 	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+	"github.com/MobasirSarkar/hookfilter/pkg/netguard"
+	"github.com/MobasirSarkar/hookfilter/pkg/tmplguard"
 	"github.com/google/uuid"
 )
 
 const (
-	WEBHOOK_QUEUE_KEY  = "webhook_queue"
+	WEBHOOK_QUEUE_KEY  = queue.WEBHOOK_QUEUE_KEY
 	MAX_CONCURRENCY    = 1
 	MAX_RETRY          = 3
 	PUBLISH_CHANNE_KEY = "events:pipe"
@@ -29,66 +31,162 @@ const (
 )
 
 type Worker interface {
-	Start(ctx context.Context)
-	Stop()
+	Start(ctx context.Context, workCount int)
+	Stop(ctx context.Context) (ShutdownReport, error)
+	Health() HealthStatus
+}
+
+// job is a raw task together with where it was popped from.
+// pipeID is uuid.Nil for tasks taken from a shared base queue.
+type job struct {
+	base   string
+	queue  string
+	pipeID uuid.UUID
+	raw    string
 }
 
 type Runner struct {
-	jobs       chan string
+	jobs       chan job
+	queues     []string
 	httpClient *http.Client
+	transports *transportPool
+	files      *filePool
+	chatLimits *chatLimiter
+	guard      *netguard.Guard
 	cache      cache.Cacher
 	querier    db.Querier
 	wg         sync.WaitGroup
 	log        *logger.Logger
 	cfg        *config.Config
 	batcher    *EventBatcher
+	sched      *scheduler
+	redactors  *redactorPool
+
+	// paused is the paused set as pauseSyncer last read it
+	paused atomic.Pointer[map[uuid.UUID]bool]
+
+	// workCtx outlives the dispatcher so buffered jobs can drain on
+	// shutdown; workCancel aborts them once the drain deadline passes.
+	workCtx    context.Context
+	workCancel context.CancelFunc
+
+	mu         sync.Mutex
+	pool       []chan struct{}
+	minWorkers int
+	maxWorkers int
+
+	inFlight atomic.Int64
+	lastPoll atomic.Int64
+	latency  atomic.Int64
+
+	// stopping is closed when Stop begins; shutdown counts what was
+	// handed back to redis for the ShutdownReport.
+	stopping chan struct{}
+	shutdown shutdownCounters
 }
 
 func NewRunner(c cache.Cacher, querier db.Querier, maxConcur int64, logger *logger.Logger, cfg *config.Config) *Runner {
-	batcher := NewEventBatcher(querier)
+	batcher := NewEventBatcher(querier, c, cfg, logger)
+	queues := cfg.Worker.Queues
+	if len(queues) == 0 {
+		queues = []string{WEBHOOK_QUEUE_KEY}
+	}
+	// maxConcur is a hard cap on top of the configured bounds.
+	maxWorkers := cfg.Worker.MaxConcurrency
+	if maxConcur > 0 && int(maxConcur) < maxWorkers {
+		maxWorkers = int(maxConcur)
+	}
+	minWorkers := min(max(cfg.Worker.MinConcurrency, 1), maxWorkers)
+	guard := netguard.New(cfg.Security.SSRFAllowlist)
 	return &Runner{
-		cache:   c,
-		querier: querier,
-		log:     logger,
-		cfg:     cfg,
-		batcher: batcher,
-		jobs:    make(chan string, 100),
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 20,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
+		cache:      c,
+		querier:    querier,
+		log:        logger,
+		cfg:        cfg,
+		batcher:    batcher,
+		queues:     queues,
+		minWorkers: minWorkers,
+		maxWorkers: maxWorkers,
+		sched:      newScheduler(cfg.Worker.PipeConcurrency),
+		jobs:       make(chan job, 100),
+		guard:      guard,
+		httpClient: newHTTPClient(guard, nil),
+		transports: &transportPool{clients: make(map[string]*pooledClient)},
+		files:      &filePool{sinks: make(map[string]*fileSink)},
+		chatLimits: &chatLimiter{until: make(map[string]time.Time)},
+		redactors:  newRedactorPool(cfg.Redaction.HashKey, cfg.Aes.EncryptionKey),
+		stopping:   make(chan struct{}),
 	}
 }
 
-// Start launches the dispatcher and a fixed number of workers.
-// Workers process jobs from the internal queue until ctx is cancelled.
+// Start launches the dispatcher, the autoscaler, the scheduled task
+// promoter, the paused pipe sync, the partition and retention jobs, the
+// idle client and file sweeper and workCount workers, clamped to the
+// configured pool bounds. The dispatcher stops pulling from redis when
+// ctx is cancelled; workers keep draining the internal queue until Stop
+// is called.
 func (r *Runner) Start(ctx context.Context, workCount int) {
-	for range workCount {
-		r.wg.Add(1)
-		go r.worker(ctx)
-	}
-	r.wg.Add(1)
+	r.workCtx, r.workCancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	r.resize(min(max(workCount, r.minWorkers), r.maxWorkers))
+	r.syncPaused(ctx)
+
+	r.wg.Add(8)
 	go r.dispatcher(ctx)
+	go r.autoscaler(ctx)
+	go r.promoter(ctx)
+	go r.pauseSyncer(ctx)
+	go r.partitioner(ctx)
+	go r.retainer(ctx)
+	go r.poolSweeper(ctx)
+	go func() {
+		defer r.wg.Done()
+		r.batcher.replaySpilled(ctx)
+	}()
 }
 
-// Stop waits for all workers to finish and flushes
-// any remaining batched events to the database.
-func (r *Runner) Stop() {
-	r.wg.Wait()
-	r.batcher.flush(context.Background())
+// Stop waits for the workers to drain the internal queue and flushes
+// any remaining batched events to the database. The context passed to
+// Start must already be cancelled. Retries waiting in backoff move to
+// the deferred store straight away. If ctx expires before the drain is
+// complete, in-flight deliveries are cancelled and requeued, tasks left
+// in the local buffer are pushed back to redis and ctx.Err() is
+// returned. The report says what was handed back.
+func (r *Runner) Stop(ctx context.Context) (ShutdownReport, error) {
+	close(r.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.workCancel()
+		<-done
+		err = ctx.Err()
+	}
+	r.workCancel()
+	r.drainBuffered()
+
+	r.files.closeAll()
+	if err := r.batcher.close(context.Background()); err != nil {
+		r.log.Errorf("[WORKER] final event batch flush failed -> %v", err)
+	}
+	return r.shutdown.report(err != nil), err
 }
 
-// dispatcher continuously pulls tasks from redis and
-// forwards them to the internal jobs channel
+// dispatcher continuously pulls tasks from the per-pipe sub-queues in
+// weighted round-robin order and forwards them to the internal jobs
+// channel, so one busy pipe cannot starve the others.
 // It exists cleanly when ctx is cancelled.
 func (r *Runner) dispatcher(ctx context.Context) {
 	defer r.wg.Done()
 	defer close(r.jobs)
-	r.log.Info("[WORKER] -> Dispatcher started...")
+	r.log.Infof("[WORKER] -> Dispatcher started, consuming %v", r.queues)
 
 	for {
 		select {
@@ -97,41 +195,46 @@ func (r *Runner) dispatcher(ctx context.Context) {
 		default:
 		}
 
-		// 2. Fetch Task (blocking)
-		raw, err := r.cache.QueueBlockingPop(ctx, WEBHOOK_QUEUE_KEY)
-		if err != nil {
-			if errors.Is(err, cache.ErrQueueEmpty) {
-				continue
-
-			}
+		dispatched, err := r.dispatchRound(ctx)
+		r.lastPoll.Store(time.Now().UnixNano())
+		if err != nil && ctx.Err() == nil {
 			r.log.Warnf("[WORKER] queue pop failed -> %v", err)
-			continue
-		}
-		select {
-		case r.jobs <- raw:
-		case <-ctx.Done():
-			return
 		}
 
+		if dispatched == 0 {
+			select {
+			case <-time.After(IDLE_POLL_INTERVAL):
+			case <-ctx.Done():
+				return
+			}
+		}
 	}
 }
 
-func (r *Runner) worker(ctx context.Context) {
+// worker processes jobs until the jobs channel is closed, ctx is
+// cancelled, or quit is closed by the autoscaler.
+func (r *Runner) worker(ctx context.Context, quit <-chan struct{}) {
 	defer r.wg.Done()
 	for {
 		select {
-		case payload, ok := <-r.jobs:
+		case <-quit:
+			return
+		case j, ok := <-r.jobs:
 			if !ok {
 				return
 			}
-			r.process(ctx, payload)
+			r.inFlight.Add(1)
+			r.process(ctx, j)
+			r.inFlight.Add(-1)
+			r.sched.release(j.pipeID)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (r *Runner) process(ctx context.Context, raw string) {
+func (r *Runner) process(ctx context.Context, j job) {
+	raw := j.raw
 	var task model.WorkerTask
 	if err := json.Unmarshal([]byte(raw), &task); err != nil {
 		r.log.Errorf("[WORKER] failed to unmarshal task -> %v", err)
@@ -140,48 +243,129 @@ func (r *Runner) process(ctx context.Context, raw string) {
 
 	logger := r.log.With("pipe_id", task.PipeID, "worker_id", "dynamic")
 
-	// run transformation
-	transformedPayload, err := jsonfilter.Transform(task.Payload, task.JQFilter)
+	// a window flush carries no payload until the window is taken;
+	// afterwards it is an ordinary task so retries keep the payload
+	if task.WindowID != "" {
+		payload, ok, err := r.takeWindow(ctx, task)
+		if err != nil {
+			if ctx.Err() != nil {
+				r.requeue(ctx, j, raw, &r.shutdown.inFlight)
+				return
+			}
+			logger.Errorf("[WORKER] failed to take window -> %v", err)
+			_ = r.moveTODLQ(ctx, raw, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		task.Payload = payload
+		task.WindowKey, task.WindowID = "", ""
+		if b, err := json.Marshal(task); err == nil {
+			raw = string(b)
+		}
+	}
+
+	// run transformation, the compiled filter is cached per process
+	transformedPayload, err := r.transform(ctx, task)
 	if err != nil {
 		if errors.Is(err, jsonfilter.ErrEmptyOutput) {
 			logger.Infof("[WORKER] Event filtered out by user rule -> pipe_id : %s", task.PipeID)
 			return
 		}
-		logger.Errorf("[WORKER] JQ transformation failed -> %v", err)
+		if ctx.Err() != nil {
+			r.requeue(ctx, j, raw, &r.shutdown.inFlight)
+			return
+		}
 		errorData := map[string]string{
 			"error": err.Error(),
 		}
-		r.recordEvent(ctx, task, 0, task.Payload, errorData)
+		var limitErr *jsonfilter.LimitError
+		if errors.As(err, &limitErr) {
+			logger.Warnf("[WORKER] JQ filter exceeded its %s limit -> pipe_id : %s", limitErr.Limit, task.PipeID)
+			transformLimitCounter(limitErr.Limit).Inc()
+			errorData["limit"] = limitErr.Limit
+		} else {
+			logger.Errorf("[WORKER] JQ transformation failed -> %v", err)
+		}
+		r.recordEvent(task, 0, task.Payload, errorData)
 		return
 	}
 
-	realUrl, err := encryption.Decrypt(task.TargetURL, r.cfg.Aes.EncryptionKey)
+	dest, err := r.destinationFor(ctx, task)
 	if err != nil {
-		logger.Errorf("[WORKER] failed to decrypt target URL -> %v", err)
-		_ = r.recordEvent(ctx, task, 0, task.Payload, map[string]string{
-			"error": "failed to decrypt target URL",
+		if ctx.Err() != nil {
+			r.requeue(ctx, j, raw, &r.shutdown.inFlight)
+			return
+		}
+		logger.Errorf("[WORKER] failed to prepare destination -> %v", err)
+		r.recordEvent(task, 0, task.Payload, map[string]string{
+			"error": err.Error(),
 		})
 		return
 	}
 
-	// send to destination
-
-	statusCode, err := r.deliverWebhook(ctx, realUrl, transformedPayload)
-	r.log.Info(statusCode)
-	if shouldRetry(statusCode, err) && task.RetryCount < MAX_RETRY {
-		task.RetryCount++
-		select {
-		case <-time.After(backOff(task.RetryCount)):
-		case <-ctx.Done():
+	// a task deferred by the rate limiter already holds its token
+	reserved := task.RateReserved
+	task.RateReserved = false
+	if !reserved {
+		deferred, err := r.pace(ctx, j, task, dest)
+		if err != nil {
+			if ctx.Err() != nil {
+				r.requeue(ctx, j, raw, &r.shutdown.inFlight)
+				return
+			}
+			logger.Errorf("[WORKER] failed to defer rate limited task -> %v", err)
+			_ = r.moveTODLQ(ctx, raw, err)
+			return
+		}
+		if deferred {
 			return
 		}
+	}
+
+	// send to destination
+	start := time.Now()
+	statusCode, err := dest.Deliver(ctx, task, transformedPayload)
+	r.observeLatency(time.Since(start))
+	if err != nil && ctx.Err() != nil {
+		// interrupted by the drain deadline, not a delivery failure
+		r.requeue(ctx, j, raw, &r.shutdown.inFlight)
+		return
+	}
+	// a runaway output template is the pipe's own doing, record it like
+	// a transform that hit its limits rather than retry it
+	var tmplErr *tmplguard.LimitError
+	if errors.As(err, &tmplErr) {
+		logger.Warnf("[WORKER] output template exceeded its %s limit -> pipe_id : %s", tmplErr.Limit, task.PipeID)
+		templateLimitCounter(tmplErr.Limit).Inc()
+		r.recordEvent(task, 0, task.Payload, map[string]string{
+			"error": err.Error(),
+			"limit": tmplErr.Limit,
+		})
+		return
+	}
+	if shouldRetry(dest, statusCode, err) && task.RetryCount < MAX_RETRY {
+		task.RetryCount++
 		rawRetry, marshalErr := json.Marshal(task)
 		if marshalErr != nil {
 			r.log.Errorf("[WORKER] failed to marshal retry task -> %v", marshalErr)
 			_ = r.moveTODLQ(ctx, raw, marshalErr)
 			return
 		}
-		if pushErr := r.cache.QueuePush(ctx, WEBHOOK_QUEUE_KEY, string(rawRetry)); pushErr != nil {
+		// once shutdown starts, park the retry in the deferred store
+		// rather than holding it in memory for the rest of its backoff
+		due := retryDue(dest, task.RetryCount)
+		select {
+		case <-time.After(time.Until(due)):
+		case <-r.stopping:
+			r.deferRetry(ctx, j.base, task, due, string(rawRetry))
+			return
+		case <-ctx.Done():
+			r.deferRetry(ctx, j.base, task, due, string(rawRetry))
+			return
+		}
+		if pushErr := queue.Push(ctx, r.cache, j.base, task.PipeID, string(rawRetry)); pushErr != nil {
 			r.log.Errorf("[WORKER] failed to requeue retry -> %v", pushErr)
 			_ = r.moveTODLQ(ctx, raw, pushErr)
 		}
@@ -202,71 +386,60 @@ func (r *Runner) process(ctx context.Context, raw string) {
 		}
 	}
 
-	if err := r.recordEvent(ctx, task, statusCode, task.Payload, transformedPayload); err != nil {
-		logger.Errorf("[WORKER] to save event log -> %v", err)
-	}
+	r.recordEvent(task, statusCode, task.Payload, transformedPayload)
 
 	r.publishRealtimeUpdate(ctx, task, statusCode, transformedPayload)
 
 }
 
-func (r *Runner) deliverWebhook(ctx context.Context, url string, payload any) (int, error) {
-	reqBody, err := json.Marshal(payload)
+// recordEvent hands an event to the batcher for persistence, with the
+// pipe's redaction rules applied to both payloads. It never waits on
+// the database; write failures are handled and logged by the batcher.
+func (r *Runner) recordEvent(task model.WorkerTask, status int, original, transformed any) {
+	original, transformed = r.redact(task, original), r.redact(task, transformed)
+	originalBytes, err := json.Marshal(original)
 	if err != nil {
-		return 0, fmt.Errorf("failed to marshal payload: %w", err)
+		r.log.Errorf("[WORKER] failed to marshal request payload, event not recorded -> %v", err)
+		return
 	}
-
-	// create a request with a short timeout
-	reqctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(reqctx, "POST", url, bytes.NewBuffer(reqBody))
+	transformBytes, err := json.Marshal(transformed)
 	if err != nil {
-		return 0, err
+		r.log.Errorf("[WORKER] failed to marshal transformed payload, event not recorded -> %v", err)
+		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "HookFilter-Worker/1.0")
-
-	resp, err := r.httpClient.Do(req)
+	// the event id and receive time keep the record idempotent when a
+	// task is processed again after a requeue
+	id, err := uuid.Parse(task.EventID)
 	if err != nil {
-		return 0, err
+		id = uuid.New()
 	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode, nil
-}
-
-// recordEvent enqueues an event for batched persistence.
-// An error here means the event could not be accepted into the batch,
-// Not that the database write failed.
-func (r *Runner) recordEvent(ctx context.Context, task model.WorkerTask, status int, original, transformed any) error {
-	originalBytes, err := json.Marshal(original)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request payload: %w", err)
-	}
-	transformBytes, err := json.Marshal(transformed)
-	if err != nil {
-		return fmt.Errorf("failed to marshal transformed payload: %w", err)
+	createdAt := task.ReceivedAt.UTC()
+	if task.ReceivedAt.IsZero() {
+		createdAt = time.Now().UTC()
 	}
 
-	return r.batcher.add(ctx, db.CreateEventParams{
-		ID:                 uuid.New(),
+	r.batcher.add(db.CreateEventParams{
+		ID:                 id,
 		PipeID:             task.PipeID,
 		StatusCode:         int32(status),
 		RequestPayload:     originalBytes,
 		TransformedPayload: transformBytes,
+		PipeRevision:       task.PipeRevision,
+		CreatedAt:          createdAt,
 	})
 }
 
+// publishRealtimeUpdate streams the event to the pipe's realtime
+// subscribers, redacted the same way as the stored event.
 func (r *Runner) publishRealtimeUpdate(ctx context.Context, task model.WorkerTask, status int, data any) {
 	evnt := model.RealtimeEvent{
 		ID:           task.EventID,
 		PipeID:       task.PipeID.String(),
 		StatusCode:   status,
 		ReceivedAt:   time.Now(),
-		Payload:      task.Payload,
-		ResponseBody: data,
+		Payload:      r.redact(task, task.Payload),
+		ResponseBody: r.redact(task, data),
 	}
 	msg, _ := json.Marshal(evnt)
 	channel := fmt.Sprintf("%s:%s", PUBLISH_CHANNE_KEY, task.PipeID.String())
@@ -291,3 +464,20 @@ func (r *Runner) moveTODLQ(ctx context.Context, rawTask string, errReason error)
 
 	return r.cache.QueuePush(ctx, DLQ_QUEUE_KEY, string(data))
 }
+
+func (r *Runner) transform(ctx context.Context, task model.WorkerTask) (any, error) {
+	prog, err := jsonfilter.Compile(task.JQFilter)
+	if err != nil {
+		return nil, err
+	}
+	return prog.Run(ctx, task.Payload, jsonfilter.Vars{
+		Pipe: jsonfilter.PipeMeta{
+			ID:   task.PipeID.String(),
+			Name: task.PipeName,
+			Slug: task.PipeSlug,
+		},
+		EventID:    task.EventID,
+		ReceivedAt: task.ReceivedAt,
+		Headers:    task.Headers,
+	}, jsonfilter.Limits(r.cfg.Filter))
+}
@@ -0,0 +1,328 @@
+package worker
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/google/uuid"
+)
+
+const (
+	// SCHEDULE_TTL is how long a pipe's weight and concurrency cap are
+	// cached before being re-read from the database.
+	SCHEDULE_TTL = 30 * time.Second
+	// IDLE_POLL_INTERVAL is how long the dispatcher waits after a round
+	// in which no pipe had work.
+	IDLE_POLL_INTERVAL = 200 * time.Millisecond
+	// LEGACY_BATCH is how many tasks are taken from the shared base queue
+	// per round; it only holds tasks enqueued before per-pipe sub-queues.
+	LEGACY_BATCH = 10
+	// PAUSE_SYNC_INTERVAL is how often the paused set in redis is
+	// reconciled with the database.
+	PAUSE_SYNC_INTERVAL = 30 * time.Second
+	// PAUSE_REFRESH_INTERVAL is how often the dispatcher's copy of the
+	// paused set is re-read from redis, and so how long a pause or
+	// resume may take to reach it.
+	PAUSE_REFRESH_INTERVAL = time.Second
+)
+
+type pipeSchedule struct {
+	weight         int
+	maxConcurrency int
+	fetchedAt      time.Time
+}
+
+// scheduler tracks per-pipe weights, concurrency caps and in-flight
+// counts for the weighted round-robin dispatcher.
+type scheduler struct {
+	mu         sync.Mutex
+	schedules  map[uuid.UUID]pipeSchedule
+	inFlight   map[uuid.UUID]int
+	defaultCap int
+	cursor     int
+}
+
+func newScheduler(defaultCap int) *scheduler {
+	return &scheduler{
+		schedules:  make(map[uuid.UUID]pipeSchedule),
+		inFlight:   make(map[uuid.UUID]int),
+		defaultCap: defaultCap,
+	}
+}
+
+func (s *scheduler) get(pipeID uuid.UUID) pipeSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sched, ok := s.schedules[pipeID]; ok {
+		return sched
+	}
+	return pipeSchedule{weight: 1, maxConcurrency: s.defaultCap}
+}
+
+// stale returns the pipes whose schedule is missing or expired.
+func (s *scheduler) stale(pipes []uuid.UUID) []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []uuid.UUID
+	for _, id := range pipes {
+		if sched, ok := s.schedules[id]; !ok || time.Since(sched.fetchedAt) > SCHEDULE_TTL {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (s *scheduler) set(pipeID uuid.UUID, weight, maxConcurrency int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if maxConcurrency <= 0 {
+		maxConcurrency = s.defaultCap
+	}
+	s.schedules[pipeID] = pipeSchedule{
+		weight:         max(weight, 1),
+		maxConcurrency: maxConcurrency,
+		fetchedAt:      time.Now(),
+	}
+}
+
+// acquire reserves an in-flight slot for the pipe. A cap of 0 means
+// the pipe is only bounded by the worker pool.
+func (s *scheduler) acquire(pipeID uuid.UUID, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit > 0 && s.inFlight[pipeID] >= limit {
+		return false
+	}
+	s.inFlight[pipeID]++
+	return true
+}
+
+func (s *scheduler) release(pipeID uuid.UUID) {
+	if pipeID == uuid.Nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[pipeID] <= 1 {
+		delete(s.inFlight, pipeID)
+		return
+	}
+	s.inFlight[pipeID]--
+}
+
+// rotate orders pipes deterministically and shifts the starting point
+// every round so no pipe is always served first.
+func (s *scheduler) rotate(pipes []uuid.UUID) []uuid.UUID {
+	if len(pipes) == 0 {
+		return pipes
+	}
+	slices.SortFunc(pipes, func(a, b uuid.UUID) int {
+		return slices.Compare(a[:], b[:])
+	})
+	s.mu.Lock()
+	start := s.cursor % len(pipes)
+	s.cursor++
+	s.mu.Unlock()
+	return append(pipes[start:], pipes[:start]...)
+}
+
+// refreshSchedules loads weights and caps for pipes not seen recently.
+// On failure the previous (or default) schedule stays in effect.
+func (r *Runner) refreshSchedules(ctx context.Context, pipes []uuid.UUID) {
+	stale := r.sched.stale(pipes)
+	if len(stale) == 0 {
+		return
+	}
+	rows, err := r.querier.GetPipeSchedules(ctx, stale)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to load pipe schedules -> %v", err)
+		return
+	}
+	found := make(map[uuid.UUID]bool, len(rows))
+	for _, row := range rows {
+		r.sched.set(row.ID, int(row.Weight), int(row.MaxConcurrency))
+		found[row.ID] = true
+	}
+	for _, id := range stale {
+		if !found[id] {
+			r.sched.set(id, 1, 0)
+		}
+	}
+}
+
+// syncPaused brings the redis paused set in line with the database, in
+// case the set was lost or a pause or resume only reached one of them.
+// The service keeps both in step otherwise.
+func (r *Runner) syncPaused(ctx context.Context) {
+	// redis is read first, so a pause or resume landing in between is
+	// already in the database and neither undone nor missed
+	current, err := queue.Paused(ctx, r.cache)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to read paused pipes -> %v", err)
+		return
+	}
+	ids, err := r.querier.ListPausedPipes(ctx)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to load paused pipes -> %v", err)
+		return
+	}
+
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if !current[id] {
+			missing = append(missing, id)
+		}
+		delete(current, id)
+	}
+	if err := queue.Pause(ctx, r.cache, missing...); err != nil {
+		r.log.Warnf("[WORKER] failed to restore paused pipes -> %v", err)
+	}
+	stale := make([]uuid.UUID, 0, len(current))
+	for id := range current {
+		stale = append(stale, id)
+	}
+	if err := queue.Resume(ctx, r.cache, stale...); err != nil {
+		r.log.Warnf("[WORKER] failed to clear resumed pipes -> %v", err)
+	}
+	r.refreshPaused(ctx)
+}
+
+// refreshPaused re-reads the paused set for the dispatcher. On failure
+// the previous copy is kept.
+func (r *Runner) refreshPaused(ctx context.Context) {
+	paused, err := queue.Paused(ctx, r.cache)
+	if err != nil {
+		r.log.Warnf("[WORKER] failed to refresh paused pipes -> %v", err)
+		return
+	}
+	r.paused.Store(&paused)
+	pausedGauge.Set(int64(len(paused)))
+}
+
+// pausedPipes returns the dispatcher's copy of the paused set.
+func (r *Runner) pausedPipes() map[uuid.UUID]bool {
+	if paused := r.paused.Load(); paused != nil {
+		return *paused
+	}
+	return nil
+}
+
+// pauseSyncer refreshes the paused set every PAUSE_REFRESH_INTERVAL and
+// runs syncPaused every PAUSE_SYNC_INTERVAL until ctx is cancelled.
+func (r *Runner) pauseSyncer(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(PAUSE_REFRESH_INTERVAL)
+	defer ticker.Stop()
+	nextSync := time.Now().Add(PAUSE_SYNC_INTERVAL)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Before(nextSync) {
+				r.refreshPaused(ctx)
+				continue
+			}
+			nextSync = now.Add(PAUSE_SYNC_INTERVAL)
+		}
+		r.syncPaused(ctx)
+	}
+}
+
+// activePipes returns the pipes with pending tasks on base.
+func (r *Runner) activePipes(ctx context.Context, base string) ([]uuid.UUID, error) {
+	members, err := r.cache.SetMembers(ctx, queue.ActiveKey(base))
+	if err != nil {
+		return nil, err
+	}
+	pipes := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(m)
+		if err != nil {
+			continue
+		}
+		pipes = append(pipes, id)
+	}
+	return pipes, nil
+}
+
+// dispatchRound visits every active pipe once in round-robin order and
+// hands up to weight tasks from each to the workers, skipping pipes
+// that are at their concurrency cap or, going by the copy pauseSyncer
+// keeps, paused. It returns how many tasks were dispatched.
+func (r *Runner) dispatchRound(ctx context.Context) (int, error) {
+	paused := r.pausedPipes()
+
+	dispatched := 0
+	for _, base := range r.queues {
+		pipes, err := r.activePipes(ctx, base)
+		if err != nil {
+			return dispatched, err
+		}
+		// a paused pipe's sub-queue is its buffer, it stays tracked as
+		// active so resuming needs nothing but leaving the paused set
+		pipes = slices.DeleteFunc(pipes, func(id uuid.UUID) bool { return paused[id] })
+		r.refreshSchedules(ctx, pipes)
+
+		for _, pipeID := range r.sched.rotate(pipes) {
+			sched := r.sched.get(pipeID)
+			key := queue.PipeKey(base, pipeID)
+
+			for range sched.weight {
+				if !r.sched.acquire(pipeID, sched.maxConcurrency) {
+					break
+				}
+				raw, ok, err := r.cache.QueueTryPop(ctx, key)
+				if err != nil {
+					r.sched.release(pipeID)
+					return dispatched, err
+				}
+				if !ok {
+					r.sched.release(pipeID)
+					if _, err := r.cache.QueueUntrackIfEmpty(ctx, queue.ActiveKey(base), pipeID.String(), key); err != nil {
+						r.log.Warnf("[WORKER] failed to untrack idle pipe -> %v", err)
+					}
+					break
+				}
+				if !r.send(ctx, job{base: base, queue: key, pipeID: pipeID, raw: raw}) {
+					return dispatched, ctx.Err()
+				}
+				dispatched++
+			}
+		}
+
+		// tasks enqueued before per-pipe sub-queues existed
+		for range LEGACY_BATCH {
+			raw, ok, err := r.cache.QueueTryPop(ctx, base)
+			if err != nil {
+				return dispatched, err
+			}
+			if !ok {
+				break
+			}
+			if !r.send(ctx, job{base: base, queue: base, raw: raw}) {
+				return dispatched, ctx.Err()
+			}
+			dispatched++
+		}
+	}
+	return dispatched, nil
+}
+
+// send hands a popped task to the workers. If ctx is cancelled first the
+// task is pushed back to the queue it came from instead of being dropped.
+func (r *Runner) send(ctx context.Context, j job) bool {
+	select {
+	case r.jobs <- j:
+		return true
+	case <-ctx.Done():
+		r.sched.release(j.pipeID)
+		r.requeue(ctx, j, j.raw, &r.shutdown.buffered)
+		return false
+	}
+}
@@ -0,0 +1,274 @@
+package worker
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	db "github.com/MobasirSarkar/hookfilter/internal/database"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/MobasirSarkar/hookfilter/pkg/config"
+	"github.com/MobasirSarkar/hookfilter/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// memCache keeps the lists, sets and delayed stores the worker uses in
+// memory. Lists are kept in pop order.
+type memCache struct {
+	cache.Cacher
+
+	mu      sync.Mutex
+	lists   map[string][]string
+	sets    map[string]map[string]bool
+	delayed map[string]map[string]memDelayed
+	// reads counts SetMembers calls per key
+	reads map[string]int
+}
+
+// memDelayed is a task held in one of memCache's delayed stores.
+type memDelayed struct {
+	due time.Time
+	raw string
+}
+
+func newMemCache() *memCache {
+	return &memCache{
+		lists:   make(map[string][]string),
+		sets:    make(map[string]map[string]bool),
+		delayed: make(map[string]map[string]memDelayed),
+		reads:   make(map[string]int),
+	}
+}
+
+func (c *memCache) QueuePush(_ context.Context, queue, val string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists[queue] = append(c.lists[queue], val)
+	return nil
+}
+
+func (c *memCache) QueuePushFront(ctx context.Context, queue, val string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists[queue] = append([]string{val}, c.lists[queue]...)
+	return nil
+}
+
+func (c *memCache) QueueTryPop(_ context.Context, queue string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.lists[queue]) == 0 {
+		return "", false, nil
+	}
+	val := c.lists[queue][0]
+	c.lists[queue] = c.lists[queue][1:]
+	return val, true, nil
+}
+
+func (c *memCache) QueueUntrackIfEmpty(_ context.Context, set, member, queue string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.lists[queue]) > 0 {
+		return false, nil
+	}
+	delete(c.sets[set], member)
+	return true, nil
+}
+
+func (c *memCache) SetAdd(_ context.Context, key string, members ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sets[key] == nil {
+		c.sets[key] = make(map[string]bool)
+	}
+	for _, m := range members {
+		c.sets[key][m] = true
+	}
+	return nil
+}
+
+func (c *memCache) SetMembers(_ context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads[key]++
+	members := make([]string, 0, len(c.sets[key]))
+	for m := range c.sets[key] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (c *memCache) SetRemove(_ context.Context, key string, members ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range members {
+		delete(c.sets[key], m)
+	}
+	return nil
+}
+
+func (c *memCache) DelayedAdd(_ context.Context, keys cache.DelayedKeys, member string, due time.Time, raw string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delayed[keys.Schedule] == nil {
+		c.delayed[keys.Schedule] = make(map[string]memDelayed)
+	}
+	c.delayed[keys.Schedule][member] = memDelayed{due: due, raw: raw}
+	return nil
+}
+
+// pausedQuerier reports paused as the pipes paused in the database.
+type pausedQuerier struct {
+	db.Querier
+	paused []uuid.UUID
+}
+
+func (q *pausedQuerier) GetPipeSchedules(context.Context, []uuid.UUID) ([]db.GetPipeSchedulesRow, error) {
+	return nil, nil
+}
+
+func (q *pausedQuerier) ListPausedPipes(context.Context) ([]uuid.UUID, error) {
+	return q.paused, nil
+}
+
+func newTestRunner(c cache.Cacher, q db.Querier) *Runner {
+	return &Runner{
+		cache:   c,
+		querier: q,
+		log:     logger.NewLogger(&config.Config{}),
+		queues:  []string{WEBHOOK_QUEUE_KEY},
+		sched:   newScheduler(0),
+		jobs:    make(chan job, 100),
+	}
+}
+
+// dispatched runs dispatch rounds until one hands out nothing and
+// returns the tasks handed out, in order.
+func dispatched(t *testing.T, r *Runner) []string {
+	t.Helper()
+	var got []string
+	for {
+		n, err := r.dispatchRound(context.Background())
+		if err != nil {
+			t.Fatalf("dispatchRound() unexpected error: %v", err)
+		}
+		for range n {
+			j := <-r.jobs
+			r.sched.release(j.pipeID)
+			got = append(got, j.raw)
+		}
+		if n == 0 {
+			return got
+		}
+	}
+}
+
+func TestDispatchPausedPipe(t *testing.T) {
+	ctx := context.Background()
+	c := newMemCache()
+	r := newTestRunner(c, &pausedQuerier{})
+	paused, running := uuid.New(), uuid.New()
+
+	if err := queue.Pause(ctx, c, paused); err != nil {
+		t.Fatal(err)
+	}
+	r.refreshPaused(ctx)
+	for _, task := range []string{"p1", "p2", "p3"} {
+		if err := queue.Push(ctx, c, WEBHOOK_QUEUE_KEY, paused, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := queue.Push(ctx, c, WEBHOOK_QUEUE_KEY, running, "r1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dispatched(t, r); !slices.Equal(got, []string{"r1"}) {
+		t.Fatalf("dispatched while paused = %v, want only the running pipe's task", got)
+	}
+	if got := c.lists[queue.PipeKey(WEBHOOK_QUEUE_KEY, paused)]; len(got) != 3 {
+		t.Fatalf("paused buffer = %v, want all 3 tasks kept", got)
+	}
+
+	if err := queue.Resume(ctx, c, paused); err != nil {
+		t.Fatal(err)
+	}
+	r.refreshPaused(ctx)
+	if got := dispatched(t, r); !slices.Equal(got, []string{"p1", "p2", "p3"}) {
+		t.Errorf("dispatched after resume = %v, want the buffer in arrival order", got)
+	}
+	if got := c.lists[queue.PipeKey(WEBHOOK_QUEUE_KEY, paused)]; len(got) != 0 {
+		t.Errorf("buffer after drain = %v, want it empty", got)
+	}
+	if c.sets[queue.ActiveKey(WEBHOOK_QUEUE_KEY)][paused.String()] {
+		t.Error("drained pipe still tracked as active")
+	}
+}
+
+func TestDispatchReadsCachedPausedSet(t *testing.T) {
+	ctx := context.Background()
+	c := newMemCache()
+	r := newTestRunner(c, &pausedQuerier{})
+	paused := uuid.New()
+
+	if err := queue.Push(ctx, c, WEBHOOK_QUEUE_KEY, paused, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Pause(ctx, c, paused); err != nil {
+		t.Fatal(err)
+	}
+
+	// until pauseSyncer refreshes, the dispatcher goes by its last copy
+	for range 3 {
+		if _, err := r.dispatchRound(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := c.reads[queue.PAUSED_KEY]; n != 0 {
+		t.Errorf("dispatch rounds read the paused set %d times, want 0", n)
+	}
+	if got := len(r.jobs); got != 1 {
+		t.Fatalf("dispatched %d tasks before the refresh, want 1", got)
+	}
+	j := <-r.jobs
+	r.sched.release(j.pipeID)
+
+	if err := queue.Push(ctx, c, WEBHOOK_QUEUE_KEY, paused, "p2"); err != nil {
+		t.Fatal(err)
+	}
+	r.refreshPaused(ctx)
+	if got := dispatched(t, r); len(got) != 0 {
+		t.Errorf("dispatched after the refresh = %v, want nothing", got)
+	}
+}
+
+func TestSyncPaused(t *testing.T) {
+	ctx := context.Background()
+	c := newMemCache()
+	lost, resumed, kept := uuid.New(), uuid.New(), uuid.New()
+
+	// resumed was cleared in the database but not in redis, lost the
+	// other way round
+	if err := queue.Pause(ctx, c, resumed, kept); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRunner(c, &pausedQuerier{paused: []uuid.UUID{lost, kept}})
+	r.syncPaused(ctx)
+
+	got, err := queue.Paused(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uuid.UUID]bool{lost: true, kept: true}
+	if len(got) != len(want) || !got[lost] || !got[kept] {
+		t.Errorf("paused after sync = %v, want %v", got, want)
+	}
+	if cached := r.pausedPipes(); len(cached) != len(want) || !cached[lost] || !cached[kept] {
+		t.Errorf("dispatcher's paused set after sync = %v, want %v", cached, want)
+	}
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+)
+
+// ShutdownReport describes what Stop did with work that had not been
+// delivered when the worker shut down.
+type ShutdownReport struct {
+	// Buffered tasks were popped from redis but never started.
+	Buffered int64
+	// InFlight deliveries were interrupted by the drain deadline.
+	InFlight int64
+	// Backoff retries were moved to the deferred store instead of
+	// sleeping out their backoff in memory.
+	Backoff int64
+	// Failed tasks could not be handed back to redis and were logged.
+	Failed int64
+	// TimedOut is set when the drain deadline passed.
+	TimedOut bool
+}
+
+// Requeued is the number of tasks handed back to redis.
+func (s ShutdownReport) Requeued() int64 {
+	return s.Buffered + s.InFlight + s.Backoff
+}
+
+type shutdownCounters struct {
+	buffered, inFlight, backoff, failed atomic.Int64
+}
+
+func (c *shutdownCounters) report(timedOut bool) ShutdownReport {
+	return ShutdownReport{
+		Buffered: c.buffered.Load(),
+		InFlight: c.inFlight.Load(),
+		Backoff:  c.backoff.Load(),
+		Failed:   c.failed.Load(),
+		TimedOut: timedOut,
+	}
+}
+
+// requeue hands an unfinished task back to the front of the queue it was
+// popped from. It runs during shutdown, so ctx's cancellation is ignored.
+func (r *Runner) requeue(ctx context.Context, j job, raw string, counter *atomic.Int64) {
+	if err := queue.Requeue(context.WithoutCancel(ctx), r.cache, j.base, j.pipeID, raw); err != nil {
+		r.shutdown.failed.Add(1)
+		r.log.Errorf("[WORKER] failed to requeue task, it is lost -> %v, task: %s", err, raw)
+		return
+	}
+	counter.Add(1)
+}
+
+// deferRetry stores a retry that was waiting out its backoff in the
+// internal deferred store, due when the backoff would have ended.
+func (r *Runner) deferRetry(ctx context.Context, base string, task model.WorkerTask, due time.Time, raw string) {
+	if err := queue.Defer(context.WithoutCancel(ctx), r.cache, base, task.PipeID, task.EventID, due, raw); err != nil {
+		r.shutdown.failed.Add(1)
+		r.log.Errorf("[WORKER] failed to defer retry, it is lost -> %v, task: %s", err, raw)
+		return
+	}
+	r.shutdown.backoff.Add(1)
+}
+
+// drainBuffered requeues every task still sitting in the jobs channel.
+// The dispatcher must have exited (and closed the channel) first.
+func (r *Runner) drainBuffered() {
+	for j := range r.jobs {
+		r.sched.release(j.pipeID)
+		r.requeue(context.Background(), j, j.raw, &r.shutdown.buffered)
+	}
+}
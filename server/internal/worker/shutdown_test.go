@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/cache"
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+	"github.com/google/uuid"
+)
+
+// downCache fails every write, like a redis that went away mid-drain.
+type downCache struct {
+	cache.Cacher
+}
+
+var errRedisDown = errors.New("redis is down")
+
+func (downCache) QueuePushFront(context.Context, string, string) error { return errRedisDown }
+
+func (downCache) DelayedAdd(context.Context, cache.DelayedKeys, string, time.Time, string) error {
+	return errRedisDown
+}
+
+func TestDrainBufferedRequeuesInOrder(t *testing.T) {
+	c := newMemCache()
+	r := newTestRunner(c, &pausedQuerier{})
+	pipe := uuid.New()
+	key := queue.PipeKey(WEBHOOK_QUEUE_KEY, pipe)
+	c.lists[key] = []string{"t4"}
+
+	// t1..t3 were popped in order and are still buffered
+	for _, raw := range []string{"t1", "t2", "t3"} {
+		r.jobs <- job{base: WEBHOOK_QUEUE_KEY, queue: key, pipeID: pipe, raw: raw}
+	}
+	close(r.jobs)
+	r.drainBuffered()
+
+	// each requeue goes to the front, so the last drained pops first
+	if got, want := c.lists[key], []string{"t3", "t2", "t1", "t4"}; !slices.Equal(got, want) {
+		t.Errorf("queue after drain = %v, want %v", got, want)
+	}
+	if !c.sets[queue.ActiveKey(WEBHOOK_QUEUE_KEY)][pipe.String()] {
+		t.Error("pipe not marked active after requeue")
+	}
+	if got := r.shutdown.report(false); got.Buffered != 3 || got.Requeued() != 3 || got.Failed != 0 {
+		t.Errorf("report = %+v, want 3 buffered and requeued", got)
+	}
+}
+
+func TestDeferRetry(t *testing.T) {
+	c := newMemCache()
+	r := newTestRunner(c, &pausedQuerier{})
+	task := model.WorkerTask{EventID: "evt-1", PipeID: uuid.New()}
+	due := time.Now().Add(time.Minute)
+
+	r.deferRetry(context.Background(), WEBHOOK_QUEUE_KEY, task, due, "retry")
+
+	store := c.delayed[WEBHOOK_QUEUE_KEY+":"+queue.DEFERRED_STORE]
+	got, ok := store[task.PipeID.String()+":"+task.EventID]
+	if !ok || got.raw != "retry" || !got.due.Equal(due) {
+		t.Fatalf("deferred store = %v, want the retry due at %v", store, due)
+	}
+	if len(c.delayed[WEBHOOK_QUEUE_KEY+":"+queue.DELAYED_STORE]) != 0 {
+		t.Error("retry landed in the user-visible scheduled store")
+	}
+	if got := r.shutdown.report(false); got.Backoff != 1 || got.Requeued() != 1 {
+		t.Errorf("report = %+v, want 1 backoff", got)
+	}
+}
+
+func TestShutdownReportCountsFailures(t *testing.T) {
+	r := newTestRunner(downCache{}, &pausedQuerier{})
+	pipe := uuid.New()
+
+	r.requeue(context.Background(), job{base: WEBHOOK_QUEUE_KEY, pipeID: pipe, raw: "t1"}, "t1", &r.shutdown.inFlight)
+	r.deferRetry(context.Background(), WEBHOOK_QUEUE_KEY, model.WorkerTask{EventID: "evt-1", PipeID: pipe}, time.Now(), "t2")
+
+	want := ShutdownReport{Failed: 2, TimedOut: true}
+	if got := r.shutdown.report(true); got != want {
+		t.Errorf("report = %+v, want %+v", got, want)
+	}
+}
+
+func TestRequeueCancelledContext(t *testing.T) {
+	c := newMemCache()
+	r := newTestRunner(c, &pausedQuerier{})
+	pipe := uuid.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the drain deadline cancels ctx, the task must still go back
+	r.requeue(ctx, job{base: WEBHOOK_QUEUE_KEY, pipeID: pipe, raw: "t1"}, "t1", &r.shutdown.inFlight)
+
+	if got := c.lists[queue.PipeKey(WEBHOOK_QUEUE_KEY, pipe)]; !slices.Equal(got, []string{"t1"}) {
+		t.Errorf("queue = %v, want the interrupted task back", got)
+	}
+	if got := r.shutdown.report(false); got.InFlight != 1 {
+		t.Errorf("report = %+v, want 1 in flight", got)
+	}
+}
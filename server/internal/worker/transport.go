@@ -0,0 +1,169 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/pkg/encryption"
+	"github.com/MobasirSarkar/hookfilter/pkg/netguard"
+	"github.com/MobasirSarkar/hookfilter/pkg/tlsprofile"
+	"github.com/MobasirSarkar/hookfilter/pkg/utils"
+)
+
+// newHTTPClient builds a delivery client. Every connection, including
+// redirects, is checked against the resolved IP so DNS rebinding cannot
+// reach private ranges.
+func newHTTPClient(guard *netguard.Guard, tlsCfg *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+				Control:   guard.Control,
+			}).DialContext,
+			TLSClientConfig:     tlsCfg,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 20,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+const (
+	// POOL_IDLE_TTL is how long a pooled client or file sink may go
+	// unused before it is closed and dropped.
+	POOL_IDLE_TTL = 10 * time.Minute
+	// POOL_SWEEP_INTERVAL is how often the pools are checked for idle
+	// entries.
+	POOL_SWEEP_INTERVAL = time.Minute
+)
+
+// transportPool keeps one client per TLS profile so connections are
+// reused between pipes sharing identical settings.
+type transportPool struct {
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	client   *http.Client
+	lastUsed time.Time
+}
+
+func (p *transportPool) get(key string) (*http.Client, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.clients[key]
+	if !ok {
+		return nil, false
+	}
+	c.lastUsed = time.Now()
+	return c.client, true
+}
+
+func (p *transportPool) put(key string, c *http.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clients[key] = &pooledClient{client: c, lastUsed: time.Now()}
+}
+
+// evictIdle drops the clients unused since before and closes their idle
+// connections. Requests still running on one finish normally.
+func (p *transportPool) evictIdle(before time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for key, c := range p.clients {
+		if c.lastUsed.Before(before) {
+			c.client.CloseIdleConnections()
+			delete(p.clients, key)
+			n++
+		}
+	}
+	return n
+}
+
+// poolSweeper evicts pooled clients and file sinks idle for longer than
+// POOL_IDLE_TTL until ctx is cancelled.
+func (r *Runner) poolSweeper(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(POOL_SWEEP_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		before := time.Now().Add(-POOL_IDLE_TTL)
+		if n := r.transports.evictIdle(before); n > 0 {
+			r.log.Debugf("[WORKER] closed %d idle tls clients", n)
+		}
+		if n := r.files.evictIdle(before); n > 0 {
+			r.log.Debugf("[WORKER] closed %d idle file sinks", n)
+		}
+	}
+}
+
+// clientFor returns the HTTP client for the task's TLS profile. Tasks
+// without a profile use the shared default client.
+func (r *Runner) clientFor(ctx context.Context, task model.WorkerTask) (*http.Client, error) {
+	if task.TLSProfile == "" {
+		return r.httpClient, nil
+	}
+	if c, ok := r.transports.get(task.TLSProfile); ok {
+		return c, nil
+	}
+
+	row, err := r.querier.GetPipeTLS(ctx, task.PipeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls profile: %w", err)
+	}
+
+	// the pipe may have changed since ingest, key by what is stored now
+	key := tlsprofile.Key(
+		utils.Deref(row.TlsClientCert),
+		utils.Deref(row.TlsClientKey),
+		utils.Deref(row.TlsCaBundle),
+		row.TlsSpkiPins,
+		utils.Deref(row.TlsMinVersion),
+	)
+	if key == "" {
+		return r.httpClient, nil
+	}
+	if c, ok := r.transports.get(key); ok {
+		return c, nil
+	}
+
+	profile := tlsprofile.Profile{
+		ClientCert: utils.Deref(row.TlsClientCert),
+		CABundle:   utils.Deref(row.TlsCaBundle),
+		SPKIPins:   row.TlsSpkiPins,
+		MinVersion: utils.Deref(row.TlsMinVersion),
+	}
+	if row.TlsClientKey != nil {
+		profile.ClientKey, err = encryption.Decrypt(*row.TlsClientKey, r.cfg.Aes.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt tls client key: %w", err)
+		}
+	}
+
+	tlsCfg, err := profile.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	c := newHTTPClient(r.guard, tlsCfg)
+	r.transports.put(key, c)
+	return c, nil
+}
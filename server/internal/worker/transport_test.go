@@ -0,0 +1,29 @@
+package worker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTransportPoolEvictIdle(t *testing.T) {
+	pool := &transportPool{clients: make(map[string]*pooledClient)}
+	pool.put("idle", &http.Client{})
+	pool.put("busy", &http.Client{})
+	pool.clients["idle"].lastUsed = time.Now().Add(-2 * POOL_IDLE_TTL)
+	pool.clients["busy"].lastUsed = time.Now().Add(-2 * POOL_IDLE_TTL)
+
+	// a lookup counts as use
+	if _, ok := pool.get("busy"); !ok {
+		t.Fatal("get(busy) missing")
+	}
+	if n := pool.evictIdle(time.Now().Add(-POOL_IDLE_TTL)); n != 1 {
+		t.Errorf("evictIdle() = %d, want 1", n)
+	}
+	if _, ok := pool.get("idle"); ok {
+		t.Errorf("idle client was kept")
+	}
+	if _, ok := pool.get("busy"); !ok {
+		t.Errorf("recently used client was evicted")
+	}
+}
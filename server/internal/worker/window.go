@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MobasirSarkar/hookfilter/internal/model"
+	"github.com/MobasirSarkar/hookfilter/internal/queue"
+)
+
+// takeWindow closes the window a flush task points at and returns the
+// payload to deliver: the kept event for latest/first windows, or an
+// array of every event for collect windows. ok is false when another
+// trigger already flushed the window.
+func (r *Runner) takeWindow(ctx context.Context, task model.WorkerTask) (any, bool, error) {
+	values, ok, err := queue.TakeWindow(ctx, r.cache, task.WindowKey, task.WindowID)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	events := make([]any, 0, len(values))
+	for _, v := range values {
+		var event any
+		if err := json.Unmarshal([]byte(v), &event); err != nil {
+			return nil, false, fmt.Errorf("failed to decode windowed event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if task.WindowMode == model.WindowCollect {
+		return events, true, nil
+	}
+	if len(events) == 0 {
+		return nil, false, nil
+	}
+	return events[0], true, nil
+}
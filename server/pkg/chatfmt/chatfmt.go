@@ -0,0 +1,198 @@
+package chatfmt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/tmplguard"
+)
+
+var ErrInvalidTemplate = errors.New("invalid message template")
+
+// namedColors are accepted in place of a hex color.
+var namedColors = map[string]string{
+	"good":    "2eb886",
+	"warning": "daa038",
+	"danger":  "a30200",
+}
+
+// Field is a name/value pair shown in the message body.
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// Template describes a chat message. Every string is a Go text/template
+// executed against the transformed payload, e.g. "PR {{.pull_request.title}}".
+type Template struct {
+	Title  string  `json:"title"`
+	Text   string  `json:"text"`
+	Link   string  `json:"link"`
+	Color  string  `json:"color"`
+	Fields []Field `json:"fields"`
+}
+
+// Message is a rendered Template. Color is a 6 digit hex string without '#'.
+type Message struct {
+	Title  string
+	Text   string
+	Link   string
+	Color  string
+	Fields []Field
+}
+
+type compiledField struct {
+	name   *template.Template
+	value  *template.Template
+	inline bool
+}
+
+// Compiled is a parsed Template ready to render.
+type Compiled struct {
+	title, text, link, color *template.Template
+	fields                   []compiledField
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Compile parses every template in t.
+func (t Template) Compile() (*Compiled, error) {
+	if strings.TrimSpace(t.Title) == "" && strings.TrimSpace(t.Text) == "" {
+		return nil, fmt.Errorf("%w: title or text is required", ErrInvalidTemplate)
+	}
+	if len(t.Fields) > 25 {
+		return nil, fmt.Errorf("%w: at most 25 fields are allowed", ErrInvalidTemplate)
+	}
+
+	c := &Compiled{}
+	var err error
+	for _, p := range []struct {
+		dst  **template.Template
+		name string
+		src  string
+	}{
+		{&c.title, "title", t.Title},
+		{&c.text, "text", t.Text},
+		{&c.link, "link", t.Link},
+		{&c.color, "color", t.Color},
+	} {
+		if *p.dst, err = parse(p.name, p.src); err != nil {
+			return nil, err
+		}
+	}
+	for i, f := range t.Fields {
+		name, err := parse(fmt.Sprintf("fields[%d].name", i), f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parse(fmt.Sprintf("fields[%d].value", i), f.Value)
+		if err != nil {
+			return nil, err
+		}
+		c.fields = append(c.fields, compiledField{name: name, value: value, inline: f.Inline})
+	}
+	return c, nil
+}
+
+// Render executes the templates against data. All of them share one
+// tmplguard.DefaultLimits budget; exceeding it returns a
+// *tmplguard.LimitError.
+func (c *Compiled) Render(data any) (Message, error) {
+	var m Message
+	var err error
+	deadline := time.Now().Add(tmplguard.DefaultLimits.Timeout)
+	if m.Title, err = execute(c.title, data, deadline); err != nil {
+		return m, err
+	}
+	if m.Text, err = execute(c.text, data, deadline); err != nil {
+		return m, err
+	}
+	if m.Link, err = execute(c.link, data, deadline); err != nil {
+		return m, err
+	}
+	color, err := execute(c.color, data, deadline)
+	if err != nil {
+		return m, err
+	}
+	m.Color = normalizeColor(color)
+
+	for _, f := range c.fields {
+		name, err := execute(f.name, data, deadline)
+		if err != nil {
+			return m, err
+		}
+		value, err := execute(f.value, data, deadline)
+		if err != nil {
+			return m, err
+		}
+		m.Fields = append(m.Fields, Field{Name: name, Value: value, Inline: f.inline})
+	}
+	return m, nil
+}
+
+func parse(name, src string) (*template.Template, error) {
+	if src == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Option("missingkey=zero").Funcs(funcs).Funcs(tmplguard.Funcs()).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if err := tmplguard.Prepare(t); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return t, nil
+}
+
+// execute renders t with what is left of the budget before deadline.
+func execute(t *template.Template, data any, deadline time.Time) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	limits := tmplguard.DefaultLimits
+	limits.Timeout = time.Until(deadline)
+	if limits.Timeout <= 0 {
+		return "", &tmplguard.LimitError{Limit: tmplguard.LIMIT_TIMEOUT, Max: tmplguard.DefaultLimits.Timeout.String()}
+	}
+	out, err := tmplguard.Execute(t, data, limits)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", t.Name(), err)
+	}
+	// missing keys on map payloads render as "<no value>"
+	return strings.TrimSpace(strings.ReplaceAll(string(out), "<no value>", "")), nil
+}
+
+func normalizeColor(c string) string {
+	c = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c), "#"))
+	if named, ok := namedColors[c]; ok {
+		return named
+	}
+	if len(c) != 6 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(c, 16, 32); err != nil {
+		return ""
+	}
+	return c
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,116 @@
+package chatfmt
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/tmplguard"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    Template
+		wantErr bool
+	}{
+		{name: "title only", tmpl: Template{Title: "hello"}},
+		{name: "text only", tmpl: Template{Text: "{{.msg}}"}},
+		{name: "missing title and text", tmpl: Template{Link: "https://example.com"}, wantErr: true},
+		{name: "bad syntax", tmpl: Template{Title: "{{.a"}, wantErr: true},
+		{name: "bad field", tmpl: Template{Title: "x", Fields: []Field{{Name: "a", Value: "{{end}}"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.tmpl.Compile()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTemplate) {
+				t.Errorf("expected ErrInvalidTemplate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl := Template{
+		Title:  "PR {{.pr.title}}",
+		Text:   "by {{.pr.user}}{{.missing}}",
+		Link:   "{{.pr.url}}",
+		Color:  "{{if .pr.merged}}good{{else}}#FF0000{{end}}",
+		Fields: []Field{{Name: "Repo", Value: "{{.repo}}", Inline: true}},
+	}
+	c, err := tmpl.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload any
+	_ = json.Unmarshal([]byte(`{"repo":"hookfilter","pr":{"title":"Fix","user":"sam","url":"https://x.test/1","merged":true}}`), &payload)
+
+	m, err := c.Render(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Title != "PR Fix" || m.Text != "by sam" || m.Link != "https://x.test/1" {
+		t.Errorf("unexpected message: %+v", m)
+	}
+	if m.Color != "2eb886" {
+		t.Errorf("color = %q, want named color resolved", m.Color)
+	}
+	if len(m.Fields) != 1 || m.Fields[0].Value != "hookfilter" || !m.Fields[0].Inline {
+		t.Errorf("unexpected fields: %+v", m.Fields)
+	}
+}
+
+func TestRenderLimits(t *testing.T) {
+	for _, text := range []string{
+		"{{range 1000000000}}{{end}}",
+		`{{range 2000000}}{{"x"}}{{end}}`,
+	} {
+		c, err := Template{Title: "t", Text: text}.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Render(nil); !errors.Is(err, tmplguard.ErrLimitExceeded) {
+			t.Errorf("Render(%q) error = %v, want a limit error", text, err)
+		}
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	m := Message{
+		Title:  strings.Repeat("t", 300),
+		Text:   "body",
+		Link:   "https://x.test",
+		Color:  "ff0000",
+		Fields: []Field{{Name: "", Value: ""}},
+	}
+
+	tests := []struct {
+		name string
+		body any
+		want []string
+	}{
+		{name: "slack", body: Slack(m), want: []string{`"type":"header"`, `"color":"#ff0000"`, `"type":"button"`}},
+		{name: "discord", body: Discord(m), want: []string{`"color":16711680`, `"name":"-"`, `"description":"body"`}},
+		{name: "teams", body: Teams(m), want: []string{`"@type":"MessageCard"`, `"themeColor":"ff0000"`, `"OpenUri"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(b), w) {
+					t.Errorf("%s payload missing %s: %s", tt.name, w, b)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package chatfmt
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Slack builds an incoming webhook payload using Block Kit inside a
+// colored attachment.
+func Slack(m Message) any {
+	var blocks []map[string]any
+	if m.Title != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": truncate(m.Title, 150)},
+		})
+	}
+	if m.Text != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": truncate(m.Text, 3000)},
+		})
+	}
+	// a section holds at most 10 fields
+	for i := 0; i < len(m.Fields); i += 10 {
+		var fields []map[string]any
+		for _, f := range m.Fields[i:min(i+10, len(m.Fields))] {
+			fields = append(fields, map[string]any{
+				"type": "mrkdwn",
+				"text": truncate(fmt.Sprintf("*%s*\n%s", f.Name, f.Value), 2000),
+			})
+		}
+		blocks = append(blocks, map[string]any{"type": "section", "fields": fields})
+	}
+	if m.Link != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "actions",
+			"elements": []map[string]any{{
+				"type": "button",
+				"text": map[string]any{"type": "plain_text", "text": "Open"},
+				"url":  m.Link,
+			}},
+		})
+	}
+
+	fallback := m.Title
+	if fallback == "" {
+		fallback = m.Text
+	}
+	attachment := map[string]any{"blocks": blocks}
+	if m.Color != "" {
+		attachment["color"] = "#" + m.Color
+	}
+	return map[string]any{
+		"text":        truncate(fallback, 3000),
+		"attachments": []map[string]any{attachment},
+	}
+}
+
+// Discord builds an execute-webhook payload with a single embed.
+func Discord(m Message) any {
+	embed := map[string]any{}
+	if m.Title != "" {
+		embed["title"] = truncate(m.Title, 256)
+	}
+	if m.Text != "" {
+		embed["description"] = truncate(m.Text, 4096)
+	}
+	if m.Link != "" {
+		embed["url"] = m.Link
+	}
+	if m.Color != "" {
+		if c, err := strconv.ParseInt(m.Color, 16, 32); err == nil {
+			embed["color"] = c
+		}
+	}
+	if len(m.Fields) > 0 {
+		fields := make([]map[string]any, 0, len(m.Fields))
+		for _, f := range m.Fields {
+			fields = append(fields, map[string]any{
+				"name":   truncate(orDash(f.Name), 256),
+				"value":  truncate(orDash(f.Value), 1024),
+				"inline": f.Inline,
+			})
+		}
+		embed["fields"] = fields
+	}
+	return map[string]any{"embeds": []map[string]any{embed}}
+}
+
+// Teams builds a connector MessageCard for an incoming webhook.
+func Teams(m Message) any {
+	summary := m.Title
+	if summary == "" {
+		summary = truncate(m.Text, 80)
+	}
+	card := map[string]any{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  summary,
+	}
+	if m.Title != "" {
+		card["title"] = m.Title
+	}
+	if m.Text != "" {
+		card["text"] = m.Text
+	}
+	if m.Color != "" {
+		card["themeColor"] = m.Color
+	}
+	if len(m.Fields) > 0 {
+		facts := make([]map[string]any, 0, len(m.Fields))
+		for _, f := range m.Fields {
+			facts = append(facts, map[string]any{"name": f.Name, "value": f.Value})
+		}
+		card["sections"] = []map[string]any{{"facts": facts}}
+	}
+	if m.Link != "" {
+		card["potentialAction"] = []map[string]any{{
+			"@type":   "OpenUri",
+			"name":    "Open",
+			"targets": []map[string]any{{"os": "default", "uri": m.Link}},
+		}}
+	}
+	return card
+}
+
+// Discord rejects empty field names and values.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -2,6 +2,11 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/MobasirSarkar/hookfilter/pkg/utils"
 )
@@ -12,6 +17,9 @@ type Config struct {
 		Hostname string
 		Env      string
 		Port     int
+		// MetricsToken guards /metrics with a bearer token. Without it
+		// metrics are only served on the worker health listener.
+		MetricsToken string
 	}
 
 	// Database config
@@ -51,8 +59,80 @@ type Config struct {
 		EncryptionKey string
 	}
 
+	Security struct {
+		// SSRFAllowlist lists private ranges deliveries may still reach,
+		// for self-hosted setups whose receivers live on the local network.
+		SSRFAllowlist []netip.Prefix
+	}
+
 	Worker struct {
-		Concurrency int
+		Concurrency      int
+		MinConcurrency   int
+		MaxConcurrency   int
+		ScaleInterval    time.Duration
+		TargetLatency    time.Duration
+		BacklogPerWorker int
+		PipeConcurrency  int
+		FileSinkDir      string
+		Queues           []string
+		HealthPort       int
+		DrainTimeout     time.Duration
+		// PausedBufferMax caps the events a paused pipe buffers; once
+		// reached, ingest turns its events away until it is resumed.
+		PausedBufferMax int
+	}
+
+	Events struct {
+		BatchSize     int
+		BatchInterval time.Duration
+		// BatchRetries is how often a failed batch insert is retried
+		// before the batch is spilled.
+		BatchRetries int
+		// BatchTimeout bounds each insert attempt.
+		BatchTimeout time.Duration
+		// MaxBuffered caps the events held in memory while the database
+		// is behind; past it the buffer is spilled.
+		MaxBuffered int
+		// Spill is where batches go while the database is down:
+		// "redis" or "file" (SpillFile, an absolute path).
+		Spill     string
+		SpillFile string
+	}
+
+	// Filter bounds every jq run in the worker and the expressions ingest
+	// evaluates on the request path.
+	Filter struct {
+		Timeout        time.Duration
+		MaxOutputBytes int
+		MaxOutputs     int
+	}
+
+	// Retention purges events older than their owner's retention period.
+	Retention struct {
+		// Interval between purge runs, 0 (the default) disables purging
+		// so nothing is deleted until an operator opts in. Partitions are
+		// created on their own schedule either way.
+		Interval time.Duration
+		// DefaultDays applies to users with no retention of their own and
+		// no plan, or a plan without a retention_plans row.
+		DefaultDays int
+		// a run deletes at most MaxBatches batches of BatchSize events,
+		// pausing BatchPause between them
+		BatchSize  int
+		BatchPause time.Duration
+		MaxBatches int
+		// Mode is "delete" or "archive"; archive writes expired events to
+		// ArchiveDir before deleting them.
+		Mode       string
+		ArchiveDir string
+	}
+
+	// Redaction applies to payloads stored as events or streamed to the
+	// realtime socket.
+	Redaction struct {
+		// HashKey keys the hash action; when empty a key is derived
+		// from ENCRYPTION_KEY.
+		HashKey string
 	}
 }
 
@@ -63,6 +143,7 @@ func Load() (*Config, error) {
 	cfg.Server.Env = utils.GetEnv("GO_ENV", "development")
 	cfg.Server.Hostname = utils.GetEnv("SERVER_HOST", "localhost")
 	cfg.Server.Port = utils.GetEnvInt("SERVER_PORT", 8080)
+	cfg.Server.MetricsToken = utils.GetEnv("METRICS_TOKEN", "")
 
 	// database configuration
 	cfg.Db.DSN = utils.GetEnv("DB_DSN", "")
@@ -89,7 +170,46 @@ func Load() (*Config, error) {
 
 	cfg.Aes.EncryptionKey = utils.GetEnv("ENCRYPTION_KEY", "")
 
+	allowlist, err := parsePrefixes(utils.GetEnvSlice("SSRF_ALLOWLIST", nil))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Security.SSRFAllowlist = allowlist
+
 	cfg.Worker.Concurrency = utils.GetEnvInt("CONCURRENCY_WORKERS", 4)
+	cfg.Worker.MinConcurrency = utils.GetEnvInt("WORKER_MIN_CONCURRENCY", cfg.Worker.Concurrency)
+	cfg.Worker.MaxConcurrency = utils.GetEnvInt("WORKER_MAX_CONCURRENCY", cfg.Worker.Concurrency)
+	cfg.Worker.ScaleInterval = utils.GetEnvDuration("WORKER_SCALE_INTERVAL", 5*time.Second)
+	cfg.Worker.TargetLatency = utils.GetEnvDuration("WORKER_TARGET_LATENCY", 2*time.Second)
+	cfg.Worker.BacklogPerWorker = utils.GetEnvInt("WORKER_BACKLOG_PER_WORKER", 10)
+	cfg.Worker.PipeConcurrency = utils.GetEnvInt("WORKER_PIPE_CONCURRENCY", 0)
+	cfg.Worker.FileSinkDir = utils.GetEnv("DESTINATION_FILE_DIR", "")
+	cfg.Worker.Queues = utils.GetEnvSlice("WORKER_QUEUES", []string{"webhook_queue"})
+	cfg.Worker.HealthPort = utils.GetEnvInt("WORKER_HEALTH_PORT", 8081)
+	cfg.Worker.DrainTimeout = utils.GetEnvDuration("WORKER_DRAIN_TIMEOUT", 30*time.Second)
+	cfg.Worker.PausedBufferMax = utils.GetEnvInt("PAUSED_BUFFER_MAX", 10000)
+
+	cfg.Events.BatchSize = utils.GetEnvInt("EVENT_BATCH_SIZE", 20)
+	cfg.Events.BatchInterval = utils.GetEnvDuration("EVENT_BATCH_INTERVAL", 2*time.Second)
+	cfg.Events.BatchRetries = utils.GetEnvInt("EVENT_BATCH_RETRIES", 3)
+	cfg.Events.BatchTimeout = utils.GetEnvDuration("EVENT_BATCH_TIMEOUT", 10*time.Second)
+	cfg.Events.MaxBuffered = utils.GetEnvInt("EVENT_MAX_BUFFERED", 10000)
+	cfg.Events.Spill = utils.GetEnv("EVENT_SPILL", "redis")
+	cfg.Events.SpillFile = utils.GetEnv("EVENT_SPILL_FILE", "")
+
+	cfg.Filter.Timeout = utils.GetEnvDuration("JQ_TIMEOUT", time.Second)
+	cfg.Filter.MaxOutputBytes = utils.GetEnvInt("JQ_MAX_OUTPUT_BYTES", 1<<20)
+	cfg.Filter.MaxOutputs = utils.GetEnvInt("JQ_MAX_OUTPUTS", 100)
+
+	cfg.Retention.Interval = utils.GetEnvDuration("RETENTION_INTERVAL", 0)
+	cfg.Retention.DefaultDays = utils.GetEnvInt("RETENTION_DEFAULT_DAYS", 30)
+	cfg.Retention.BatchSize = utils.GetEnvInt("RETENTION_BATCH_SIZE", 1000)
+	cfg.Retention.BatchPause = utils.GetEnvDuration("RETENTION_BATCH_PAUSE", 100*time.Millisecond)
+	cfg.Retention.MaxBatches = utils.GetEnvInt("RETENTION_MAX_BATCHES", 100)
+	cfg.Retention.Mode = utils.GetEnv("RETENTION_MODE", "delete")
+	cfg.Retention.ArchiveDir = utils.GetEnv("RETENTION_ARCHIVE_DIR", "")
+
+	cfg.Redaction.HashKey = utils.GetEnv("REDACTION_HASH_KEY", "")
 
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -141,5 +261,73 @@ func (c *Config) Validate() error {
 		return errors.New("ENCRYPTION_KEY is required")
 	}
 
+	if c.Worker.Concurrency < 1 {
+		return errors.New("CONCURRENCY_WORKERS must be at least 1.")
+	}
+
+	if c.Worker.MinConcurrency < 1 || c.Worker.MaxConcurrency < c.Worker.MinConcurrency {
+		return errors.New("WORKER_MIN_CONCURRENCY must be at least 1 and not above WORKER_MAX_CONCURRENCY.")
+	}
+
+	if c.Worker.PausedBufferMax < 1 {
+		return errors.New("PAUSED_BUFFER_MAX must be at least 1.")
+	}
+
+	if c.Events.BatchSize < 1 || c.Events.BatchInterval <= 0 || c.Events.BatchRetries < 0 {
+		return errors.New("EVENT_BATCH_SIZE and EVENT_BATCH_INTERVAL must be positive, EVENT_BATCH_RETRIES not negative.")
+	}
+
+	if c.Events.BatchTimeout <= 0 || c.Events.MaxBuffered < c.Events.BatchSize {
+		return errors.New("EVENT_BATCH_TIMEOUT must be positive and EVENT_MAX_BUFFERED at least EVENT_BATCH_SIZE.")
+	}
+
+	if c.Events.Spill != "redis" && c.Events.Spill != "file" {
+		return errors.New("EVENT_SPILL must be redis or file.")
+	}
+
+	// a relative spill file would move with the working directory and
+	// strand the events spilled before a restart
+	if c.Events.Spill == "file" && !filepath.IsAbs(c.Events.SpillFile) {
+		return errors.New("EVENT_SPILL_FILE must be an absolute path when EVENT_SPILL is file.")
+	}
+
+	if c.Filter.Timeout <= 0 || c.Filter.MaxOutputBytes < 1 || c.Filter.MaxOutputs < 1 {
+		return errors.New("JQ_TIMEOUT, JQ_MAX_OUTPUT_BYTES and JQ_MAX_OUTPUTS must be positive.")
+	}
+
+	if c.Retention.Interval < 0 || c.Retention.DefaultDays < 1 || c.Retention.BatchSize < 1 ||
+		c.Retention.BatchPause < 0 || c.Retention.MaxBatches < 1 {
+		return errors.New("RETENTION_DEFAULT_DAYS, RETENTION_BATCH_SIZE and RETENTION_MAX_BATCHES must be positive, RETENTION_INTERVAL and RETENTION_BATCH_PAUSE not negative.")
+	}
+
+	if c.Retention.Mode != "delete" && c.Retention.Mode != "archive" {
+		return errors.New("RETENTION_MODE must be delete or archive.")
+	}
+
+	if c.Retention.Mode == "archive" && c.Retention.ArchiveDir == "" {
+		return errors.New("RETENTION_ARCHIVE_DIR is required when RETENTION_MODE is archive.")
+	}
+
 	return nil
 }
+
+// parsePrefixes accepts CIDRs or bare IPs, the latter as single-host prefixes.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("SSRF_ALLOWLIST: invalid address %q", v)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("SSRF_ALLOWLIST: invalid range %q", v)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
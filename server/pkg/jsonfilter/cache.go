@@ -0,0 +1,51 @@
+package jsonfilter
+
+import (
+	"crypto/sha256"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/lru"
+)
+
+// DEFAULT_CACHE_SIZE bounds the number of compiled programs kept by the
+// package level cache.
+const DEFAULT_CACHE_SIZE = 1024
+
+// Cache is an LRU of compiled programs keyed by a hash of the filter.
+// It is safe for concurrent use.
+type Cache struct {
+	opts  []Option
+	progs *lru.Cache[[sha256.Size]byte, *Program]
+}
+
+// NewCache returns a cache holding up to size programs, compiled with
+// opts.
+func NewCache(size int, opts ...Option) *Cache {
+	return &Cache{
+		opts:  opts,
+		progs: lru.New[[sha256.Size]byte, *Program](size),
+	}
+}
+
+var defaultCache = NewCache(DEFAULT_CACHE_SIZE)
+
+// Compile returns the compiled program for filterStr, compiling it on a
+// cache miss. Parse errors are not cached.
+func (c *Cache) Compile(filterStr string) (*Program, error) {
+	key := sha256.Sum256([]byte(filterStr))
+	if prog, ok := c.progs.Get(key); ok {
+		return prog, nil
+	}
+
+	// compile outside the lock; a concurrent miss on the same filter
+	// just compiles twice
+	prog, err := compile(filterStr, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.progs.Add(key, prog), nil
+}
+
+// Len reports how many programs are cached.
+func (c *Cache) Len() int {
+	return c.progs.Len()
+}
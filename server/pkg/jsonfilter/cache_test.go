@@ -0,0 +1,47 @@
+package jsonfilter
+
+import "testing"
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2)
+
+	a, err := c.Compile(".a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Compile(".b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// touch .a so .b is the least recently used
+	if again, _ := c.Compile(".a"); again != a {
+		t.Error("expected the cached program for .a")
+	}
+	if _, err := c.Compile(".c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+	if again, _ := c.Compile(".a"); again != a {
+		t.Error(".a was evicted, want .b evicted")
+	}
+
+	if _, err := c.Compile(".["); err == nil {
+		t.Error("expected a syntax error")
+	}
+	if c.Len() != 2 {
+		t.Errorf("failed compiles must not be cached, Len() = %d", c.Len())
+	}
+}
+
+func TestValidateDoesNotCache(t *testing.T) {
+	before := defaultCache.Len()
+	if err := Validate(".validate_does_not_cache"); err != nil {
+		t.Fatal(err)
+	}
+	if got := defaultCache.Len(); got != before {
+		t.Errorf("Validate() cached the program, Len() = %d, want %d", got, before)
+	}
+}
@@ -0,0 +1,130 @@
+package jsonfilter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchyny/gojq"
+)
+
+var ErrInvalidFilter = errors.New("invalid jq filter")
+
+// SyntaxError is a filter that does not compile. Line and Column are
+// 1-based and point at Token; both are 0 when the position is unknown.
+type SyntaxError struct {
+	Message string
+	Line    int
+	Column  int
+	Token   string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return "invalid jq syntax: " + e.Message
+	}
+	return fmt.Sprintf("invalid jq syntax at line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrInvalidFilter
+}
+
+// compile errors carrying a name, e.g. "function not defined: foo/1"
+var namedErrors = []string{
+	"function not defined: ",
+	"variable not defined: ",
+	"format not defined: ",
+}
+
+func newSyntaxError(filterStr string, err error) *SyntaxError {
+	e := &SyntaxError{Message: err.Error()}
+
+	var perr *gojq.ParseError
+	if errors.As(err, &perr) {
+		// Offset is just past the offending token
+		e.Token = perr.Token
+		e.Line, e.Column = position(filterStr, max(perr.Offset-len(perr.Token), 0))
+		return e
+	}
+
+	for _, prefix := range namedErrors {
+		name, ok := strings.CutPrefix(e.Message, prefix)
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "/")
+		if i := locate(filterStr, name); i >= 0 {
+			e.Token = name
+			e.Line, e.Column = position(filterStr, i)
+		}
+	}
+	return e
+}
+
+// position converts a byte offset into a 1-based line and rune column.
+func position(s string, offset int) (line, col int) {
+	offset = min(offset, len(s))
+	before := s[:offset]
+	line = strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// locate finds name in s as a whole identifier.
+func locate(s, name string) int {
+	for from := 0; ; {
+		i := strings.Index(s[from:], name)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(name)
+		if (i == 0 || !isIdent(s[i-1])) && (end == len(s) || !isIdent(s[end])) {
+			return i
+		}
+		from = i + 1
+	}
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '$' || c == '@' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// Diagnostic is a JSON friendly description of why a filter failed to
+// compile or run.
+type Diagnostic struct {
+	// Kind is syntax, runtime, limit or empty.
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Limit   string `json:"limit,omitempty"`
+}
+
+// Diagnose describes an error returned by Compile or a program run.
+func Diagnose(err error) Diagnostic {
+	var (
+		syntaxErr *SyntaxError
+		limitErr  *LimitError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return Diagnostic{
+			Kind:    "syntax",
+			Message: syntaxErr.Message,
+			Line:    syntaxErr.Line,
+			Column:  syntaxErr.Column,
+			Token:   syntaxErr.Token,
+		}
+	case errors.As(err, &limitErr):
+		return Diagnostic{Kind: "limit", Message: limitErr.Error(), Limit: limitErr.Limit}
+	case errors.Is(err, ErrEmptyOutput):
+		return Diagnostic{Kind: "empty", Message: err.Error()}
+	default:
+		return Diagnostic{Kind: "runtime", Message: err.Error()}
+	}
+}
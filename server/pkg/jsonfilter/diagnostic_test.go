@@ -0,0 +1,36 @@
+package jsonfilter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		line   int
+		column int
+		token  string
+	}{
+		{name: "unexpected token", filter: ".a | | .b", line: 1, column: 6, token: "|"},
+		{name: "second line", filter: "{\n  a: .a,,\n}", line: 2, column: 9, token: ","},
+		{name: "unexpected EOF", filter: ".a | {", line: 1, column: 7},
+		{name: "unknown function", filter: ".a | shaa256", line: 1, column: 6, token: "shaa256"},
+		{name: "unknown variable", filter: "{id: $pipe.id, x: $nope}", line: 1, column: 19, token: "$nope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.filter)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("compile(%q) error = %v, want a SyntaxError", tt.filter, err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column || syntaxErr.Token != tt.token {
+				t.Errorf("got line %d column %d token %q, want line %d column %d token %q",
+					syntaxErr.Line, syntaxErr.Column, syntaxErr.Token, tt.line, tt.column, tt.token)
+			}
+		})
+	}
+}
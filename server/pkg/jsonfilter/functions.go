@@ -0,0 +1,157 @@
+package jsonfilter
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itchyny/gojq"
+)
+
+// isoLayouts are tried in order by parse_time. The ones without an
+// offset are read in the zone passed to parse_time, UTC by default.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// functions is the library of helpers registered on every program:
+//
+//	sha1, sha256, sha512            hex digest of the input string
+//	hmac_sha1(k), hmac_sha256(k),
+//	hmac_sha512(k)                  hex HMAC of the input string keyed by k
+//	base64url_encode,
+//	base64url_decode                unpadded URL-safe base64
+//	uuid                            a random (v4) UUID
+//	now_rfc3339                     current UTC time as RFC 3339
+//	parse_time, parse_time(tz)      ISO 8601 string to unix seconds; tz is
+//	                                the IANA zone for strings without offset
+func functions(o options) []gojq.CompilerOption {
+	return []gojq.CompilerOption{
+		gojq.WithFunction("sha1", 0, 0, digest("sha1", sha1.New)),
+		gojq.WithFunction("sha256", 0, 0, digest("sha256", sha256.New)),
+		gojq.WithFunction("sha512", 0, 0, digest("sha512", sha512.New)),
+		gojq.WithFunction("hmac_sha1", 1, 1, keyedDigest("hmac_sha1", sha1.New)),
+		gojq.WithFunction("hmac_sha256", 1, 1, keyedDigest("hmac_sha256", sha256.New)),
+		gojq.WithFunction("hmac_sha512", 1, 1, keyedDigest("hmac_sha512", sha512.New)),
+		gojq.WithFunction("base64url_encode", 0, 0, base64urlEncode),
+		gojq.WithFunction("base64url_decode", 0, 0, base64urlDecode),
+		gojq.WithFunction("uuid", 0, 0, func(any, []any) any {
+			return uuid.NewString()
+		}),
+		gojq.WithFunction("now_rfc3339", 0, 0, func(any, []any) any {
+			return o.now().UTC().Format(time.RFC3339)
+		}),
+		gojq.WithFunction("parse_time", 0, 1, parseTime),
+	}
+}
+
+func digest(name string, h func() hash.Hash) func(any, []any) any {
+	return func(v any, _ []any) any {
+		s, ok := v.(string)
+		if !ok {
+			return typeError(name, v)
+		}
+		sum := h()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+}
+
+func keyedDigest(name string, h func() hash.Hash) func(any, []any) any {
+	return func(v any, args []any) any {
+		s, ok := v.(string)
+		if !ok {
+			return typeError(name, v)
+		}
+		key, ok := args[0].(string)
+		if !ok {
+			return fmt.Errorf("%s: key must be a string, got %s", name, typeName(args[0]))
+		}
+		mac := hmac.New(h, []byte(key))
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+}
+
+func base64urlEncode(v any, _ []any) any {
+	s, ok := v.(string)
+	if !ok {
+		return typeError("base64url_encode", v)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func base64urlDecode(v any, _ []any) any {
+	s, ok := v.(string)
+	if !ok {
+		return typeError("base64url_decode", v)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("base64url_decode: %w", err)
+	}
+	return string(b)
+}
+
+func parseTime(v any, args []any) any {
+	s, ok := v.(string)
+	if !ok {
+		return typeError("parse_time", v)
+	}
+	loc := time.UTC
+	if len(args) == 1 {
+		name, ok := args[0].(string)
+		if !ok {
+			return fmt.Errorf("parse_time: zone must be a string, got %s", typeName(args[0]))
+		}
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("parse_time: unknown zone %q", name)
+		}
+		loc = l
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return float64(t.UnixNano()) / float64(time.Second)
+		}
+	}
+	return fmt.Errorf("parse_time: %q is not an ISO 8601 time", s)
+}
+
+func typeError(name string, v any) error {
+	return fmt.Errorf("%s: expected a string, got %s", name, typeName(v))
+}
+
+// typeName names v the way jq's type builtin does.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, float64, *big.Int:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,85 @@
+package jsonfilter
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFunctions(t *testing.T) {
+	clock := func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)) }
+	cache := NewCache(64, WithClock(clock))
+
+	vars := Vars{
+		Pipe:       PipeMeta{ID: "p-1", Name: "Orders", Slug: "orders"},
+		EventID:    "e-1",
+		ReceivedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Headers:    map[string]string{"x-github-event": "push"},
+	}
+
+	tests := []struct {
+		name      string
+		filter    string
+		input     any
+		vars      Vars
+		want      any
+		expectErr bool
+	}{
+		{name: "sha256", filter: "sha256", input: "abc",
+			want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{name: "hmac_sha256", filter: `hmac_sha256("key")`, input: "The quick brown fox jumps over the lazy dog",
+			want: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{name: "base64url round trip", filter: "base64url_encode", input: "??>>", want: "Pz8-Pg"},
+		{name: "base64url decode padded", filter: "base64url_decode", input: "Pz8-Pg==", want: "??>>"},
+		{name: "uuid", filter: "uuid | length", input: nil, want: 36},
+		{name: "now_rfc3339", filter: "now_rfc3339", input: nil, want: "2026-01-02T02:04:05Z"},
+		{name: "parse_time offset", filter: "parse_time", input: "2026-01-02T03:04:05+01:00", want: float64(1767319445)},
+		{name: "parse_time zone", filter: `parse_time("Europe/Berlin")`, input: "2026-01-02 03:04:05", want: float64(1767319445)},
+		{name: "parse_time date", filter: "parse_time", input: "2026-01-02", want: float64(1767312000)},
+		{name: "parse_time invalid", filter: "parse_time", input: "yesterday", expectErr: true},
+		{name: "type error", filter: "sha1", input: float64(1), expectErr: true},
+		{name: "variables", filter: `[$pipe.slug, $event_id, $received_at, $headers["x-github-event"]]`, input: nil, vars: vars,
+			want: []any{"orders", "e-1", "2026-01-02T03:04:05Z", "push"}},
+		{name: "unset variables are null", filter: "$pipe", input: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := cache.Compile(tt.filter)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.filter, err)
+			}
+			got, err := prog.Run(context.Background(), tt.input, tt.vars, DefaultLimits)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Run() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{v: nil, want: "null"},
+		{v: true, want: "boolean"},
+		{v: 1, want: "number"},
+		{v: 1.5, want: "number"},
+		{v: new(big.Int).Lsh(big.NewInt(1), 100), want: "number"},
+		{v: "s", want: "string"},
+		{v: []any{}, want: "array"},
+		{v: map[string]any{}, want: "object"},
+	}
+
+	for _, tt := range tests {
+		if got := typeName(tt.v); got != tt.want {
+			t.Errorf("typeName(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package jsonfilter
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/itchyny/gojq"
+)
+
+// Caps on what a single step of a program may build. Limits only look
+// at emitted values, these stop a filter like `"x" * 2e9` or
+// `[range(1e9)]` before it allocates.
+const (
+	MAX_STRING_REPEAT_BYTES = 1 << 24
+	MAX_RANGE               = 1 << 20
+	MAX_REPEAT              = 1 << 16
+)
+
+// guardPrelude shadows the builtins that can grow without bound with
+// checked versions. gojq resolves definitions in the query before its
+// builtins, and a filter's own defs still shadow these. _range and
+// _multiply are redefined too so they can't be called directly to get
+// around the checks; inside the defs `*` and range/3 still reach the
+// unchecked originals.
+const guardPrelude = `
+def range($end): _guard_range(0; $end; 1) | _range(0; $end; 1);
+def range($start; $end): _guard_range($start; $end; 1) | _range($start; $end; 1);
+def range($start; $end; $step): _guard_range($start; $end; $step) | _range($start; $end; $step);
+def _range($start; $end; $step): range($start; $end; $step);
+def repeat(f): foreach (def _repeat: f, _repeat; _repeat) as $x (0; . + 1; _guard_repeat | $x);
+def _multiply(l; r): r as $r | l as $l | _guard_multiply($l; $r) | $l * $r;
+.`
+
+// guardDefs is parsed once; gojq doesn't modify a query it compiles, so
+// every program can share it.
+var guardDefs = func() []*gojq.FuncDef {
+	q, err := gojq.Parse(guardPrelude)
+	if err != nil {
+		panic("jsonfilter: invalid guard prelude: " + err.Error())
+	}
+	return q.FuncDefs
+}()
+
+func guardFunctions() []gojq.CompilerOption {
+	return []gojq.CompilerOption{
+		gojq.WithFunction("_guard_range", 3, 3, guardRange),
+		gojq.WithFunction("_guard_repeat", 0, 0, guardRepeat),
+		gojq.WithFunction("_guard_multiply", 2, 2, guardMultiply),
+	}
+}
+
+// guard rewrites every `l * r` in q into _multiply(l; r), which gojq
+// would otherwise compile straight to its unchecked operator, and puts
+// the checked definitions in scope.
+func guard(q *gojq.Query) {
+	rewriteMultiply(reflect.ValueOf(q))
+	q.FuncDefs = append(append([]*gojq.FuncDef{}, guardDefs...), q.FuncDefs...)
+}
+
+var queryType = reflect.TypeOf(gojq.Query{})
+
+// rewriteMultiply walks the AST below v, children first.
+func rewriteMultiply(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		rewriteMultiply(v.Elem())
+		if v.Elem().Type() == queryType {
+			if q := v.Interface().(*gojq.Query); q.Op == gojq.OpMul {
+				*q = gojq.Query{
+					Meta:     q.Meta,
+					Imports:  q.Imports,
+					FuncDefs: q.FuncDefs,
+					Term: &gojq.Term{Type: gojq.TermTypeFunc, Func: &gojq.Func{
+						Name: "_multiply",
+						Args: []*gojq.Query{q.Left, q.Right},
+					}},
+				}
+			}
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			rewriteMultiply(v.Field(i))
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			rewriteMultiply(v.Index(i))
+		}
+	}
+}
+
+func guardRange(v any, args []any) any {
+	start, ok1 := toFloat(args[0])
+	end, ok2 := toFloat(args[1])
+	step, ok3 := toFloat(args[2])
+	if !ok1 || !ok2 || !ok3 {
+		// not numbers, _range reports that itself
+		return v
+	}
+	if step == 0 || (end-start)/step <= 0 {
+		// _range yields nothing for these
+		return v
+	}
+	if (end-start)/step > MAX_RANGE {
+		return &LimitError{Limit: LIMIT_RANGE, Max: strconv.Itoa(MAX_RANGE) + " values"}
+	}
+	return v
+}
+
+// guardRepeat gets the number of values repeat has emitted so far.
+func guardRepeat(v any, _ []any) any {
+	if n, _ := toFloat(v); n > MAX_REPEAT {
+		return &LimitError{Limit: LIMIT_REPEAT, Max: strconv.Itoa(MAX_REPEAT) + " values"}
+	}
+	return v
+}
+
+func guardMultiply(v any, args []any) any {
+	s, ok := args[0].(string)
+	n, isNum := toFloat(args[1])
+	if !ok {
+		s, ok = args[1].(string)
+		n, isNum = toFloat(args[0])
+	}
+	if ok && isNum && n*float64(len(s)) > MAX_STRING_REPEAT_BYTES {
+		return &LimitError{Limit: LIMIT_STRING_BYTES, Max: strconv.Itoa(MAX_STRING_REPEAT_BYTES) + " bytes"}
+	}
+	return v
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, !math.IsNaN(n)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, true
+	default:
+		return 0, false
+	}
+}
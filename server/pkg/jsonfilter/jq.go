@@ -1,8 +1,11 @@
 package jsonfilter
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/itchyny/gojq"
 )
@@ -11,36 +14,155 @@ var (
 	ErrEmptyOutput = errors.New("filter produced no output")
 )
 
-// Transform executes a jq filter string against a Go object (map/slice)
-// It returns the first result found
-func Transform(input any, filterStr string) (any, error) {
+// Program is a compiled jq filter. It is immutable and safe to run
+// from many goroutines at once.
+type Program struct {
+	code *gojq.Code
+}
+
+// Compile returns the compiled program for filterStr from the package
+// level cache, so a pipe's filter is only parsed once per process.
+func Compile(filterStr string) (*Program, error) {
+	return defaultCache.Compile(filterStr)
+}
+
+// Option configures how a Cache compiles its programs.
+type Option func(*options)
+
+type options struct {
+	now func() time.Time
+}
+
+// WithClock makes now_rfc3339 read the time from now rather than the
+// system clock.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) { o.now = now }
+}
+
+func compile(filterStr string, opts ...Option) (*Program, error) {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	// fast path: If filter is empty or just a dot return input as-is
 	if filterStr == "" || filterStr == "." {
-		return input, nil
+		// a nil program passes input through untouched
+		return &Program{}, nil
 	}
 
 	//parse the jq query
 	query, err := gojq.Parse(filterStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid jq syntax: %w", err)
+		return nil, newSyntaxError(filterStr, err)
 	}
+	guard(query)
+	compilerOpts := append(functions(o), guardFunctions()...)
+	code, err := gojq.Compile(query, append(compilerOpts, gojq.WithVariables(variables))...)
+	if err != nil {
+		return nil, newSyntaxError(filterStr, err)
+	}
+	return &Program{code: code}, nil
+}
 
-	// gojq works on standard map[string]any types, which matches
-	// what encoding/json unmarshals into.
-	iter := query.Run(input)
-
+// Run executes the program against a Go object (map/slice) with vars
+// bound to its variables (see Vars), and returns the first
+// result found. The run is bounded by ctx and limits; exceeding a limit
+// returns a *LimitError.
+func (p *Program) Run(ctx context.Context, input any, vars Vars, limits Limits) (any, error) {
 	// we only take the first emitted value
 	// (Webhooks are typically 1 request -> 1 transformed request)
-	v, ok := iter.Next()
-	if !ok {
+	out, err := p.run(ctx, input, vars, limits, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
 		return nil, ErrEmptyOutput
 	}
+	return out[0], nil
+}
+
+// RunAll is Run collecting every emitted value, at most
+// limits.MaxOutputs of them.
+func (p *Program) RunAll(ctx context.Context, input any, vars Vars, limits Limits) ([]any, error) {
+	return p.run(ctx, input, vars, limits, false)
+}
+
+func (p *Program) run(ctx context.Context, input any, vars Vars, limits Limits, first bool) ([]any, error) {
+	// fast path: If filter is empty or just a dot return input as-is
+	if p.code == nil {
+		return []any{input}, nil
+	}
 
-	// check for execution errors.
-	if err, ok := v.(error); ok {
-		return nil, fmt.Errorf("jq execution error: %w", err)
+	runCtx := ctx
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
 	}
 
-	return v, nil
+	// gojq works on standard map[string]any types, which matches
+	// what encoding/json unmarshals into.
+	iter := p.code.RunWithContext(runCtx, input, vars.values()...)
+
+	var out []any
+	size := 0
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return out, nil
+		}
+
+		// check for execution errors.
+		if err, ok := v.(error); ok {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("jq execution interrupted: %w", ctx.Err())
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, &LimitError{Limit: LIMIT_TIMEOUT, Max: limits.Timeout.String()}
+			}
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				return nil, limitErr
+			}
+			return nil, fmt.Errorf("jq execution error: %w", err)
+		}
+
+		if limits.MaxOutputs > 0 && len(out) == limits.MaxOutputs {
+			return nil, &LimitError{Limit: LIMIT_OUTPUTS, Max: strconv.Itoa(limits.MaxOutputs)}
+		}
+		if limits.MaxOutputBytes > 0 {
+			n, err := encodedSize(v, limits.MaxOutputBytes-size)
+			if errors.Is(err, errTooLarge) {
+				return nil, &LimitError{Limit: LIMIT_OUTPUT_BYTES, Max: strconv.Itoa(limits.MaxOutputBytes) + " bytes"}
+			}
+			if err != nil {
+				return nil, err
+			}
+			size += n
+		}
+
+		out = append(out, v)
+		if first {
+			return out, nil
+		}
+	}
+}
+
+// Transform executes a jq filter string against a Go object (map/slice)
+// It returns the first result found; the variables are all null and the
+// run is bounded by DefaultLimits.
+func Transform(input any, filterStr string) (any, error) {
+	prog, err := Compile(filterStr)
+	if err != nil {
+		return nil, err
+	}
+	return prog.Run(context.Background(), input, Vars{}, DefaultLimits)
+}
+
+// Validate reports whether filterStr is valid jq syntax, failing with a
+// *SyntaxError. The program is not cached, so validating input nobody
+// runs can't evict the filters the worker does.
+func Validate(filterStr string) error {
+	_, err := compile(filterStr)
+	return err
 }
@@ -0,0 +1,87 @@
+package jsonfilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/itchyny/gojq"
+)
+
+const benchFilter = `{action, id: .issue.id, title: .issue.title, labels: [.issue.labels[].name], author: .issue.user.login}`
+
+func benchInput(b *testing.B) any {
+	b.Helper()
+	raw := `{"action":"opened","issue":{"id":123,"title":"Fix the bug","user":{"login":"octocat"},
+		"labels":[{"name":"bug"},{"name":"p1"},{"name":"backend"}]}}`
+	var input any
+	if err := json.Unmarshal([]byte(raw), &input); err != nil {
+		b.Fatal(err)
+	}
+	return input
+}
+
+// BenchmarkTransformParseEachTime is the old behaviour: parse the filter
+// for every event.
+func BenchmarkTransformParseEachTime(b *testing.B) {
+	input := benchInput(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		query, err := gojq.Parse(benchFilter)
+		if err != nil {
+			b.Fatal(err)
+		}
+		iter := query.Run(input)
+		if _, ok := iter.Next(); !ok {
+			b.Fatal("no output")
+		}
+	}
+}
+
+// BenchmarkTransformCached goes through the compiled program cache.
+func BenchmarkTransformCached(b *testing.B) {
+	input := benchInput(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Transform(input, benchFilter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTransformCachedParallel models many workers sharing the cache.
+func BenchmarkTransformCachedParallel(b *testing.B) {
+	input := benchInput(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := Transform(input, benchFilter); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkCacheChurn measures a working set larger than the cache.
+func BenchmarkCacheChurn(b *testing.B) {
+	input := benchInput(b)
+	cache := NewCache(64)
+	filters := make([]string, 128)
+	for i := range filters {
+		filters[i] = fmt.Sprintf(".issue.id + %d", i)
+	}
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		prog, err := cache.Compile(filters[i%len(filters)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := prog.Run(context.Background(), input, Vars{}, DefaultLimits); err != nil {
+			b.Fatal(err)
+		}
+		i++
+	}
+}
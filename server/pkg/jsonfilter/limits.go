@@ -0,0 +1,82 @@
+package jsonfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits bound a single run of a program. Zero fields are unlimited.
+type Limits struct {
+	// Timeout aborts the run, gojq checks the context between steps.
+	Timeout time.Duration
+	// MaxOutputBytes caps the JSON encoded size of all emitted values.
+	MaxOutputBytes int
+	// MaxOutputs caps how many values RunAll collects.
+	MaxOutputs int
+}
+
+// DefaultLimits apply to Transform and to programs run without
+// configured limits.
+var DefaultLimits = Limits{
+	Timeout:        time.Second,
+	MaxOutputBytes: 1 << 20,
+	MaxOutputs:     100,
+}
+
+var ErrLimitExceeded = errors.New("filter exceeded its execution limits")
+
+// Limit names used in LimitError.
+const (
+	LIMIT_TIMEOUT      = "timeout"
+	LIMIT_OUTPUT_BYTES = "output_bytes"
+	LIMIT_OUTPUTS      = "outputs"
+	LIMIT_STRING_BYTES = "string_bytes"
+	LIMIT_RANGE        = "range"
+	LIMIT_REPEAT       = "repeat"
+)
+
+// LimitError reports which limit stopped a run. It matches
+// ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	Limit string
+	Max   string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("jq %s limit of %s exceeded", e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+var errTooLarge = errors.New("output too large")
+
+// capWriter counts bytes and fails once more than max were written, so
+// an oversized value is never fully encoded.
+type capWriter struct {
+	n, max int
+}
+
+func (w *capWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	if w.n > w.max {
+		return 0, errTooLarge
+	}
+	return len(p), nil
+}
+
+// encodedSize returns the JSON size of v, or errTooLarge past max.
+func encodedSize(v any, max int) (int, error) {
+	w := &capWriter{max: max}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		if errors.Is(err, errTooLarge) {
+			return w.n, errTooLarge
+		}
+		return w.n, fmt.Errorf("jq execution error: %w", err)
+	}
+	// the encoder appends a newline
+	return w.n - 1, nil
+}
@@ -0,0 +1,135 @@
+package jsonfilter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	limits := Limits{Timeout: 50 * time.Millisecond, MaxOutputBytes: 64, MaxOutputs: 3}
+
+	tests := []struct {
+		name      string
+		filter    string
+		all       bool
+		wantLimit string
+	}{
+		{name: "unbounded range", filter: "[range(1e9)]", wantLimit: LIMIT_RANGE},
+		{name: "infinite recursion", filter: "last(recurse(. + 1))", wantLimit: LIMIT_TIMEOUT},
+		{name: "large output", filter: `"x" * 100`, wantLimit: LIMIT_OUTPUT_BYTES},
+		{name: "outputs summed", filter: `range(3) | "x" * 30`, all: true, wantLimit: LIMIT_OUTPUT_BYTES},
+		{name: "too many outputs", filter: "range(10)", all: true, wantLimit: LIMIT_OUTPUTS},
+		{name: "first of many", filter: "range(10)"},
+		{name: "within limits", filter: "range(3)", all: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.filter, err)
+			}
+			if tt.all {
+				_, err = prog.RunAll(context.Background(), float64(0), Vars{}, limits)
+			} else {
+				_, err = prog.Run(context.Background(), float64(0), Vars{}, limits)
+			}
+
+			var limitErr *LimitError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("error = %v, want a LimitError", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestGuards(t *testing.T) {
+	// a generous timeout, the guards must trip long before it
+	limits := Limits{Timeout: 10 * time.Second, MaxOutputBytes: 1 << 20, MaxOutputs: 100}
+
+	tests := []struct {
+		name      string
+		filter    string
+		input     any
+		wantLimit string
+		want      any
+	}{
+		{name: "string repeat", filter: `"x" * 2000000000`, wantLimit: LIMIT_STRING_BYTES},
+		{name: "number times string", filter: `2000000000 * "x"`, wantLimit: LIMIT_STRING_BYTES},
+		{name: "repeat in an update", filter: `.a *= 2000000000`, input: map[string]any{"a": "x"}, wantLimit: LIMIT_STRING_BYTES},
+		{name: "repeat in a def", filter: `def big: . * 2000000000; "x" | big`, wantLimit: LIMIT_STRING_BYTES},
+		{name: "direct _multiply", filter: `_multiply("x"; 2000000000)`, wantLimit: LIMIT_STRING_BYTES},
+		{name: "collected range", filter: `[range(1e9)] | length`, wantLimit: LIMIT_RANGE},
+		{name: "range with step", filter: `[range(0; 1e12; 1000)] | length`, wantLimit: LIMIT_RANGE},
+		{name: "direct _range", filter: `[_range(0; 1e9; 1)] | length`, wantLimit: LIMIT_RANGE},
+		{name: "collected repeat", filter: `[repeat(1)] | length`, wantLimit: LIMIT_REPEAT},
+		{name: "small repeat", filter: `"ab" * 3`, want: "ababab"},
+		{name: "numbers", filter: `6 * 7`, want: 42},
+		{name: "object merge", filter: `{"a": {"b": 1}} * {"a": {"c": 2}}`, want: map[string]any{"a": map[string]any{"b": 1, "c": 2}}},
+		{name: "operand order", filter: `[("a", "b") * (1, 2)]`, want: []any{"a", "b", "aa", "bb"}},
+		{name: "small range", filter: `[range(3)]`, want: []any{0, 1, 2}},
+		{name: "empty range", filter: `[range(5; 0)]`, want: []any{}},
+		{name: "limited repeat", filter: `[limit(3; repeat(1))]`, want: []any{1, 1, 1}},
+		{name: "own range", filter: `def range($n): $n; range(7)`, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.filter, err)
+			}
+			start := time.Now()
+			got, err := prog.Run(context.Background(), tt.input, Vars{}, limits)
+			if elapsed := time.Since(start); elapsed > limits.Timeout/2 {
+				t.Errorf("Run() took %v, want the guard to stop it quickly", elapsed)
+			}
+
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Run() = %#v, want %#v", got, tt.want)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("error = %v, want a LimitError", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestRunCancelledIsNotALimit(t *testing.T) {
+	prog, err := Compile("[range(1e9)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = prog.Run(ctx, nil, Vars{}, DefaultLimits)
+	if err == nil || errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("error = %v, want cancellation", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,49 @@
+package jsonfilter
+
+import "time"
+
+// variables are declared on every program, in the order values()
+// passes them.
+var variables = []string{"$pipe", "$event_id", "$received_at", "$headers"}
+
+// Vars are the values bound to $pipe, $event_id, $received_at and
+// $headers. Unset fields are null in the filter.
+type Vars struct {
+	Pipe       PipeMeta
+	EventID    string
+	ReceivedAt time.Time
+	// Headers of the webhook request, keyed by lower-case name.
+	Headers map[string]string
+}
+
+// PipeMeta is what a filter sees as $pipe.
+type PipeMeta struct {
+	ID   string
+	Name string
+	Slug string
+}
+
+func (v Vars) values() []any {
+	var pipe, eventID, receivedAt, headers any
+	if v.Pipe != (PipeMeta{}) {
+		pipe = map[string]any{
+			"id":   v.Pipe.ID,
+			"name": v.Pipe.Name,
+			"slug": v.Pipe.Slug,
+		}
+	}
+	if v.EventID != "" {
+		eventID = v.EventID
+	}
+	if !v.ReceivedAt.IsZero() {
+		receivedAt = v.ReceivedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if v.Headers != nil {
+		h := make(map[string]any, len(v.Headers))
+		for k, val := range v.Headers {
+			h[k] = val
+		}
+		headers = h
+	}
+	return []any{pipe, eventID, receivedAt, headers}
+}
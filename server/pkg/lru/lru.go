@@ -0,0 +1,68 @@
+// Package lru provides a size bounded least recently used cache.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache keeps up to size values, evicting the least recently used one
+// when full. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// New returns a cache holding up to size values.
+func New[K comparable, V any](size int) *Cache[K, V] {
+	return &Cache[K, V]{
+		size:  max(size, 1),
+		order: list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value cached under key, marking it recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*entry[K, V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Add caches val under key and returns the cached value. When key is
+// already cached, as after a concurrent miss, the value already there is
+// kept and returned.
+func (c *Cache[K, V]) Add(key K, val V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*entry[K, V]).val
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, val: val})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+	return val
+}
+
+// Len reports how many values are cached.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,36 @@
+package lru
+
+import "testing"
+
+func TestEviction(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// touch a so b is the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	c.Add("c", 3)
+
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is still cached, want it evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was evicted, want b evicted")
+	}
+}
+
+func TestAddKeepsExisting(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	if got := c.Add("a", 2); got != 1 {
+		t.Errorf("Add(a, 2) = %d, want the cached 1", got)
+	}
+	if v, _ := c.Get("a"); v != 1 {
+		t.Errorf("Get(a) = %d, want 1", v)
+	}
+}
@@ -0,0 +1,127 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var (
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+	ErrInvalidTarget  = errors.New("target must be an absolute http or https URL")
+)
+
+// blockedPrefixes are ranges a webhook must never be delivered to:
+// loopback, private, link-local (including cloud metadata endpoints),
+// carrier-grade NAT and other non-routable space, plus the IPv6
+// transition ranges (NAT64, Teredo, 6to4) that embed an IPv4 address.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// Guard decides whether outbound connections may reach an address.
+// Operators can allow specific ranges for self-hosted setups.
+type Guard struct {
+	allow    []netip.Prefix
+	resolver *net.Resolver
+}
+
+func New(allow []netip.Prefix) *Guard {
+	return &Guard{
+		allow:    allow,
+		resolver: net.DefaultResolver,
+	}
+}
+
+// IsAllowed reports whether ip may be connected to.
+func (g *Guard) IsAllowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range g.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	if !ip.IsValid() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks that raw is an http(s) URL whose host resolves only
+// to allowed addresses. It is a first line of defence at configuration
+// time; Control enforces the same rule when the connection is made.
+func (g *Guard) ValidateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidTarget
+	}
+
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !g.IsAllowed(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+
+	addrs, err := g.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s", ErrInvalidTarget, host)
+	}
+	for _, ip := range addrs {
+		if !g.IsAllowed(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, ip)
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control hook. It runs after DNS resolution
+// with the concrete IP being dialled, so a hostname that re-resolves to
+// a private address (DNS rebinding) is still rejected.
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	if !g.IsAllowed(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsAllowed(t *testing.T) {
+	g := New([]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")})
+
+	tests := []struct {
+		name string
+		ip   string
+		want bool
+	}{
+		{name: "Public IPv4", ip: "93.184.216.34", want: true},
+		{name: "Public IPv6", ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{name: "Loopback", ip: "127.0.0.1", want: false},
+		{name: "IPv6 loopback", ip: "::1", want: false},
+		{name: "RFC1918", ip: "192.168.1.10", want: false},
+		{name: "Cloud metadata", ip: "169.254.169.254", want: false},
+		{name: "Unspecified", ip: "0.0.0.0", want: false},
+		{name: "IPv4-mapped loopback", ip: "::ffff:127.0.0.1", want: false},
+		{name: "Unique local IPv6", ip: "fd00:ec2::254", want: false},
+		{name: "6to4 embedding loopback", ip: "2002:7f00:1::1", want: false},
+		{name: "Teredo", ip: "2001:0:4136:e378:8000:63bf:3fff:fdd2", want: false},
+		{name: "Allowlisted private range", ip: "10.1.2.3", want: true},
+		{name: "Private range outside allowlist", ip: "10.2.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.IsAllowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Errorf("IsAllowed(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	g := New(nil)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "Public literal IP", url: "https://93.184.216.34/hook"},
+		{name: "Loopback literal", url: "http://127.0.0.1:8080/hook", wantErr: ErrBlockedAddress},
+		{name: "Localhost name", url: "http://localhost/hook", wantErr: ErrBlockedAddress},
+		{name: "Metadata endpoint", url: "http://169.254.169.254/latest/meta-data", wantErr: ErrBlockedAddress},
+		{name: "Unsupported scheme", url: "file:///etc/passwd", wantErr: ErrInvalidTarget},
+		{name: "Relative URL", url: "/hook", wantErr: ErrInvalidTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := g.ValidateURL(context.Background(), tt.url)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateURL() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package outbound
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// encodeForm flattens an object into application/x-www-form-urlencoded
+// using bracket notation for nesting: {"a":{"b":1},"c":[1,2]} becomes
+// a[b]=1&c[]=1&c[]=2.
+func encodeForm(v any) ([]byte, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: form encoding needs a JSON object, got %T", ErrInvalidEncoding, v)
+	}
+
+	var pairs []string
+	for _, k := range sortedKeys(obj) {
+		flattenForm(k, obj[k], &pairs)
+	}
+	return []byte(strings.Join(pairs, "&")), nil
+}
+
+func flattenForm(key string, v any, pairs *[]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(t) {
+			flattenForm(key+"["+k+"]", t[k], pairs)
+		}
+	case []any:
+		for _, item := range t {
+			flattenForm(key+"[]", item, pairs)
+		}
+	default:
+		*pairs = append(*pairs, url.QueryEscape(key)+"="+url.QueryEscape(scalar(t)))
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
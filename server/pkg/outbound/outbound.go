@@ -0,0 +1,128 @@
+package outbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/tmplguard"
+)
+
+// Format is the wire encoding of a delivered payload.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatForm     Format = "form"
+	FormatXML      Format = "xml"
+	FormatTemplate Format = "template"
+)
+
+const (
+	CONTENT_TYPE_JSON = "application/json"
+	CONTENT_TYPE_FORM = "application/x-www-form-urlencoded"
+	CONTENT_TYPE_XML  = "application/xml"
+	CONTENT_TYPE_TEXT = "text/plain; charset=utf-8"
+)
+
+var ErrInvalidEncoding = errors.New("invalid output encoding")
+
+// Encoder turns a transformed payload into a request body.
+type Encoder struct {
+	format      Format
+	contentType string
+	tmpl        *template.Template
+}
+
+// New builds an encoder. tmpl is required for FormatTemplate and ignored
+// otherwise; contentType overrides the format's default content type.
+func New(format Format, tmpl, contentType string) (*Encoder, error) {
+	e := &Encoder{format: format, contentType: contentType}
+	switch format {
+	case "", FormatJSON:
+		e.format = FormatJSON
+		e.setDefaultContentType(CONTENT_TYPE_JSON)
+	case FormatForm:
+		e.setDefaultContentType(CONTENT_TYPE_FORM)
+	case FormatXML:
+		e.setDefaultContentType(CONTENT_TYPE_XML)
+	case FormatTemplate:
+		if strings.TrimSpace(tmpl) == "" {
+			return nil, fmt.Errorf("%w: template is required for the template format", ErrInvalidEncoding)
+		}
+		t, err := template.New("body").Option("missingkey=zero").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+			"urlquery": url.QueryEscape,
+		}).Funcs(tmplguard.Funcs()).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+		}
+		if err := tmplguard.Prepare(t); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+		}
+		e.tmpl = t
+		e.setDefaultContentType(CONTENT_TYPE_TEXT)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidEncoding, format)
+	}
+	if strings.ContainsAny(e.contentType, "\r\n") {
+		return nil, fmt.Errorf("%w: content type must be a single line", ErrInvalidEncoding)
+	}
+	return e, nil
+}
+
+func (e *Encoder) setDefaultContentType(ct string) {
+	if e.contentType == "" {
+		e.contentType = ct
+	}
+}
+
+// ContentType is the value sent in the Content-Type header.
+func (e *Encoder) ContentType() string {
+	return e.contentType
+}
+
+// Encode renders v in the encoder's format. Templates run within
+// tmplguard.DefaultLimits and return a *tmplguard.LimitError past them.
+func (e *Encoder) Encode(v any) ([]byte, error) {
+	switch e.format {
+	case FormatForm:
+		return encodeForm(v)
+	case FormatXML:
+		return encodeXML(v)
+	case FormatTemplate:
+		body, err := tmplguard.Execute(e.tmpl, v, tmplguard.DefaultLimits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render body template: %w", err)
+		}
+		return bytes.ReplaceAll(body, []byte("<no value>"), nil), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// scalar formats a JSON scalar the way form and xml bodies carry it.
+func scalar(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case json.Number:
+		return t.String()
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,110 @@
+package outbound
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/MobasirSarkar/hookfilter/pkg/tmplguard"
+)
+
+func TestEncode(t *testing.T) {
+	payload := `{"user":{"name":"A & B","tags":["x","y"]},"amount":12.5,"ok":true,"note":null}`
+
+	tests := []struct {
+		name        string
+		format      Format
+		tmpl        string
+		contentType string
+		input       string
+		want        string
+		wantType    string
+		wantErr     error
+	}{
+		{
+			name:     "json default",
+			input:    `{"a":1}`,
+			want:     `{"a":1}`,
+			wantType: CONTENT_TYPE_JSON,
+		},
+		{
+			name:     "form flattens nested values",
+			format:   FormatForm,
+			input:    payload,
+			want:     "amount=12.5&note=&ok=true&user%5Bname%5D=A+%26+B&user%5Btags%5D%5B%5D=x&user%5Btags%5D%5B%5D=y",
+			wantType: CONTENT_TYPE_FORM,
+		},
+		{
+			name:    "form rejects non objects",
+			format:  FormatForm,
+			input:   `[1,2]`,
+			wantErr: ErrInvalidEncoding,
+		},
+		{
+			name:     "xml object",
+			format:   FormatXML,
+			input:    payload,
+			want:     `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<payload><amount>12.5</amount><note></note><ok>true</ok><user><name>A &amp; B</name><tags>x</tags><tags>y</tags></user></payload>`,
+			wantType: CONTENT_TYPE_XML,
+		},
+		{
+			name:     "xml single key becomes root",
+			format:   FormatXML,
+			input:    `{"order":{"1id":7}}`,
+			want:     `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<order><_1id>7</_1id></order>`,
+			wantType: CONTENT_TYPE_XML,
+		},
+		{
+			name:        "template with content type",
+			format:      FormatTemplate,
+			tmpl:        `name={{.user.name | urlquery}}{{.missing}}`,
+			contentType: "text/csv",
+			input:       payload,
+			want:        "name=A+%26+B",
+			wantType:    "text/csv",
+		},
+		{
+			name:    "template required",
+			format:  FormatTemplate,
+			wantErr: ErrInvalidEncoding,
+		},
+		{
+			name:    "unknown format",
+			format:  "yaml",
+			wantErr: ErrInvalidEncoding,
+		},
+		{
+			name:    "runaway template",
+			format:  FormatTemplate,
+			tmpl:    `{{range 100000000}}{{.}}{{end}}`,
+			input:   payload,
+			wantErr: tmplguard.ErrLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := New(tt.format, tt.tmpl, tt.contentType)
+			if err == nil {
+				var v any
+				_ = json.Unmarshal([]byte(tt.input), &v)
+				var body []byte
+				body, err = enc.Encode(v)
+				if err == nil {
+					if string(body) != tt.want {
+						t.Errorf("body = %q, want %q", body, tt.want)
+					}
+					if enc.ContentType() != tt.wantType {
+						t.Errorf("content type = %q, want %q", enc.ContentType(), tt.wantType)
+					}
+				}
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
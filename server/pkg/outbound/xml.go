@@ -0,0 +1,87 @@
+package outbound
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"unicode"
+)
+
+const (
+	XML_ROOT_ELEMENT = "payload"
+	XML_ITEM_ELEMENT = "item"
+)
+
+// encodeXML maps JSON onto elements: object keys become child elements,
+// array items repeat their parent's element name and scalars become
+// character data. An object with a single key uses it as the root.
+func encodeXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	root := XML_ROOT_ELEMENT
+	if obj, ok := v.(map[string]any); ok && len(obj) == 1 {
+		for k, child := range obj {
+			if _, isArr := child.([]any); !isArr {
+				root, v = k, child
+			}
+		}
+	}
+	if arr, ok := v.([]any); ok {
+		v = map[string]any{XML_ITEM_ELEMENT: arr}
+	}
+	if err := writeElement(&buf, xmlName(root), v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeElement(buf *bytes.Buffer, name string, v any) error {
+	if arr, ok := v.([]any); ok {
+		for _, item := range arr {
+			if err := writeElement(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	buf.WriteString("<" + name + ">")
+	switch t := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(t) {
+			if err := writeElement(buf, xmlName(k), t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := xml.EscapeText(buf, []byte(scalar(t))); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+// xmlName turns an arbitrary JSON key into a valid element name.
+func xmlName(k string) string {
+	var b strings.Builder
+	for i, r := range k {
+		valid := unicode.IsLetter(r) || r == '_' ||
+			(i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'))
+		if !valid {
+			if i == 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+				b.WriteRune('_')
+				b.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,359 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ActionMask = "mask"
+	ActionHash = "hash"
+	ActionDrop = "drop"
+
+	// MASK replaces masked values.
+	MASK = "[REDACTED]"
+	// HASH_PREFIX marks hashed values, followed by 32 hex characters.
+	HASH_PREFIX = "hash:"
+
+	MAX_RULES   = 50
+	MAX_PATTERN = 1000
+)
+
+var (
+	ErrInvalidRules = errors.New("invalid redaction rules")
+	// ErrNoHashKey is returned by Compile without a key to hash under.
+	ErrNoHashKey = errors.New("redaction hash key is required")
+)
+
+// presets can be used as a rule's pattern instead of a regular expression.
+// A phone number needs a leading '+' or two separated groups of digits,
+// so plain numbers such as ids, amounts and timestamps never match.
+var presets = map[string]string{
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`,
+	"phone": `\+\d[\d ().-]{5,}\d|\(?\d{2,4}\)?[ .-]\d{2,4}[ .-]\d{4,}`,
+}
+
+// Rule selects values with exactly one of Path, Key and Pattern and
+// redacts them with Action.
+type Rule struct {
+	// Path is a dot separated path from the payload root. "*" matches
+	// every key or array element and a number an array index, e.g.
+	// "customer.address" or "items.*.card.fingerprint".
+	Path string `json:"path,omitempty"`
+	// Key is a regular expression matched against object keys at any
+	// depth, e.g. "(?i)^(email|phone)$".
+	Key string `json:"key,omitempty"`
+	// Pattern is a regular expression, or the preset "email" or
+	// "phone", searched for in string values at any depth. mask and hash
+	// replace the matched text, drop removes the whole value.
+	Pattern string `json:"pattern,omitempty"`
+	Action  string `json:"action"`
+}
+
+// Rules are applied in order.
+type Rules []Rule
+
+type compiledRule struct {
+	path    []string
+	key     *regexp.Regexp
+	pattern *regexp.Regexp
+	action  string
+}
+
+// Redactor is a compiled set of Rules. It is safe for concurrent use.
+type Redactor struct {
+	rules   []compiledRule
+	hashKey []byte
+}
+
+// Parse decodes rules stored as JSON, empty input meaning none.
+func Parse(raw []byte) (Rules, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var rules Rules
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	return rules, nil
+}
+
+// Compile validates the rules. Hashes are HMAC-SHA256 under hashKey,
+// which is required, so guessable values like emails cannot be
+// recovered by hashing guesses without it.
+func (rs Rules) Compile(hashKey []byte) (*Redactor, error) {
+	if len(hashKey) == 0 {
+		return nil, ErrNoHashKey
+	}
+	rules, err := rs.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{rules: rules, hashKey: hashKey}, nil
+}
+
+// Validate reports whether the rules would compile, for checking them
+// where no hash key is at hand.
+func (rs Rules) Validate() error {
+	_, err := rs.compile()
+	return err
+}
+
+func (rs Rules) compile() ([]compiledRule, error) {
+	if len(rs) > MAX_RULES {
+		return nil, fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidRules, MAX_RULES)
+	}
+	compiled := make([]compiledRule, 0, len(rs))
+	for i, rule := range rs {
+		c, err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func (rule Rule) compile() (compiledRule, error) {
+	c := compiledRule{action: rule.Action}
+	switch rule.Action {
+	case ActionMask, ActionHash, ActionDrop:
+	default:
+		return c, fmt.Errorf("action must be %s, %s or %s", ActionMask, ActionHash, ActionDrop)
+	}
+
+	set := 0
+	for _, s := range []string{rule.Path, rule.Key, rule.Pattern} {
+		if s != "" {
+			set++
+		}
+		if len(s) > MAX_PATTERN {
+			return c, fmt.Errorf("selectors are limited to %d characters", MAX_PATTERN)
+		}
+	}
+	if set != 1 {
+		return c, errors.New("exactly one of path, key and pattern is required")
+	}
+
+	var err error
+	switch {
+	case rule.Path != "":
+		c.path = strings.Split(strings.TrimPrefix(rule.Path, "$."), ".")
+		for _, seg := range c.path {
+			if seg == "" {
+				return c, fmt.Errorf("invalid path %q", rule.Path)
+			}
+		}
+	case rule.Key != "":
+		if c.key, err = regexp.Compile(rule.Key); err != nil {
+			return c, fmt.Errorf("invalid key pattern: %v", err)
+		}
+	default:
+		expr := rule.Pattern
+		if preset, ok := presets[expr]; ok {
+			expr = preset
+		}
+		if c.pattern, err = regexp.Compile(expr); err != nil {
+			return c, fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// Empty reports whether r redacts nothing.
+func (r *Redactor) Empty() bool {
+	return r == nil || len(r.rules) == 0
+}
+
+// Apply returns a redacted copy of v, a value decoded from JSON; v is
+// left untouched. The result is nil if a rule dropped v itself.
+func (r *Redactor) Apply(v any) any {
+	if r.Empty() {
+		return v
+	}
+	out := clone(v)
+	for _, rule := range r.rules {
+		var keep bool
+		switch {
+		case rule.path != nil:
+			out, keep = r.applyPath(out, rule.path, rule.action), true
+		case rule.key != nil:
+			out, keep = r.applyKey(out, rule), true
+		default:
+			out, keep = r.applyPattern(out, rule)
+		}
+		if !keep {
+			return nil
+		}
+	}
+	return out
+}
+
+// redact applies action to a selected value, reporting false when the
+// value is to be dropped.
+func (r *Redactor) redact(v any, action string) (any, bool) {
+	switch action {
+	case ActionDrop:
+		return nil, false
+	case ActionHash:
+		return r.hash(v), true
+	default:
+		return MASK, true
+	}
+}
+
+func (r *Redactor) hash(v any) string {
+	var b []byte
+	switch s := v.(type) {
+	case string:
+		b = []byte(s)
+	case json.Number:
+		b = []byte(s)
+	default:
+		b, _ = json.Marshal(v)
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write(b)
+	return HASH_PREFIX + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (r *Redactor) applyPath(v any, path []string, action string) any {
+	seg, rest := path[0], path[1:]
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if seg != "*" && k != seg {
+				continue
+			}
+			if len(rest) > 0 {
+				node[k] = r.applyPath(child, rest, action)
+				continue
+			}
+			if red, keep := r.redact(child, action); keep {
+				node[k] = red
+			} else {
+				delete(node, k)
+			}
+		}
+		return node
+	case []any:
+		idx := -1
+		if seg != "*" {
+			n, err := strconv.Atoi(seg)
+			if err != nil {
+				return node
+			}
+			idx = n
+		}
+		out := node[:0]
+		for i, child := range node {
+			if idx >= 0 && i != idx {
+				out = append(out, child)
+				continue
+			}
+			if len(rest) > 0 {
+				out = append(out, r.applyPath(child, rest, action))
+				continue
+			}
+			if red, keep := r.redact(child, action); keep {
+				out = append(out, red)
+			}
+		}
+		return out
+	}
+	return v
+}
+
+func (r *Redactor) applyKey(v any, rule compiledRule) any {
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if !rule.key.MatchString(k) {
+				node[k] = r.applyKey(child, rule)
+				continue
+			}
+			if red, keep := r.redact(child, rule.action); keep {
+				node[k] = red
+			} else {
+				delete(node, k)
+			}
+		}
+		return node
+	case []any:
+		for i, child := range node {
+			node[i] = r.applyKey(child, rule)
+		}
+		return node
+	}
+	return v
+}
+
+func (r *Redactor) applyPattern(v any, rule compiledRule) (any, bool) {
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if red, keep := r.applyPattern(child, rule); keep {
+				node[k] = red
+			} else {
+				delete(node, k)
+			}
+		}
+		return node, true
+	case []any:
+		out := node[:0]
+		for _, child := range node {
+			if red, keep := r.applyPattern(child, rule); keep {
+				out = append(out, red)
+			}
+		}
+		return out, true
+	case string:
+		return r.replace(node, rule)
+	case json.Number:
+		if red, keep := r.replace(string(node), rule); !keep || red != string(node) {
+			return red, keep
+		}
+		return node, true
+	}
+	return v, true
+}
+
+func (r *Redactor) replace(s string, rule compiledRule) (any, bool) {
+	if !rule.pattern.MatchString(s) {
+		return s, true
+	}
+	switch rule.action {
+	case ActionDrop:
+		return nil, false
+	case ActionHash:
+		return rule.pattern.ReplaceAllStringFunc(s, func(m string) string { return r.hash(m) }), true
+	default:
+		return rule.pattern.ReplaceAllLiteralString(s, MASK), true
+	}
+}
+
+// clone deep copies the maps and slices of a decoded JSON value.
+func clone(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for k, child := range node {
+			out[k] = clone(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, child := range node {
+			out[i] = clone(child)
+		}
+		return out
+	}
+	return v
+}
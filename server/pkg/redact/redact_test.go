@@ -0,0 +1,179 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("test-key")
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+		in    string
+		want  string
+	}{
+		{
+			name:  "path mask",
+			rules: Rules{{Path: "customer.email", Action: ActionMask}},
+			in:    `{"customer":{"email":"a@b.io","id":1}}`,
+			want:  `{"customer":{"email":"[REDACTED]","id":1}}`,
+		},
+		{
+			name:  "path wildcard drop",
+			rules: Rules{{Path: "items.*.card", Action: ActionDrop}},
+			in:    `{"items":[{"card":{"last4":"4242"},"sku":"a"},{"sku":"b"}]}`,
+			want:  `{"items":[{"sku":"a"},{"sku":"b"}]}`,
+		},
+		{
+			name:  "path array index",
+			rules: Rules{{Path: "$.lines.1", Action: ActionDrop}},
+			in:    `{"lines":["a","b","c"]}`,
+			want:  `{"lines":["a","c"]}`,
+		},
+		{
+			name:  "missing path",
+			rules: Rules{{Path: "customer.address.zip", Action: ActionMask}},
+			in:    `{"customer":"x"}`,
+			want:  `{"customer":"x"}`,
+		},
+		{
+			name:  "key at any depth",
+			rules: Rules{{Key: "(?i)^(address|fingerprint)$", Action: ActionMask}},
+			in:    `{"Address":{"line1":"1 Main St"},"card":{"Fingerprint":"fp_1","brand":"visa"}}`,
+			want:  `{"Address":"[REDACTED]","card":{"Fingerprint":"[REDACTED]","brand":"visa"}}`,
+		},
+		{
+			name:  "email preset in text",
+			rules: Rules{{Pattern: "email", Action: ActionMask}},
+			in:    `{"note":"contact jane.doe@example.co.uk today","list":["x@y.io"]}`,
+			want:  `{"note":"contact [REDACTED] today","list":["[REDACTED]"]}`,
+		},
+		{
+			name:  "phone preset drop",
+			rules: Rules{{Pattern: "phone", Action: ActionDrop}},
+			in:    `{"phone":"+1 (555) 010-9999","amount":"12.50"}`,
+			want:  `{"amount":"12.50"}`,
+		},
+		{
+			name:  "phone preset formats",
+			rules: Rules{{Pattern: "phone", Action: ActionMask}},
+			in:    `{"a":"call (555) 010-9999","b":"555.010.9999","c":"020 7946 0958","d":"+4915112345678"}`,
+			want:  `{"a":"call [REDACTED]","b":"[REDACTED]","c":"[REDACTED]","d":"[REDACTED]"}`,
+		},
+		{
+			name:  "phone preset skips plain numbers",
+			rules: Rules{{Pattern: "phone", Action: ActionDrop}},
+			in:    `{"ts":1768828800123,"sent_at":"1768828800","id":"123456789012","amount":"1234.56789","date":"2026-01-19","ip":"192.168.100.200"}`,
+			want:  `{"ts":1768828800123,"sent_at":"1768828800","id":"123456789012","amount":"1234.56789","date":"2026-01-19","ip":"192.168.100.200"}`,
+		},
+		{
+			name:  "rules in order",
+			rules: Rules{{Key: "^email$", Action: ActionDrop}, {Pattern: "email", Action: ActionMask}},
+			in:    `{"email":"a@b.io","cc":"c@d.io"}`,
+			want:  `{"cc":"[REDACTED]"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.rules.Compile(testKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := decode(t, tt.in)
+			got := r.Apply(in)
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				g, _ := json.Marshal(got)
+				t.Errorf("Apply() = %s, want %s", g, tt.want)
+			}
+			// the input is what gets delivered, it must not change
+			if orig := decode(t, tt.in); !reflect.DeepEqual(in, orig) {
+				t.Errorf("Apply() modified its input")
+			}
+		})
+	}
+}
+
+func TestApplyHash(t *testing.T) {
+	rules := Rules{{Path: "email", Action: ActionHash}}
+	a, _ := rules.Compile([]byte("key-a"))
+	b, _ := rules.Compile([]byte("key-b"))
+
+	in := decode(t, `{"email":"a@b.io"}`)
+	ha := a.Apply(in).(map[string]any)["email"].(string)
+	hb := b.Apply(in).(map[string]any)["email"].(string)
+
+	if !strings.HasPrefix(ha, HASH_PREFIX) || len(ha) != len(HASH_PREFIX)+32 {
+		t.Fatalf("hash = %q, want %s followed by 32 hex characters", ha, HASH_PREFIX)
+	}
+	if again := a.Apply(in).(map[string]any)["email"]; again != ha {
+		t.Errorf("hash is not stable: %q then %q", ha, again)
+	}
+	if ha == hb {
+		t.Errorf("hashes under different keys are equal")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+	}{
+		{"no selector", Rules{{Action: ActionMask}}},
+		{"two selectors", Rules{{Path: "a", Key: "b", Action: ActionMask}}},
+		{"bad action", Rules{{Path: "a", Action: "erase"}}},
+		{"empty segment", Rules{{Path: "a..b", Action: ActionMask}}},
+		{"bad regex", Rules{{Pattern: "(", Action: ActionMask}}},
+		{"too many", make(Rules, MAX_RULES+1)},
+	}
+	for _, tt := range tests {
+		if _, err := tt.rules.Compile(testKey); !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("%s: Compile() error = %v, want ErrInvalidRules", tt.name, err)
+		}
+		if err := tt.rules.Validate(); !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("%s: Validate() error = %v, want ErrInvalidRules", tt.name, err)
+		}
+	}
+}
+
+func TestCompileRequiresKey(t *testing.T) {
+	rules := Rules{{Path: "email", Action: ActionHash}}
+	if _, err := rules.Compile(nil); !errors.Is(err, ErrNoHashKey) {
+		t.Errorf("Compile(nil) error = %v, want ErrNoHashKey", err)
+	}
+	if err := rules.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestPhonePresetSkipsNumbers(t *testing.T) {
+	r, err := Rules{{Pattern: "phone", Action: ActionDrop}}.Compile(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// payloads are decoded with UseNumber, so epoch timestamps reach the
+	// pattern as their digits
+	dec := json.NewDecoder(strings.NewReader(`{"ts":1768828800123,"seq":-12345678.5}`))
+	dec.UseNumber()
+	var in any
+	if err := dec.Decode(&in); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Apply(in).(map[string]any)
+	if got["ts"] != json.Number("1768828800123") || got["seq"] != json.Number("-12345678.5") {
+		t.Errorf("Apply() = %v, want numbers kept", got)
+	}
+}
@@ -52,6 +52,12 @@ func JSON(w http.ResponseWriter, status int, data any, message string, metadata
 
 // Error sends a standardized error response
 func Error(w http.ResponseWriter, status int, message string, meta *Metadata) {
+	ErrorDetails(w, status, message, nil, meta)
+}
+
+// ErrorDetails is Error with structured details in data, e.g. the
+// position of a jq syntax error.
+func ErrorDetails(w http.ResponseWriter, status int, message string, details any, meta *Metadata) {
 	if meta == nil {
 		meta = &Metadata{}
 	}
@@ -65,6 +71,7 @@ func Error(w http.ResponseWriter, status int, message string, meta *Metadata) {
 	resp := Envelope{
 		Success:  false,
 		Error:    message,
+		Data:     details,
 		Metadata: meta,
 	}
 
@@ -0,0 +1,137 @@
+package tlsprofile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidProfile = errors.New("invalid tls settings")
+	ErrPinMismatch    = errors.New("server certificate does not match any pinned key")
+)
+
+// Profile is the TLS configuration used to reach a pipe's destination.
+// All fields are optional; the zero value means system defaults.
+type Profile struct {
+	// ClientCert and ClientKey are PEM encoded and used for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// CABundle holds extra PEM encoded roots trusted on top of the system pool.
+	CABundle string
+	// SPKIPins are base64 SHA-256 hashes of a SubjectPublicKeyInfo; at least
+	// one certificate in the verified chain must match one of them.
+	SPKIPins []string
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string
+}
+
+func (p Profile) IsZero() bool {
+	return p.ClientCert == "" && p.ClientKey == "" && p.CABundle == "" &&
+		len(p.SPKIPins) == 0 && p.MinVersion == ""
+}
+
+// Validate checks that the profile can be turned into a tls.Config.
+func (p Profile) Validate() error {
+	_, err := p.Build()
+	return err
+}
+
+// Build returns the tls.Config for the profile.
+func (p Profile) Build() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if p.MinVersion != "" {
+		v, err := parseVersion(p.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MinVersion = v
+	}
+
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		return nil, fmt.Errorf("%w: client certificate and key must be set together", ErrInvalidProfile)
+	}
+	if p.ClientCert != "" {
+		pair, err := tls.X509KeyPair([]byte(p.ClientCert), []byte(p.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate: %v", ErrInvalidProfile, err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+
+	if p.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(p.CABundle)) {
+			return nil, fmt.Errorf("%w: ca bundle contains no certificates", ErrInvalidProfile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(p.SPKIPins) > 0 {
+		pins := make([][]byte, 0, len(p.SPKIPins))
+		for _, pin := range p.SPKIPins {
+			raw, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("%w: pin %q is not a base64 sha256 hash", ErrInvalidProfile, pin)
+			}
+			pins = append(pins, raw)
+		}
+		cfg.VerifyConnection = verifyPins(pins)
+	}
+
+	return cfg, nil
+}
+
+// Key identifies a profile as stored, so transports can be shared by
+// pipes with identical settings. It is computed over the stored (and
+// therefore encrypted) key so no secret material is hashed.
+func Key(clientCert, storedKey, caBundle string, pins []string, minVersion string) string {
+	if clientCert == "" && storedKey == "" && caBundle == "" && len(pins) == 0 && minVersion == "" {
+		return ""
+	}
+	h := sha256.New()
+	for _, part := range []string{clientCert, storedKey, caBundle, strings.Join(pins, ","), minVersion} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifyPins runs after standard chain verification and requires one
+// certificate of the verified chain to carry a pinned public key.
+func verifyPins(pins [][]byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				for _, pin := range pins {
+					if bytes.Equal(sum[:], pin) {
+						return nil
+					}
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+}
+
+func parseVersion(v string) (uint16, error) {
+	switch v {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w: min_version must be 1.2 or 1.3", ErrInvalidProfile)
+	}
+}
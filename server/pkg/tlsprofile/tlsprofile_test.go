@@ -0,0 +1,83 @@
+package tlsprofile
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildPinning(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cert := srv.Certificate()
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	goodPin := base64.StdEncoding.EncodeToString(sum[:])
+	badPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name    string
+		profile Profile
+		wantErr error
+	}{
+		{name: "Custom CA", profile: Profile{CABundle: caPEM}},
+		{name: "Custom CA with matching pin", profile: Profile{CABundle: caPEM, SPKIPins: []string{badPin, goodPin}}},
+		{name: "Custom CA with wrong pin", profile: Profile{CABundle: caPEM, SPKIPins: []string{badPin}}, wantErr: ErrPinMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.profile.Build()
+			if err != nil {
+				t.Fatalf("Build() unexpected error: %v", err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+
+			resp, err := client.Get(srv.URL)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Get() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		wantErr bool
+	}{
+		{name: "Empty profile", profile: Profile{}},
+		{name: "TLS 1.3 minimum", profile: Profile{MinVersion: "1.3"}},
+		{name: "Unsupported version", profile: Profile{MinVersion: "1.0"}, wantErr: true},
+		{name: "Cert without key", profile: Profile{ClientCert: "x"}, wantErr: true},
+		{name: "Garbage CA bundle", profile: Profile{CABundle: "not pem"}, wantErr: true},
+		{name: "Short pin", profile: Profile{SPKIPins: []string{"YWJj"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("Validate() error = %v, want ErrInvalidProfile", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package tmplguard executes user supplied text/templates within a time
+// and output budget.
+package tmplguard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Limits bound a single execution. Zero fields are unlimited.
+type Limits struct {
+	// Timeout is checked whenever the template writes and at the start
+	// of every range iteration and template call.
+	Timeout time.Duration
+	// MaxOutputBytes caps the rendered size.
+	MaxOutputBytes int
+}
+
+// DefaultLimits apply to every template rendered by the destinations.
+var DefaultLimits = Limits{
+	Timeout:        time.Second,
+	MaxOutputBytes: 1 << 20,
+}
+
+var ErrLimitExceeded = errors.New("template exceeded its execution limits")
+
+// Limit names used in LimitError.
+const (
+	LIMIT_TIMEOUT      = "timeout"
+	LIMIT_OUTPUT_BYTES = "output_bytes"
+	LIMIT_FORMAT_WIDTH = "format_width"
+
+	// MAX_FORMAT_WIDTH bounds printf widths and precisions, which are
+	// allocated before anything is written.
+	MAX_FORMAT_WIDTH = 1000
+
+	guardFunc = "_tmplguard"
+)
+
+// LimitError reports which limit stopped a run. It matches
+// ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	Limit string
+	Max   string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("template %s limit of %s exceeded", e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// formatWidth matches the width and precision of a printf verb, either
+// of which may come from an argument as '*'.
+var formatWidth = regexp.MustCompile(`%[-+# 0]*(?:\[\d+\])?(\*|\d*)(?:\.(?:\[\d+\])?(\*|\d*))?`)
+
+// Funcs are the functions Prepare relies on. They must be added to a
+// template before it is parsed; printf replaces the builtin with one that
+// rejects oversized widths.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		guardFunc: func() (string, error) { return "", nil },
+		"printf":  printf,
+	}
+}
+
+func printf(format string, args ...any) (string, error) {
+	for _, m := range formatWidth.FindAllStringSubmatch(format, -1) {
+		for _, n := range m[1:] {
+			if n == "*" || len(n) > 3 {
+				return "", &LimitError{Limit: LIMIT_FORMAT_WIDTH, Max: fmt.Sprint(MAX_FORMAT_WIDTH)}
+			}
+		}
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+// Prepare inserts the deadline check at the start of every range body
+// and associated template of t. It must run once, after parsing.
+func Prepare(t *template.Template) error {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		check, err := guardNode()
+		if err != nil {
+			return err
+		}
+		tmpl.Tree.Root.Nodes = append([]parse.Node{check}, tmpl.Tree.Root.Nodes...)
+		if err := guardRanges(tmpl.Tree.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func guardNode() (parse.Node, error) {
+	trees, err := parse.Parse("guard", "{{"+guardFunc+"}}", "{{", "}}", map[string]any{guardFunc: true})
+	if err != nil {
+		return nil, err
+	}
+	return trees["guard"].Root.Nodes[0], nil
+}
+
+func guardRanges(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+	for _, n := range list.Nodes {
+		var branch *parse.BranchNode
+		switch node := n.(type) {
+		case *parse.RangeNode:
+			branch = &node.BranchNode
+			check, err := guardNode()
+			if err != nil {
+				return err
+			}
+			node.List.Nodes = append([]parse.Node{check}, node.List.Nodes...)
+		case *parse.IfNode:
+			branch = &node.BranchNode
+		case *parse.WithNode:
+			branch = &node.BranchNode
+		case *parse.ListNode:
+			if err := guardRanges(node); err != nil {
+				return err
+			}
+		}
+		if branch != nil {
+			if err := guardRanges(branch.List); err != nil {
+				return err
+			}
+			if err := guardRanges(branch.ElseList); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// capWriter fails once more than max bytes were written or the deadline
+// passed, which aborts the execution writing to it.
+type capWriter struct {
+	buf      bytes.Buffer
+	max      int
+	deadline time.Time
+	limits   Limits
+}
+
+func (w *capWriter) check() error {
+	if !w.deadline.IsZero() && time.Now().After(w.deadline) {
+		return &LimitError{Limit: LIMIT_TIMEOUT, Max: w.limits.Timeout.String()}
+	}
+	return nil
+}
+
+func (w *capWriter) Write(p []byte) (int, error) {
+	if err := w.check(); err != nil {
+		return 0, err
+	}
+	if w.max > 0 && w.buf.Len()+len(p) > w.max {
+		return 0, &LimitError{Limit: LIMIT_OUTPUT_BYTES, Max: fmt.Sprint(w.max)}
+	}
+	return w.buf.Write(p)
+}
+
+// Execute renders t, prepared with Prepare, against data within limits.
+// A run stopped by a limit returns a *LimitError.
+func Execute(t *template.Template, data any, limits Limits) ([]byte, error) {
+	w := &capWriter{max: limits.MaxOutputBytes, limits: limits}
+	if limits.Timeout > 0 {
+		w.deadline = time.Now().Add(limits.Timeout)
+	}
+
+	// the guard is bound per execution, a clone shares the parse trees
+	run, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	run.Funcs(template.FuncMap{guardFunc: func() (string, error) { return "", w.check() }})
+
+	if err := run.Execute(w, data); err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return nil, limitErr
+		}
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package tmplguard
+
+import (
+	"errors"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func prepared(t *testing.T, src string) *template.Template {
+	t.Helper()
+	tmpl, err := template.New("t").Funcs(Funcs()).Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Prepare(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestExecute(t *testing.T) {
+	limits := Limits{Timeout: 50 * time.Millisecond, MaxOutputBytes: 64}
+	tests := []struct {
+		name  string
+		src   string
+		want  string
+		limit string
+	}{
+		{name: "renders", src: `{{range .items}}{{.}},{{end}}{{printf "%05d" 7}}`, want: "a,b,00007"},
+		{name: "nested template", src: `{{define "x"}}[{{.}}]{{end}}{{range .items}}{{template "x" .}}{{end}}`, want: "[a][b]"},
+		{name: "silent loop", src: `{{range 1000000000}}{{end}}`, limit: LIMIT_TIMEOUT},
+		{name: "nested silent loop", src: `{{if true}}{{range 100000}}{{range 100000}}{{end}}{{end}}{{end}}`, limit: LIMIT_TIMEOUT},
+		{name: "large output", src: `{{range 1000}}0123456789{{end}}`, limit: LIMIT_OUTPUT_BYTES},
+		{name: "wide printf", src: `{{printf "%01000000000d" 1}}`, limit: LIMIT_FORMAT_WIDTH},
+		{name: "star printf", src: `{{printf "%*d" 1000000000 1}}`, limit: LIMIT_FORMAT_WIDTH},
+	}
+	data := map[string]any{"items": []any{"a", "b"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := Execute(prepared(t, tt.src), data, limits)
+			if time.Since(start) > time.Second {
+				t.Errorf("Execute() took %s", time.Since(start))
+			}
+			if tt.limit == "" {
+				if err != nil || string(got) != tt.want {
+					t.Errorf("Execute() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit || !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("Execute() error = %v, want %s limit", err, tt.limit)
+			}
+		})
+	}
+}
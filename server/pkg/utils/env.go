@@ -3,6 +3,8 @@ package utils
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func GetEnv(key, def string) string {
@@ -35,3 +37,33 @@ func GetEnvBool(key string, def bool) bool {
 	}
 	return b
 }
+
+func GetEnvSlice(key string, def []string) []string {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	parts := strings.Split(val, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
+func GetEnvDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS max_concurrency,
+DROP COLUMN IF EXISTS weight;
@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+ADD COLUMN weight INT NOT NULL DEFAULT 1,
+ADD COLUMN max_concurrency INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS tls_min_version,
+DROP COLUMN IF EXISTS tls_spki_pins,
+DROP COLUMN IF EXISTS tls_ca_bundle,
+DROP COLUMN IF EXISTS tls_client_key,
+DROP COLUMN IF EXISTS tls_client_cert;
@@ -0,0 +1,6 @@
+ALTER TABLE pipes
+ADD COLUMN tls_client_cert TEXT DEFAULT NULL,
+ADD COLUMN tls_client_key TEXT DEFAULT NULL,
+ADD COLUMN tls_ca_bundle TEXT DEFAULT NULL,
+ADD COLUMN tls_spki_pins TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN tls_min_version TEXT DEFAULT NULL;
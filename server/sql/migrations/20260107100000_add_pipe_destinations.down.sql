@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS destination_config,
+DROP COLUMN IF EXISTS destination_type;
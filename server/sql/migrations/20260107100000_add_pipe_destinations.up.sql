@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+ADD COLUMN destination_type TEXT NOT NULL DEFAULT 'http',
+ADD COLUMN destination_config JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS output_content_type,
+DROP COLUMN IF EXISTS output_template,
+DROP COLUMN IF EXISTS output_format;
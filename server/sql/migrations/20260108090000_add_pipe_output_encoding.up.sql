@@ -0,0 +1,4 @@
+ALTER TABLE pipes
+ADD COLUMN output_format TEXT NOT NULL DEFAULT 'json',
+ADD COLUMN output_template TEXT NULL,
+ADD COLUMN output_content_type TEXT NULL;
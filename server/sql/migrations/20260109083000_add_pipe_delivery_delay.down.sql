@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS deliver_at_expr,
+DROP COLUMN IF EXISTS delivery_delay_seconds;
@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+ADD COLUMN delivery_delay_seconds INT NOT NULL DEFAULT 0,
+ADD COLUMN deliver_at_expr TEXT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS window_max_events,
+DROP COLUMN IF EXISTS window_seconds,
+DROP COLUMN IF EXISTS window_key_expr,
+DROP COLUMN IF EXISTS window_mode;
@@ -0,0 +1,5 @@
+ALTER TABLE pipes
+ADD COLUMN window_mode TEXT NULL,
+ADD COLUMN window_key_expr TEXT NULL,
+ADD COLUMN window_seconds INT NOT NULL DEFAULT 0,
+ADD COLUMN window_max_events INT NOT NULL DEFAULT 0;
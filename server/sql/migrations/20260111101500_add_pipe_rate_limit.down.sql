@@ -0,0 +1,4 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS rate_limit_scope,
+DROP COLUMN IF EXISTS rate_limit_burst,
+DROP COLUMN IF EXISTS rate_limit_per_sec;
@@ -0,0 +1,4 @@
+ALTER TABLE pipes
+ADD COLUMN rate_limit_per_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN rate_limit_burst INT NOT NULL DEFAULT 0,
+ADD COLUMN rate_limit_scope TEXT NOT NULL DEFAULT 'pipe';
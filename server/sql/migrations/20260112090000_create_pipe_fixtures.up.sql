@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS pipe_fixtures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pipe_id UUID NOT NULL REFERENCES pipes(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    input JSONB NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    expected JSONB,
+    expect_dropped BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (pipe_id, name)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS pipe_revisions;
+DROP FUNCTION IF EXISTS pipe_config(JSONB);
+
+ALTER TABLE events
+DROP COLUMN IF EXISTS pipe_revision;
+
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS revision;
@@ -0,0 +1,27 @@
+ALTER TABLE pipes
+ADD COLUMN revision INT NOT NULL DEFAULT 1;
+
+ALTER TABLE events
+ADD COLUMN pipe_revision INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS pipe_revisions (
+    pipe_id UUID NOT NULL REFERENCES pipes(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    user_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    -- source_revision is the revision a rollback restored
+    source_revision INT,
+    config JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (pipe_id, revision)
+);
+
+-- pipe_config strips a pipes row (as JSONB) down to what a revision
+-- records, leaving out identity, lifecycle and bookkeeping columns.
+CREATE OR REPLACE FUNCTION pipe_config(p JSONB) RETURNS JSONB AS $$
+    SELECT p - ARRAY['id', 'user_id', 'is_active', 'created_at', 'updated_at', 'deleted_at', 'revision']
+$$ LANGUAGE SQL IMMUTABLE;
+
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config, created_at)
+SELECT p.id, 1, p.user_id, 'create', pipe_config(to_jsonb(p)), p.created_at
+FROM pipes p;
@@ -0,0 +1,6 @@
+CREATE OR REPLACE FUNCTION pipe_config(p JSONB) RETURNS JSONB AS $$
+    SELECT p - ARRAY['id', 'user_id', 'is_active', 'created_at', 'updated_at', 'deleted_at', 'revision']
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS paused_at;
@@ -0,0 +1,9 @@
+-- a paused pipe keeps accepting events, the worker buffers them until
+-- it is resumed
+ALTER TABLE pipes
+ADD COLUMN paused_at TIMESTAMP;
+
+-- pausing is not a configuration change, keep it out of revisions
+CREATE OR REPLACE FUNCTION pipe_config(p JSONB) RETURNS JSONB AS $$
+    SELECT p - ARRAY['id', 'user_id', 'is_active', 'created_at', 'updated_at', 'deleted_at', 'revision', 'paused_at']
+$$ LANGUAGE SQL IMMUTABLE;
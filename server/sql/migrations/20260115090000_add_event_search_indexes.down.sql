@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_transformed_payload;
+DROP INDEX IF EXISTS idx_events_request_payload;
+DROP INDEX IF EXISTS idx_events_pipe_created;
@@ -0,0 +1,10 @@
+-- keyset pagination walks a pipe's events newest first
+CREATE INDEX IF NOT EXISTS idx_events_pipe_created
+ON events(pipe_id, created_at DESC, id DESC);
+
+-- jsonb_path_ops serves both containment (@>) and jsonpath (@?) search
+CREATE INDEX IF NOT EXISTS idx_events_request_payload
+ON events USING GIN (request_payload jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_transformed_payload
+ON events USING GIN (transformed_payload jsonb_path_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS event_retention_days,
+DROP COLUMN IF EXISTS plan;
+
+DROP TABLE IF EXISTS retention_plans;
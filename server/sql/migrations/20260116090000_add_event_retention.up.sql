@@ -0,0 +1,16 @@
+-- how long each plan keeps its users' events
+CREATE TABLE IF NOT EXISTS retention_plans (
+    plan TEXT PRIMARY KEY,
+    retention_days INT NOT NULL CHECK (retention_days > 0)
+);
+
+INSERT INTO retention_plans (plan, retention_days) VALUES
+    ('free', 7),
+    ('pro', 30),
+    ('business', 90)
+ON CONFLICT (plan) DO NOTHING;
+
+-- event_retention_days overrides the plan's retention for one user
+ALTER TABLE users
+ADD COLUMN plan TEXT NOT NULL DEFAULT 'free',
+ADD COLUMN event_retention_days INT CHECK (event_retention_days > 0);
@@ -0,0 +1,32 @@
+ALTER TABLE events RENAME TO events_partitioned;
+ALTER INDEX events_pkey RENAME TO events_partitioned_pkey;
+
+CREATE TABLE events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pipe_id UUID NOT NULL REFERENCES pipes(id) ON DELETE CASCADE,
+    status_code INT NOT NULL,
+    request_payload JSONB NOT NULL,
+    transformed_payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    pipe_revision INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO events (id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision)
+SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision
+FROM events_partitioned
+ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE events_partitioned;
+DROP FUNCTION IF EXISTS drop_event_partition(TEXT);
+DROP FUNCTION IF EXISTS create_event_partition(TIMESTAMP);
+
+CREATE INDEX IF NOT EXISTS idx_events_pipe_id ON events(pipe_id);
+
+CREATE INDEX IF NOT EXISTS idx_events_pipe_created
+ON events(pipe_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_events_request_payload
+ON events USING GIN (request_payload jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_transformed_payload
+ON events USING GIN (transformed_payload jsonb_path_ops);
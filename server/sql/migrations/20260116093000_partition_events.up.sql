@@ -0,0 +1,84 @@
+-- events becomes range partitioned by month on created_at, so months
+-- past every retention period are dropped rather than deleted row by
+-- row. The primary key has to include the partition key; writers pass
+-- created_at (when the event was received) so retried inserts still
+-- conflict.
+--
+-- The copy below runs in this migration's transaction, and the rename
+-- holds an ACCESS EXCLUSIVE lock on events until it commits: ingest and
+-- event reads block for as long as copying every existing event takes.
+-- On a large table, stop ingest and run this in a maintenance window.
+ALTER TABLE events RENAME TO events_unpartitioned;
+ALTER INDEX events_pkey RENAME TO events_unpartitioned_pkey;
+
+CREATE TABLE events (
+    id UUID NOT NULL DEFAULT gen_random_uuid(),
+    pipe_id UUID NOT NULL REFERENCES pipes(id) ON DELETE CASCADE,
+    status_code INT NOT NULL,
+    request_payload JSONB NOT NULL,
+    transformed_payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    pipe_revision INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- catches rows outside every monthly partition; the worker creates
+-- partitions ahead of time so this normally stays empty
+CREATE TABLE events_default PARTITION OF events DEFAULT;
+
+-- create_event_partition creates the partition for the month of m and
+-- returns its name.
+CREATE OR REPLACE FUNCTION create_event_partition(m TIMESTAMP) RETURNS TEXT AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', m);
+    part_name TEXT := 'events_p' || to_char(date_trunc('month', m), 'YYYYMM');
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF events FOR VALUES FROM (%L) TO (%L)',
+        part_name, start_at, start_at + INTERVAL '1 month'
+    );
+    RETURN part_name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- drop_event_partition drops a monthly partition with everything in it.
+CREATE OR REPLACE FUNCTION drop_event_partition(part_name TEXT) RETURNS VOID AS $$
+BEGIN
+    IF part_name !~ '^events_p[0-9]{6}$' THEN
+        RAISE EXCEPTION 'not an event partition: %', part_name;
+    END IF;
+    EXECUTE format('DROP TABLE IF EXISTS %I', part_name);
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    m TIMESTAMP;
+BEGIN
+    FOR m IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE((SELECT MIN(created_at) FROM events_unpartitioned), NOW()::timestamp)),
+            date_trunc('month', NOW()::timestamp) + INTERVAL '1 month',
+            INTERVAL '1 month'
+        )
+    LOOP
+        PERFORM create_event_partition(m);
+    END LOOP;
+END;
+$$;
+
+INSERT INTO events (id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision)
+SELECT id, pipe_id, status_code, request_payload, transformed_payload, created_at, pipe_revision
+FROM events_unpartitioned;
+
+DROP TABLE events_unpartitioned;
+
+-- indexes on the parent are created on every partition
+CREATE INDEX IF NOT EXISTS idx_events_pipe_created
+ON events(pipe_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_events_request_payload
+ON events USING GIN (request_payload jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_events_transformed_payload
+ON events USING GIN (transformed_payload jsonb_path_ops);
@@ -0,0 +1,2 @@
+ALTER TABLE pipes
+DROP COLUMN IF EXISTS redaction_rules;
@@ -0,0 +1,4 @@
+-- rules applied to payloads before they are stored as events or
+-- streamed over the realtime socket, delivery is unaffected
+ALTER TABLE pipes
+ADD COLUMN redaction_rules JSONB NOT NULL DEFAULT '[]';
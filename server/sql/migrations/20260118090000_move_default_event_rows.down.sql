@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION create_event_partition(m TIMESTAMP) RETURNS TEXT AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', m);
+    part_name TEXT := 'events_p' || to_char(date_trunc('month', m), 'YYYYMM');
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF events FOR VALUES FROM (%L) TO (%L)',
+        part_name, start_at, start_at + INTERVAL '1 month'
+    );
+    RETURN part_name;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,34 @@
+-- create_event_partition now moves rows that landed in events_default
+-- for the month into the new partition. Postgres refuses to create a
+-- partition while the default one holds rows in its range, which is the
+-- case whenever partitions were not created ahead of time.
+CREATE OR REPLACE FUNCTION create_event_partition(m TIMESTAMP) RETURNS TEXT AS $$
+DECLARE
+    start_at TIMESTAMP := date_trunc('month', m);
+    end_at TIMESTAMP := date_trunc('month', m) + INTERVAL '1 month';
+    part_name TEXT := 'events_p' || to_char(date_trunc('month', m), 'YYYYMM');
+BEGIN
+    IF to_regclass(part_name) IS NOT NULL THEN
+        RETURN part_name;
+    END IF;
+
+    -- the whole move runs in the caller's transaction, so the rows are
+    -- never missing from events as far as other sessions can tell
+    CREATE TEMP TABLE event_partition_move (LIKE events);
+    WITH moved AS (
+        DELETE FROM events_default
+        WHERE created_at >= start_at AND created_at < end_at
+        RETURNING *
+    )
+    INSERT INTO event_partition_move SELECT * FROM moved;
+
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF events FOR VALUES FROM (%L) TO (%L)',
+        part_name, start_at, end_at
+    );
+
+    INSERT INTO events SELECT * FROM event_partition_move;
+    DROP TABLE event_partition_move;
+    RETURN part_name;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,2 @@
+-- the caps set by the up migration can't be told apart from chosen ones
+SELECT 1;
@@ -0,0 +1,4 @@
+-- collect windows hold every event until they flush, so each needs a cap
+UPDATE pipes
+SET window_max_events = 10000
+WHERE window_mode = 'collect' AND window_max_events = 0;
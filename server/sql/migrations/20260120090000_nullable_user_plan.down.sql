@@ -0,0 +1,5 @@
+UPDATE users SET plan = 'free' WHERE plan IS NULL;
+
+ALTER TABLE users
+ALTER COLUMN plan SET DEFAULT 'free',
+ALTER COLUMN plan SET NOT NULL;
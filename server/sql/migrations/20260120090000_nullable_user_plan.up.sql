@@ -0,0 +1,9 @@
+-- plan is set by operators, not through the API. Users without one get
+-- the user's own event_retention_days or RETENTION_DEFAULT_DAYS rather
+-- than the free plan's retention.
+ALTER TABLE users
+ALTER COLUMN plan DROP NOT NULL,
+ALTER COLUMN plan DROP DEFAULT;
+
+-- nothing could set plan before this, so every 'free' is the old default
+UPDATE users SET plan = NULL WHERE plan = 'free';
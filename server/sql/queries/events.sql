@@ -1,9 +1,10 @@
 -- name: CreateEvent :exec
 INSERT INTO events (
-    id, pipe_id, status_code, request_payload, transformed_payload
+    id, pipe_id, status_code, request_payload, transformed_payload, pipe_revision, created_at
 ) VALUES (
-    $1, $2, $3, $4, $5
-);
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (id, created_at) DO NOTHING;
 
 
 -- name: ListEvents :many
@@ -19,11 +20,53 @@ INSERT INTO events (
     pipe_id,
     status_code,
     request_payload,
-    transformed_payload
+    transformed_payload,
+    pipe_revision,
+    created_at
 )
 SELECT
     unnest(@ids::uuid[]),
     unnest(@pipe_ids::uuid[]),
     unnest(@status_codes::int[]),
     unnest(@request_payloads::jsonb[]),
-    unnest(@transformed_payloads::jsonb[]);
+    unnest(@transformed_payloads::jsonb[]),
+    unnest(@pipe_revisions::int[]),
+    unnest(@created_ats::timestamp[])
+ON CONFLICT (id, created_at) DO NOTHING;
+
+
+-- name: GetEventForUser :one
+SELECT e.id, e.pipe_id, e.request_payload, e.created_at, p.name AS pipe_name, p.slug AS pipe_slug
+FROM events e
+JOIN pipes p ON p.id = e.pipe_id
+WHERE e.id = $1 AND p.user_id = $2 AND p.deleted_at IS NULL
+LIMIT 1;
+
+
+-- name: ListPipeEvents :many
+-- A keyset page of the pipe's events, newest first. Every filter is
+-- optional; search_in ('request', 'transformed' or 'any') selects the
+-- payloads path and contains are matched against.
+SELECT * FROM events
+WHERE pipe_id = @pipe_id
+  AND (sqlc.narg('status_code')::int IS NULL OR status_code = sqlc.narg('status_code'))
+  AND (sqlc.narg('min_status')::int IS NULL OR status_code >= sqlc.narg('min_status'))
+  AND (sqlc.narg('max_status')::int IS NULL OR status_code <= sqlc.narg('max_status'))
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+  AND (sqlc.narg('path')::text IS NULL
+       OR (@search_in::text <> 'transformed' AND request_payload @? sqlc.narg('path')::jsonpath)
+       OR (@search_in::text <> 'request' AND transformed_payload @? sqlc.narg('path')::jsonpath))
+  AND (sqlc.narg('contains')::jsonb IS NULL
+       OR (@search_in::text <> 'transformed' AND request_payload @> sqlc.narg('contains'))
+       OR (@search_in::text <> 'request' AND transformed_payload @> sqlc.narg('contains')))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit;
+
+
+-- name: GetPipeEvent :one
+SELECT * FROM events
+WHERE id = $1 AND pipe_id = $2
+LIMIT 1;
@@ -0,0 +1,24 @@
+-- name: UpsertPipeFixture :one
+INSERT INTO pipe_fixtures (
+    pipe_id, name, input, headers, expected, expect_dropped
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (pipe_id, name) DO UPDATE
+SET input = EXCLUDED.input,
+    headers = EXCLUDED.headers,
+    expected = EXCLUDED.expected,
+    expect_dropped = EXCLUDED.expect_dropped,
+    updated_at = NOW()
+RETURNING *;
+
+
+-- name: ListPipeFixtures :many
+SELECT * FROM pipe_fixtures
+WHERE pipe_id = $1
+ORDER BY name;
+
+
+-- name: DeletePipeFixture :execrows
+DELETE FROM pipe_fixtures
+WHERE id = $1 AND pipe_id = $2;
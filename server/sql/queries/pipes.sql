@@ -1,9 +1,23 @@
 -- name: CreatePipe :exec
-INSERT INTO pipes (
-   id, user_id, name, slug, target_url, jq_filter
-) VALUES (
-    $1, $2, $3, $4, $5, $6
-);
+-- the pipe is created together with its first revision
+WITH created AS (
+    INSERT INTO pipes (
+       id, user_id, name, slug, target_url, jq_filter, weight, max_concurrency,
+       tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version,
+       destination_type, destination_config, output_format, output_template, output_content_type,
+       delivery_delay_seconds, deliver_at_expr,
+       window_mode, window_key_expr, window_seconds, window_max_events,
+       rate_limit_per_sec, rate_limit_burst, rate_limit_scope,
+       redaction_rules
+    ) VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+        $21, $22, $23, $24, $25, $26, $27, $28
+    )
+    RETURNING *
+)
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+SELECT id, revision, user_id, 'create', pipe_config(to_jsonb(created))
+FROM created;
 
 
 -- name: GetPipeBySlug :one
@@ -28,13 +42,31 @@ ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
 -- name: UpdatePipe :one
-UPDATE pipes
-SET target_url = $3,
-    jq_filter = $4,
-    is_active = $5,
-    updated_at = NOW()
-WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-RETURNING *;
+-- Updates the editable fields of a pipe last seen at revision $8. A
+-- change to anything but is_active bumps the revision and records it.
+WITH updated AS (
+    UPDATE pipes
+    SET name = $3,
+        slug = $4,
+        target_url = $5,
+        jq_filter = $6,
+        is_active = $7,
+        redaction_rules = $9,
+        revision = CASE
+            WHEN (name, slug, target_url, jq_filter, redaction_rules) IS DISTINCT FROM ($3, $4, $5, $6, $9::jsonb)
+            THEN revision + 1
+            ELSE revision
+        END,
+        updated_at = NOW()
+    WHERE id = $1 AND user_id = $2 AND revision = $8 AND deleted_at IS NULL
+    RETURNING *
+), recorded AS (
+    INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+    SELECT id, revision, user_id, 'update', pipe_config(to_jsonb(updated))
+    FROM updated
+    WHERE updated.revision <> $8
+)
+SELECT * FROM updated;
 
 -- name: DeletePipe :execrows
 UPDATE pipes
@@ -60,3 +92,46 @@ SELECT EXISTS (
     AND user_id = $2
     AND deleted_at IS NULL
 );
+
+
+-- name: GetPipeSchedules :many
+SELECT id, weight, max_concurrency
+FROM pipes
+WHERE id = ANY(@ids::uuid[]);
+
+
+-- name: GetPipeTLS :one
+SELECT tls_client_cert, tls_client_key, tls_ca_bundle, tls_spki_pins, tls_min_version
+FROM pipes
+WHERE id = $1 AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: UpdatePipeFilter :execrows
+WITH updated AS (
+    UPDATE pipes
+    SET jq_filter = $3,
+        revision = revision + 1,
+        updated_at = NOW()
+    WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+    RETURNING *
+)
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, config)
+SELECT id, revision, user_id, 'update_filter', pipe_config(to_jsonb(updated))
+FROM updated;
+
+-- name: PausePipe :execrows
+UPDATE pipes
+SET paused_at = COALESCE(paused_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ResumePipe :execrows
+UPDATE pipes
+SET paused_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListPausedPipes :many
+SELECT id FROM pipes
+WHERE paused_at IS NOT NULL
+  AND deleted_at IS NULL;
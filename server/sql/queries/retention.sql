@@ -0,0 +1,70 @@
+-- name: CreateEventPartition :one
+-- Creates the events partition for the month of @month if missing.
+SELECT create_event_partition(@month::timestamp)::text;
+
+-- name: DropEventPartition :exec
+SELECT drop_event_partition(@part_name::text);
+
+-- name: ListEventPartitions :many
+SELECT c.relname::text AS name
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+JOIN pg_class parent ON parent.oid = i.inhparent
+WHERE parent.relname = 'events'
+  AND c.relname ~ '^events_p[0-9]{6}$'
+ORDER BY c.relname;
+
+-- name: ListDefaultEventMonths :many
+-- The months events_default holds events for, which should have had a
+-- partition of their own.
+SELECT date_trunc('month', created_at)::timestamp AS month, COUNT(*) AS events
+FROM events_default
+GROUP BY 1
+ORDER BY 1;
+
+-- name: HasEventsBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM events
+    WHERE created_at >= @since::timestamp AND created_at < @until::timestamp
+);
+
+-- name: GetRetentionBounds :one
+-- The shortest and longest retention set by any plan or user override.
+SELECT COALESCE(MIN(days), 0)::int AS min_days, COALESCE(MAX(days), 0)::int AS max_days
+FROM (
+    SELECT retention_days AS days FROM retention_plans
+    UNION ALL
+    SELECT event_retention_days FROM users WHERE event_retention_days IS NOT NULL
+) r;
+
+-- name: ListExpiredEvents :many
+-- Events past their owner's retention: the user's own setting, else
+-- their plan's, else @default_days. Only rows before @scan_before, the
+-- shortest retention anywhere, are looked at so recent partitions are
+-- pruned.
+SELECT e.* FROM events e
+JOIN pipes p ON p.id = e.pipe_id
+JOIN users u ON u.id = p.user_id
+LEFT JOIN retention_plans rp ON rp.plan = u.plan
+WHERE e.created_at < @scan_before::timestamp
+  AND e.created_at < @now::timestamp - make_interval(days => COALESCE(u.event_retention_days, rp.retention_days, @default_days::int))
+LIMIT @batch_size;
+
+-- name: DeleteExpiredEvents :execrows
+-- Deletes a batch of the events ListExpiredEvents would return.
+DELETE FROM events
+WHERE (id, created_at) IN (
+    SELECT e.id, e.created_at FROM events e
+    JOIN pipes p ON p.id = e.pipe_id
+    JOIN users u ON u.id = p.user_id
+    LEFT JOIN retention_plans rp ON rp.plan = u.plan
+    WHERE e.created_at < @scan_before::timestamp
+      AND e.created_at < @now::timestamp - make_interval(days => COALESCE(u.event_retention_days, rp.retention_days, @default_days::int))
+    LIMIT @batch_size
+);
+
+-- name: DeleteEvents :execrows
+DELETE FROM events
+WHERE (id, created_at) IN (
+    SELECT unnest(@ids::uuid[]), unnest(@created_ats::timestamp[])
+);
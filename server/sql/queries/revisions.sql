@@ -0,0 +1,65 @@
+-- name: ListPipeRevisions :many
+SELECT * FROM pipe_revisions
+WHERE pipe_id = $1
+ORDER BY revision DESC
+LIMIT $2 OFFSET $3;
+
+
+-- name: CountPipeRevisions :one
+SELECT COUNT(*) FROM pipe_revisions
+WHERE pipe_id = $1;
+
+
+-- name: GetPipeRevision :one
+SELECT * FROM pipe_revisions
+WHERE pipe_id = $1 AND revision = $2;
+
+
+-- name: RollbackPipe :execrows
+-- Columns missing from an older revision keep their current value.
+-- New config columns must be added to the SET list.
+WITH target AS (
+    SELECT r.config, t.*
+    FROM pipe_revisions r
+    CROSS JOIN LATERAL jsonb_populate_record(NULL::pipes, r.config) t
+    WHERE r.pipe_id = $1 AND r.revision = $3
+),
+updated AS (
+    UPDATE pipes p
+    SET
+        name = CASE WHEN t.config ? 'name' THEN t.name ELSE p.name END,
+        slug = CASE WHEN t.config ? 'slug' THEN t.slug ELSE p.slug END,
+        target_url = CASE WHEN t.config ? 'target_url' THEN t.target_url ELSE p.target_url END,
+        jq_filter = CASE WHEN t.config ? 'jq_filter' THEN t.jq_filter ELSE p.jq_filter END,
+        weight = CASE WHEN t.config ? 'weight' THEN t.weight ELSE p.weight END,
+        max_concurrency = CASE WHEN t.config ? 'max_concurrency' THEN t.max_concurrency ELSE p.max_concurrency END,
+        tls_client_cert = CASE WHEN t.config ? 'tls_client_cert' THEN t.tls_client_cert ELSE p.tls_client_cert END,
+        tls_client_key = CASE WHEN t.config ? 'tls_client_key' THEN t.tls_client_key ELSE p.tls_client_key END,
+        tls_ca_bundle = CASE WHEN t.config ? 'tls_ca_bundle' THEN t.tls_ca_bundle ELSE p.tls_ca_bundle END,
+        tls_spki_pins = CASE WHEN t.config ? 'tls_spki_pins' THEN t.tls_spki_pins ELSE p.tls_spki_pins END,
+        tls_min_version = CASE WHEN t.config ? 'tls_min_version' THEN t.tls_min_version ELSE p.tls_min_version END,
+        destination_type = CASE WHEN t.config ? 'destination_type' THEN t.destination_type ELSE p.destination_type END,
+        destination_config = CASE WHEN t.config ? 'destination_config' THEN t.destination_config ELSE p.destination_config END,
+        output_format = CASE WHEN t.config ? 'output_format' THEN t.output_format ELSE p.output_format END,
+        output_template = CASE WHEN t.config ? 'output_template' THEN t.output_template ELSE p.output_template END,
+        output_content_type = CASE WHEN t.config ? 'output_content_type' THEN t.output_content_type ELSE p.output_content_type END,
+        delivery_delay_seconds = CASE WHEN t.config ? 'delivery_delay_seconds' THEN t.delivery_delay_seconds ELSE p.delivery_delay_seconds END,
+        deliver_at_expr = CASE WHEN t.config ? 'deliver_at_expr' THEN t.deliver_at_expr ELSE p.deliver_at_expr END,
+        window_mode = CASE WHEN t.config ? 'window_mode' THEN t.window_mode ELSE p.window_mode END,
+        window_key_expr = CASE WHEN t.config ? 'window_key_expr' THEN t.window_key_expr ELSE p.window_key_expr END,
+        window_seconds = CASE WHEN t.config ? 'window_seconds' THEN t.window_seconds ELSE p.window_seconds END,
+        window_max_events = CASE WHEN t.config ? 'window_max_events' THEN t.window_max_events ELSE p.window_max_events END,
+        rate_limit_per_sec = CASE WHEN t.config ? 'rate_limit_per_sec' THEN t.rate_limit_per_sec ELSE p.rate_limit_per_sec END,
+        rate_limit_burst = CASE WHEN t.config ? 'rate_limit_burst' THEN t.rate_limit_burst ELSE p.rate_limit_burst END,
+        rate_limit_scope = CASE WHEN t.config ? 'rate_limit_scope' THEN t.rate_limit_scope ELSE p.rate_limit_scope END,
+        redaction_rules = CASE WHEN t.config ? 'redaction_rules' THEN t.redaction_rules ELSE p.redaction_rules END,
+        revision = p.revision + 1,
+        updated_at = NOW()
+    FROM target t
+    WHERE p.id = $1 AND p.user_id = $2 AND p.deleted_at IS NULL
+    RETURNING p.*
+)
+INSERT INTO pipe_revisions (pipe_id, revision, user_id, action, source_revision, config)
+-- recorded against the caller rather than the pipe's owner
+SELECT id, revision, $2, 'rollback', $3, pipe_config(to_jsonb(updated))
+FROM updated;
@@ -26,6 +26,18 @@ WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
 SELECT * FROM users
 WHERE oauth_provider = $1 AND oauth_provider_id = $2 AND deleted_at IS NULL LIMIT 1;
 
+-- name: GetUserRetention :one
+-- The user's plan and retention override, with the plan's retention.
+SELECT u.plan, u.event_retention_days, rp.retention_days AS plan_retention_days
+FROM users u
+LEFT JOIN retention_plans rp ON rp.plan = u.plan
+WHERE u.id = $1 AND u.deleted_at IS NULL;
+
+-- name: SetUserEventRetention :execrows
+UPDATE users
+SET event_retention_days = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
 
 -- name: LoginOAuthUser :one
 INSERT INTO users (